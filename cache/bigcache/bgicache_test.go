@@ -2,20 +2,29 @@ package bigcache
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/andrewbytecoder/gokit/container/bytesqyeye"
 	"github.com/andrewbytecoder/gokit/encoding/hash"
 	"github.com/andrewbytecoder/gokit/logger"
 	"github.com/andrewbytecoder/gokit/timer/clock"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestWriteAndGetOnCache(t *testing.T) {
@@ -208,6 +217,57 @@ func TestAppendCollision(t *testing.T) {
 
 }
 
+// lowBitsZeroHasher 低位全为0的哈希函数，用于模拟分片倾斜
+type lowBitsZeroHasher struct{}
+
+func (lowBitsZeroHasher) Sum64(key string) uint64 {
+	return hash.NewFnv64().Sum64(key) << 16
+}
+
+func TestShardSelector(t *testing.T) {
+	t.Parallel()
+
+	fibonacci := func(hashedKey uint64, numShards int) int {
+		return int((hashedKey * 11400714819323198485) >> (64 - 3))
+	}
+
+	for _, tc := range []struct {
+		name           string
+		selector       func(hashedKey uint64, numShards int) int
+		expectedShards int
+	}{
+		{name: "default", selector: nil, expectedShards: 1},
+		{name: "fibonacci", selector: fibonacci, expectedShards: 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			cache, _ := New(context.Background(), Config{
+				Shards:             8,
+				LifeWindow:         time.Second,
+				MaxEntriesInWindow: 1,
+				MaxEntrySize:       256,
+				Hasher:             lowBitsZeroHasher{},
+				ShardSelector:      tc.selector,
+			})
+
+			// when
+			for i := 0; i < 1000; i++ {
+				cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+			}
+
+			// then
+			usedShards := 0
+			for _, shard := range cache.shards {
+				if shard.len() > 0 {
+					usedShards++
+				}
+			}
+			assertEqual(t, tc.expectedShards, usedShards)
+			assertEqual(t, 1000, cache.Len())
+		})
+	}
+}
+
 func TestNewBigcacheValidation(t *testing.T) {
 	t.Parallel()
 
@@ -231,6 +291,10 @@ func TestNewBigcacheValidation(t *testing.T) {
 			cfg:  Config{Shards: 16, HardMaxCacheSize: -1},
 			want: "HardMaxCacheSize must be >= 0",
 		},
+		{
+			cfg:  Config{Shards: 16},
+			want: "LifeWindow must be > 0 unless AllowNeverExpire is set",
+		},
 	} {
 		t.Run(tc.want, func(t *testing.T) {
 			cache, error := New(context.Background(), tc.cfg)
@@ -241,6 +305,217 @@ func TestNewBigcacheValidation(t *testing.T) {
 	}
 }
 
+func TestAllowNeverExpire(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := newBigCache(context.Background(), Config{
+		Shards:             1,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		AllowNeverExpire:   true,
+	}, mock)
+	noError(t, err)
+
+	// when
+	cache.Set("key", []byte("value"))
+	mock.Add(100 * time.Second)
+	cache.Set("key2", []byte("value2"))
+	cachedValue, err := cache.Get("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	src, _ := newBigCache(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	dst, _ := newBigCache(context.Background(), Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	keys := 100
+	for i := 0; i < keys; i++ {
+		src.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i)))
+	}
+
+	// when
+	mock.Add(4 * time.Second)
+	copied, err := Migrate(dst, src)
+
+	// then
+	noError(t, err)
+	assertEqual(t, keys, copied)
+	assertEqual(t, keys, dst.Len())
+	for i := 0; i < keys; i++ {
+		cachedValue, fresh, err := dst.TryGet(fmt.Sprintf("key%d", i))
+		noError(t, err)
+		assertEqual(t, []byte(fmt.Sprintf("value%d", i)), cachedValue)
+		assertEqual(t, true, fresh)
+	}
+
+	// when
+	mock.Add(2 * time.Second)
+	_, fresh, err := dst.TryGet("key0")
+
+	// then
+	noError(t, err)
+	assertEqual(t, false, fresh)
+}
+
+func TestMigrateCompressed(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		src, dst Compressor
+	}{
+		{name: "compressed src", src: SnappyCompressor{}},
+		{name: "compressed dst", dst: SnappyCompressor{}},
+		{name: "compressed src and dst", src: SnappyCompressor{}, dst: SnappyCompressor{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			src, _ := New(context.Background(), Config{
+				Shards:             4,
+				LifeWindow:         5 * time.Second,
+				MaxEntriesInWindow: 10,
+				MaxEntrySize:       256,
+				Compressor:         tc.src,
+			})
+			dst, _ := New(context.Background(), Config{
+				Shards:             16,
+				LifeWindow:         5 * time.Second,
+				MaxEntriesInWindow: 10,
+				MaxEntrySize:       256,
+				Compressor:         tc.dst,
+			})
+			noError(t, src.Set("small", []byte("value")))
+			noError(t, src.Set("large", blob('a', 1024)))
+
+			// when
+			copied, err := Migrate(dst, src)
+
+			// then
+			noError(t, err)
+			assertEqual(t, 2, copied)
+			cachedValue, err := dst.Get("small")
+			noError(t, err)
+			assertEqual(t, []byte("value"), cachedValue)
+			cachedValue, err = dst.Get("large")
+			noError(t, err)
+			assertEqual(t, blob('a', 1024), cachedValue)
+		})
+	}
+}
+
+func TestMigrateKeyNormalizer(t *testing.T) {
+	t.Parallel()
+
+	// given
+	src, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	dst, _ := New(context.Background(), Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		KeyNormalizer:      strings.ToLower,
+	})
+	noError(t, src.Set("User:1", []byte("value")))
+
+	// when
+	_, err := Migrate(dst, src)
+
+	// then
+	noError(t, err)
+	for _, key := range []string{"User:1", "user:1"} {
+		cachedValue, err := dst.Get(key)
+		noError(t, err)
+		assertEqual(t, []byte("value"), cachedValue)
+	}
+}
+
+func TestMigrateKeepsTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	src, _ := newBigCache(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	dst, _ := newBigCache(context.Background(), Config{
+		Shards:             16,
+		AllowNeverExpire:   true,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, src.SetWithTTL("ttl", []byte("value"), 2*time.Second))
+	noError(t, src.Set("plain", []byte("value")))
+
+	// when
+	_, err := Migrate(dst, src)
+
+	// then
+	noError(t, err)
+	_, ttl, err := dst.GetWithTTL("ttl")
+	noError(t, err)
+	assertEqual(t, 2*time.Second, ttl)
+	_, ttl, err = dst.GetWithTTL("plain")
+	noError(t, err)
+	assertEqual(t, NoExpiration, ttl)
+
+	// when
+	mock.Add(3 * time.Second)
+	_, fresh, err := dst.TryGet("ttl")
+
+	// then
+	noError(t, err)
+	assertEqual(t, false, fresh)
+}
+
+func TestRemoveReasonString(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		reason RemoveReason
+		want   string
+	}{
+		{reason: NotRemoved, want: "NotRemoved"},
+		{reason: Expired, want: "Expired"},
+		{reason: NoSpace, want: "NoSpace"},
+		{reason: Deleted, want: "Deleted"},
+		{reason: RemoveReason(42), want: "Unknown(42)"},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			assertEqual(t, tc.want, tc.reason.String())
+
+			data, err := json.Marshal(Response{EntryStatus: tc.reason})
+			noError(t, err)
+			assertEqual(t, `{"EntryStatus":"`+tc.want+`"}`, string(data))
+		})
+	}
+}
+
 func TestEntryNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -316,6 +591,111 @@ func TestOnRemoveGetEntryStats(t *testing.T) {
 	assertEqual(t, uint32(100), count)
 }
 
+func TestAsyncOnRemove(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var cache *BigCache
+	removed := make(chan string, 1)
+	release := make(chan struct{})
+	onRemove := func(key string, entry []byte, reason RemoveReason) {
+		// 回调在分片锁外执行，访问同一分片不会死锁
+		cache.Get(key)
+		removed <- key + "=" + string(entry)
+		<-release
+	}
+	cache, _ = newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		OnRemoveWithReason: onRemove,
+		AsyncOnRemove:      true,
+	}, clock.New())
+	defer cache.Close()
+
+	// when
+	cache.Set("key", []byte("value"))
+	cache.Delete("key")
+
+	// then
+	select {
+	case entry := <-removed:
+		assertEqual(t, "key=value", entry)
+	case <-time.After(time.Second):
+		t.Fatal("callback was not called")
+	}
+
+	// when the callback is still blocked
+	set := make(chan error)
+	go func() {
+		cache.Set("key2", []byte("value2"))
+		set <- cache.Delete("key2")
+	}()
+
+	// then
+	select {
+	case err := <-set:
+		noError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Set blocked by slow callback")
+	}
+	close(release)
+}
+
+func TestContextCancelClosesCache(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	cache, _ := New(ctx, Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		CleanWindow:        time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	})
+	noError(t, cache.Set("key", []byte("value")))
+
+	// when
+	cancel()
+
+	// then
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := cache.Get("key"); err == ErrCacheClosed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cache was not closed after context cancellation")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assertEqual(t, ErrCacheClosed, cache.Set("key", []byte("value")))
+	assertEqual(t, ErrCacheClosed, cache.Delete("key"))
+	noError(t, cache.Close())
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	noError(t, cache.Close())
+	noError(t, cache.Close())
+
+	// then
+	_, err := cache.Get("key")
+	assertEqual(t, ErrCacheClosed, err)
+}
+
 func TestCacheLen(t *testing.T) {
 	t.Parallel()
 
@@ -356,7 +736,108 @@ func TestCacheCapacity(t *testing.T) {
 
 	// then
 	assertEqual(t, keys, cache.Len())
-	assertEqual(t, 40960, cache.Capacity())
+	assertEqual(t, 81920, cache.Capacity())
+}
+
+func TestCachePeakCapacity(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	})
+	assertEqual(t, cache.Capacity(), cache.PeakCapacity())
+
+	// when
+	for i := 0; i < 1337; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	cache.Reset()
+
+	// then
+	assertEqual(t, 81920, cache.PeakCapacity())
+}
+
+func TestLazyShards(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             64,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 64 * 100,
+		MaxEntrySize:       1024,
+		LazyShards:         true,
+	})
+	defer cache.Close()
+
+	// then
+	assertEqual(t, 0, cache.Capacity())
+	_, err := cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	// when
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			noError(t, cache.Set(fmt.Sprintf("key%d", i), []byte("value")))
+		}(i)
+	}
+	wg.Wait()
+
+	// then
+	for i := 0; i < 32; i++ {
+		value, err := cache.Get(fmt.Sprintf("key%d", i))
+		noError(t, err)
+		assertEqual(t, []byte("value"), value)
+	}
+	touched := 0
+	for _, shard := range cache.shards {
+		if shard.capacity() > 0 {
+			assertEqual(t, 100*1024, shard.capacity())
+			touched++
+		}
+	}
+	assertEqual(t, touched*100*1024, cache.Capacity())
+}
+
+func TestLazyShardsConcurrentFirstWrite(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 100,
+		MaxEntrySize:       16,
+		LazyShards:         true,
+	})
+
+	// when
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if i%2 == 0 {
+				noError(t, cache.Append(fmt.Sprintf("key%d", i), []byte("value")))
+			} else {
+				noError(t, cache.Set(fmt.Sprintf("key%d", i), []byte("value")))
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// then
+	assertEqual(t, 16, cache.Len())
 }
 
 func TestCacheInitialCapacity(t *testing.T) {
@@ -810,16 +1291,54 @@ func TestEntryBiggerThanMaxShardSizeError(t *testing.T) {
 	err := cache.Set("key1", blob('a', 1024*1025))
 
 	// then
-	assertEqual(t, "entry is bigger than max shard size", err.Error())
+	assertEqual(t, "entry is bigger than max shard size: queue is full, Maximum size limit reached", err.Error())
+	assertEqual(t, true, errors.Is(err, bytesqyeye.ErrFull))
 }
 
-func TestHashCollision(t *testing.T) {
+func TestSetLarge(t *testing.T) {
 	t.Parallel()
 
-	logger, err := logger.CreateProductZapLogger(
-		logger.SetLogLevel(zap.InfoLevel),
-		logger.SetLogMaxSize(100),
-		logger.SetLogMaxAge(30),
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+	})
+	value := blob('a', 1024*400)
+
+	// when
+	err := cache.Set("key1", value)
+
+	// then
+	assertEqual(t, "entry is bigger than max shard size: queue is full, Maximum size limit reached", err.Error())
+	assertEqual(t, true, errors.Is(err, bytesqyeye.ErrFull))
+
+	// when
+	err = cache.SetLarge("key1", value)
+	cachedValue, getErr := cache.Get("key1")
+
+	// then
+	noError(t, err)
+	noError(t, getErr)
+	assertEqual(t, value, cachedValue)
+
+	// when
+	err = cache.SetLarge("key2", blob('b', 1024*1025))
+
+	// then
+	assertEqual(t, "entry is bigger than max shard size: queue is full, Maximum size limit reached", err.Error())
+	assertEqual(t, true, errors.Is(err, bytesqyeye.ErrFull))
+}
+
+func TestHashCollision(t *testing.T) {
+	t.Parallel()
+
+	logger, err := logger.CreateProductZapLogger(
+		logger.SetLogLevel(zap.InfoLevel),
+		logger.SetLogMaxSize(100),
+		logger.SetLogMaxAge(30),
 		logger.SetLogCompress(true),
 		logger.SetConsoleWriterSyncer(true),
 		logger.SetLogFilename("bigcache.log"),
@@ -929,6 +1448,87 @@ func TestClosing(t *testing.T) {
 	assertEqual(t, true, math.Abs(float64(endGR-startGR)) < 25)
 }
 
+func TestOffHeap(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, err := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OffHeap:            true,
+	})
+	noError(t, err)
+
+	// when
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), blob('a', 512))
+	}
+	cachedValue, err := cache.Get("key42")
+
+	// then
+	noError(t, err)
+	assertEqual(t, blob('a', 512), cachedValue)
+
+	// when
+	noError(t, cache.Close())
+	_, err = cache.Get("key42")
+
+	// then
+	assertEqual(t, ErrCacheClosed, err)
+	assertEqual(t, 0, cache.Len())
+
+	// when 通过关闭检查之后才取得分片锁的读写
+	shard := cache.shards[0]
+	hashedKey := cache.hash.Sum64("key")
+	setErr := shard.set("key", hashedKey, []byte("value"))
+	_, getErr := shard.get("key", hashedKey)
+
+	// then 不会访问或重新分配已释放的字节队列
+	assertEqual(t, ErrCacheClosed, setErr)
+	assertEqual(t, ErrCacheClosed, getErr)
+	assertEqual(t, 0, shard.capacity())
+}
+
+func TestOffHeapConcurrentClose(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, err := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OffHeap:            true,
+	})
+	noError(t, err)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := fmt.Sprintf("key%d", i%100)
+				if err := cache.Set(key, blob('a', 128)); err == ErrCacheClosed {
+					return
+				}
+				cache.Get(key)
+			}
+		}()
+	}
+
+	// when
+	time.Sleep(time.Millisecond)
+	noError(t, cache.Close())
+	wg.Wait()
+
+	// then 关闭后没有重新分配的堆外内存
+	for _, shard := range cache.shards {
+		assertEqual(t, 0, shard.capacity())
+	}
+}
+
 func TestEntryNotPresent(t *testing.T) {
 	t.Parallel()
 
@@ -944,11 +1544,273 @@ func TestEntryNotPresent(t *testing.T) {
 	// when
 	value, resp, err := cache.GetWithInfo("blah")
 	assertEqual(t, ErrEntryNotFound, err)
-	assertEqual(t, resp.EntryStatus, RemoveReason(0))
+	assertEqual(t, resp.EntryStatus, NotRemoved)
 	assertEqual(t, cache.Stats().Misses, int64(1))
 	assertEqual(t, []byte(nil), value)
 }
 
+func TestGetBatch(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		StatsEnabled:       true,
+	})
+	cache.Set("a", []byte("1"))
+	cache.Set("c", []byte("3"))
+	cache.Set("e", []byte("5"))
+
+	// when
+	results, err := cache.GetBatch([]string{"a", "b", "c", "d", "e", "a"})
+
+	// then
+	noError(t, err)
+	assertEqual(t, []BatchResult{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Err: ErrEntryNotFound},
+		{Key: "c", Value: []byte("3")},
+		{Key: "d", Err: ErrEntryNotFound},
+		{Key: "e", Value: []byte("5")},
+		{Key: "a", Value: []byte("1")},
+	}, results)
+	assertEqual(t, int64(4), cache.Stats().Hits)
+	assertEqual(t, int64(2), cache.Stats().Misses)
+	assertEqual(t, uint32(2), cache.KeyMetadata("a").RequestCount)
+}
+
+func TestGetMultiSetMulti(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	items := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		items[fmt.Sprintf("key%d", i)] = []byte(fmt.Sprintf("value%d", i))
+	}
+
+	// when
+	err := cache.SetMulti(items)
+
+	// then
+	noError(t, err)
+	assertEqual(t, 100, cache.Len())
+
+	// when
+	found, err := cache.GetMulti([]string{"key1", "missing", "key99"})
+
+	// then
+	noError(t, err)
+	assertEqual(t, map[string][]byte{"key1": []byte("value1"), "key99": []byte("value99")}, found)
+
+	// when a key is too long only that key fails
+	err = cache.SetMulti(map[string][]byte{strings.Repeat("k", maxKeySize+1): nil, "short": []byte("ok")})
+
+	// then
+	assertEqual(t, true, errors.Is(err, ErrKeyTooLong))
+	value, err := cache.Get("short")
+	noError(t, err)
+	assertEqual(t, []byte("ok"), value)
+
+	// when
+	cache.Close()
+
+	// then
+	assertEqual(t, ErrCacheClosed, cache.SetMulti(items))
+	_, err = cache.GetMulti([]string{"key1"})
+	assertEqual(t, ErrCacheClosed, err)
+}
+
+func TestGetBatchCollision(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hash.NewConstant(5),
+	})
+	cache.Set("a", []byte("1"))
+
+	// when
+	results, err := cache.GetBatch([]string{"b", "a"})
+
+	// then
+	noError(t, err)
+	assertEqual(t, []BatchResult{
+		{Key: "b", Err: ErrEntryNotFound},
+		{Key: "a", Value: []byte("1")},
+	}, results)
+	assertEqual(t, int64(1), cache.Stats().Collisions)
+
+	// when
+	cache.Close()
+	_, err = cache.GetBatch([]string{"a"})
+
+	// then
+	assertEqual(t, ErrCacheClosed, err)
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	t.Parallel()
+
+	for _, sliding := range []bool{false, true} {
+		// given
+		mock := clock.NewMock()
+		cache, _ := newBigCache(context.Background(), Config{
+			Shards:             1,
+			LifeWindow:         5 * time.Second,
+			MaxEntriesInWindow: 1,
+			MaxEntrySize:       256,
+			SlidingExpiration:  sliding,
+		}, mock)
+		cache.Set("key", []byte("value"))
+
+		// when
+		for i := 0; i < 3; i++ {
+			mock.Add(4 * time.Second)
+			cache.Get("key")
+		}
+		mock.Add(4 * time.Second)
+		cache.cleanUp(uint64(mock.Now().Unix()))
+		cachedValue, err := cache.Get("key")
+
+		// then
+		if sliding {
+			noError(t, err)
+			assertEqual(t, []byte("value"), cachedValue)
+		} else {
+			assertEqual(t, ErrEntryNotFound, err)
+		}
+	}
+}
+
+func TestSlidingExpirationReads(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		read  func(cache *BigCache)
+		slide bool
+	}{
+		{name: "Get", read: func(cache *BigCache) { cache.Get("key") }, slide: true},
+		{name: "GetNonBlocking", read: func(cache *BigCache) { cache.GetNonBlocking("key") }, slide: true},
+		{name: "GetWithAffinity", read: func(cache *BigCache) { cache.GetWithAffinity("key", "key") }, slide: true},
+		{name: "GetBatch", read: func(cache *BigCache) { cache.GetBatch([]string{"key"}) }},
+		{name: "GetWithInfo", read: func(cache *BigCache) { cache.GetWithInfo("key") }},
+		{name: "GetWithTTL", read: func(cache *BigCache) { cache.GetWithTTL("key") }},
+		{name: "TryGet", read: func(cache *BigCache) { cache.TryGet("key") }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			mock := clock.NewMock()
+			cache, _ := newBigCache(context.Background(), Config{
+				Shards:             1,
+				LifeWindow:         5 * time.Second,
+				MaxEntriesInWindow: 1,
+				MaxEntrySize:       256,
+				SlidingExpiration:  true,
+			}, mock)
+			defer cache.Close()
+			noError(t, cache.Set("key", []byte("value")))
+
+			// when
+			mock.Add(4 * time.Second)
+			tc.read(cache)
+			mock.Add(4 * time.Second)
+			cache.cleanUp(uint64(mock.Epoch()))
+
+			// then 只有文档列出的读取会重置过期时间
+			_, _, err := cache.GetWithTTL("key")
+			if tc.slide {
+				noError(t, err)
+			} else {
+				assertEqual(t, ErrEntryNotFound, err)
+			}
+		})
+	}
+}
+
+func TestIteratorEntryShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntrySize:       256,
+	})
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	iterator := cache.Iterator()
+	count := 0
+	for iterator.SetNext() {
+		current, err := iterator.Value()
+		noError(t, err)
+
+		// then
+		hashedKey := cache.hash.Sum64(current.Key())
+		assertEqual(t, hashedKey, current.Hash())
+		assertEqual(t, cache.getShard(hashedKey), cache.shards[current.Shard()])
+		count++
+	}
+	assertEqual(t, 100, count)
+}
+
+func TestTryGet(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, mock)
+	cache.Set("key", []byte("value"))
+
+	// when
+	mock.Add(5 * time.Second)
+	cachedValue, fresh, err := cache.TryGet("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+	assertEqual(t, true, fresh)
+
+	// when
+	mock.Add(time.Second)
+	cachedValue, fresh, err = cache.TryGet("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+	assertEqual(t, false, fresh)
+
+	// when
+	cachedValue, fresh, err = cache.TryGet("nonExistingKey")
+
+	// then
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, []byte(nil), cachedValue)
+	assertEqual(t, false, fresh)
+}
+
 func TestBigCache_GetWithInfoCollision(t *testing.T) {
 	t.Parallel()
 
@@ -1019,6 +1881,47 @@ func blob(char byte, len int) []byte {
 	return bytes.Repeat([]byte{char}, len)
 }
 
+func TestConstantHasherCollisions(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hash.NewConstant(0),
+		StatsEnabled:       true,
+	})
+
+	// when
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+
+	// then
+	for i, shard := range cache.shards {
+		if i == 0 {
+			assertEqual(t, 1, shard.len())
+		} else {
+			assertEqual(t, 0, shard.len())
+		}
+	}
+
+	// when
+	_, err := cache.Get("a")
+
+	// then
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, int64(1), cache.Stats().Collisions)
+
+	// when
+	cachedValue, err := cache.Get("b")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("2"), cachedValue)
+}
+
 func TestCache_SetWithoutCleanWindow(t *testing.T) {
 
 	opt := DefaultConfig(time.Second)
@@ -1071,3 +1974,2429 @@ func TestRemoveNonExpiredData(t *testing.T) {
 		noError(t, err)
 	}
 }
+
+func TestReserve(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 4,
+		MaxEntrySize:       16,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	cache.Reserve(10000)
+	for i := 0; i < 10000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// then
+	value, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+	assertEqual(t, 10001, cache.Len())
+}
+
+func BenchmarkReserve(b *testing.B) {
+	const entries = 1000000
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	value := []byte("value")
+
+	for _, reserve := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reserve=%t", reserve), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				cache, _ := New(context.Background(), Config{
+					Shards:             16,
+					LifeWindow:         time.Minute,
+					MaxEntriesInWindow: 16,
+					MaxEntrySize:       32,
+				})
+				b.StartTimer()
+
+				if reserve {
+					cache.Reserve(entries)
+				}
+				for _, key := range keys {
+					cache.Set(key, value)
+				}
+
+				b.StopTimer()
+				cache.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestConcurrentCleanUp(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntrySize:       256,
+		CleanupConcurrency: 4,
+	}, mock)
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	mock.Add(3 * time.Second)
+	cache.Set("fresh", []byte("value"))
+	mock.Add(3 * time.Second)
+	cache.cleanUp(uint64(mock.Now().Unix()))
+
+	// then
+	assertEqual(t, 1, cache.Len())
+	_, err := cache.Get("fresh")
+	noError(t, err)
+}
+
+func BenchmarkCleanUp(b *testing.B) {
+	for _, concurrency := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			mock := clock.NewMock()
+			cache, _ := newBigCache(context.Background(), Config{
+				Shards:             4096,
+				LifeWindow:         5 * time.Second,
+				MaxEntriesInWindow: 4096 * 16,
+				MaxEntrySize:       64,
+				CleanupConcurrency: concurrency,
+			}, mock)
+			value := blob('a', 64)
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for j := 0; j < 4096*16; j++ {
+					cache.Set(fmt.Sprintf("key%d", j), value)
+				}
+				mock.Add(10 * time.Second)
+				b.StartTimer()
+
+				cache.cleanUp(uint64(mock.Now().Unix()))
+			}
+		})
+	}
+}
+
+func TestKeyTooLong(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	})
+	longKey := string(blob('a', 70*1024))
+	maxKey := string(blob('b', maxKeySize))
+
+	// when
+	err := cache.Set(longKey, []byte("value"))
+
+	// then
+	assertEqual(t, ErrKeyTooLong, err)
+	assertEqual(t, ErrKeyTooLong, cache.Append(longKey, []byte("value")))
+	_, err = cache.Get(longKey)
+	assertEqual(t, ErrEntryNotFound, err)
+
+	// when
+	noError(t, cache.Set(maxKey, []byte("value")))
+	cachedValue, err := cache.Get(maxKey)
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		KeyNormalizer:      strings.ToLower,
+	})
+
+	// when
+	noError(t, cache.Set("Foo", []byte("value")))
+	noError(t, cache.Append("FOO", []byte("2")))
+
+	// then
+	cachedValue, err := cache.Get("foo")
+	noError(t, err)
+	assertEqual(t, []byte("value2"), cachedValue)
+	results, err := cache.GetBatch([]string{"fOO"})
+	noError(t, err)
+	assertEqual(t, []BatchResult{{Key: "fOO", Value: []byte("value2")}}, results)
+	assertEqual(t, 1, cache.Len())
+
+	// when
+	noError(t, cache.Delete("FoO"))
+
+	// then
+	_, err = cache.Get("Foo")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestUpdateConcurrentIncrements(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	increment := func(old []byte, found bool) ([]byte, error) {
+		var n uint64
+		if found {
+			n = binary.LittleEndian.Uint64(old)
+		}
+		return binary.LittleEndian.AppendUint64(nil, n+1), nil
+	}
+	nWorker, nIncrements := 10, 1000
+
+	// when
+	var wg sync.WaitGroup
+	for i := 0; i < nWorker; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < nIncrements; j++ {
+				noError(t, cache.Update("counter", increment))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// then
+	cachedValue, err := cache.Get("counter")
+	noError(t, err)
+	assertEqual(t, uint64(nWorker*nIncrements), binary.LittleEndian.Uint64(cachedValue))
+	assertEqual(t, 1, cache.Len())
+}
+
+func TestUpdateDeleteAndError(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	errAbort := errors.New("abort")
+	noError(t, cache.Set("key", []byte("value")))
+
+	// when
+	err := cache.Update("key", func(old []byte, found bool) ([]byte, error) {
+		return []byte("ignored"), errAbort
+	})
+
+	// then
+	assertEqual(t, errAbort, err)
+	cachedValue, _ := cache.Get("key")
+	assertEqual(t, []byte("value"), cachedValue)
+
+	// when
+	var seen []byte
+	err = cache.Update("key", func(old []byte, found bool) ([]byte, error) {
+		seen = old
+		return nil, nil
+	})
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), seen)
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, 0, cache.Len())
+
+	// when
+	var wasFound bool
+	err = cache.Update("missing", func(old []byte, found bool) ([]byte, error) {
+		wasFound = found
+		return nil, nil
+	})
+
+	// then
+	noError(t, err)
+	assertEqual(t, false, wasFound)
+	assertEqual(t, 0, cache.Len())
+}
+
+func TestUpdateKeepsTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Hour,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	defer cache.Close()
+	noError(t, cache.SetWithTTL("key", []byte("value"), 10*time.Second))
+	mock.Add(5 * time.Second)
+
+	// when
+	err := cache.Update("key", func(old []byte, found bool) ([]byte, error) {
+		return append(old, '!'), nil
+	})
+
+	// then 写回的条目保留自己的生存时间，从写回时重新计算
+	noError(t, err)
+	value, ttl, err := cache.GetWithTTL("key")
+	noError(t, err)
+	assertEqual(t, []byte("value!"), value)
+	assertEqual(t, 10*time.Second, ttl)
+}
+
+func TestGetSet(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	old, existed, err := cache.GetSet("key", []byte("v1"))
+
+	// then
+	noError(t, err)
+	assertEqual(t, false, existed)
+	assertEqual(t, []byte(nil), old)
+
+	// when
+	old, existed, err = cache.GetSet("key", []byte("v2"))
+
+	// then
+	noError(t, err)
+	assertEqual(t, true, existed)
+	assertEqual(t, []byte("v1"), old)
+	cachedValue, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("v2"), cachedValue)
+	assertEqual(t, 1, cache.Len())
+}
+
+func TestGetSetCompressed(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Compressor:         SnappyCompressor{},
+	})
+	noError(t, cache.Set("key", blob('a', 1024)))
+
+	// when
+	old, existed, err := cache.GetSet("key", blob('b', 1024))
+
+	// then
+	noError(t, err)
+	assertEqual(t, true, existed)
+	assertEqual(t, blob('a', 1024), old)
+	cachedValue, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, blob('b', 1024), cachedValue)
+}
+
+func TestGetSetConcurrentChain(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	nWorker, nSwaps := 10, 500
+
+	// when
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		olds    = make(map[string]int)
+		missing int
+	)
+	for i := 0; i < nWorker; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < nSwaps; j++ {
+				old, existed, err := cache.GetSet("key", []byte(fmt.Sprintf("%d-%d", worker, j)))
+				noError(t, err)
+				mu.Lock()
+				if existed {
+					olds[string(old)]++
+				} else {
+					missing++
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// then every written value is returned as old exactly once, except the last one which is still cached
+	cachedValue, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, 1, missing)
+	assertEqual(t, nWorker*nSwaps-1, len(olds))
+	assertEqual(t, 0, olds[string(cachedValue)])
+	for i := 0; i < nWorker; i++ {
+		for j := 0; j < nSwaps; j++ {
+			value := fmt.Sprintf("%d-%d", i, j)
+			if value == string(cachedValue) {
+				continue
+			}
+			assertEqual(t, 1, olds[value], value)
+		}
+	}
+}
+
+func TestTouch(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, mock)
+	cache.Set("untouched", []byte("value"))
+	cache.Set("touched", []byte("value"))
+
+	// when
+	mock.Add(4 * time.Second)
+	touchErr := cache.Touch("touched")
+	missingErr := cache.Touch("missing")
+	mock.Add(4 * time.Second)
+	cache.cleanUp(uint64(mock.Now().Unix()))
+
+	// then
+	noError(t, touchErr)
+	assertEqual(t, ErrEntryNotFound, missingErr)
+	cachedValue, err := cache.Get("touched")
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+	_, err = cache.Get("untouched")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestTouchKeepsTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, cache.SetWithTTL("key", []byte("value"), 5*time.Second))
+
+	// when
+	mock.Add(4 * time.Second)
+	noError(t, cache.Touch("key"))
+
+	// then the entry lives for its own TTL again, not for LifeWindow
+	value, ttl, err := cache.GetWithTTL("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+	assertEqual(t, 5*time.Second, ttl)
+}
+
+func TestForEachSnapshot(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	expected := map[string][]byte{}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		expected[key] = []byte(fmt.Sprintf("value%d", i))
+		cache.Set(key, expected[key])
+	}
+
+	// when
+	visited := map[string][]byte{}
+	err := cache.ForEachSnapshot(func(key string, value []byte) bool {
+		visited[key] = value
+		// the shard lock is not held, so writing into the same shard must not deadlock
+		noError(t, cache.Set(key, []byte("overwritten")))
+		return true
+	})
+
+	// then
+	noError(t, err)
+	assertEqual(t, expected, visited)
+	cachedValue, _ := cache.Get("key0")
+	assertEqual(t, []byte("overwritten"), cachedValue)
+
+	// when
+	count := 0
+	err = cache.ForEachSnapshot(func(key string, value []byte) bool {
+		count++
+		return count < 3
+	})
+
+	// then
+	noError(t, err)
+	assertEqual(t, 3, count)
+}
+
+func TestOversizeEntryPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []OversizeEntryPolicy{Reject, Skip, Truncate} {
+		// given
+		cache, _ := New(context.Background(), Config{
+			Shards:              1,
+			LifeWindow:          5 * time.Second,
+			MaxEntriesInWindow:  1,
+			MaxEntrySize:        1,
+			HardMaxCacheSize:    1,
+			OversizeEntryPolicy: policy,
+		})
+		cache.Set("key", []byte("old"))
+		value := blob('a', 1024*1025)
+
+		// when
+		err := cache.Set("key", value)
+		cachedValue, getErr := cache.Get("key")
+
+		// then
+		switch policy {
+		case Reject:
+			assertEqual(t, true, errors.Is(err, bytesqyeye.ErrFull))
+			assertEqual(t, ErrEntryNotFound, getErr)
+			assertEqual(t, 0, cache.Len())
+		case Skip:
+			noError(t, err)
+			assertEqual(t, ErrEntryNotFound, getErr)
+			assertEqual(t, 0, cache.Len())
+		case Truncate:
+			noError(t, err)
+			noError(t, getErr)
+			assertEqual(t, 1, cache.Len())
+			assertEqual(t, true, len(cachedValue) > 1024*1000)
+			assertEqual(t, value[:len(cachedValue)], cachedValue)
+		}
+	}
+}
+
+func TestOversizeEntryPolicyTruncateCompressed(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(context.Background(), Config{
+		Shards:              1,
+		LifeWindow:          time.Minute,
+		OversizeEntryPolicy: Truncate,
+		Compressor:          SnappyCompressor{},
+	})
+	assertEqual(t, true, err != nil)
+}
+
+func BenchmarkParallelGet64(b *testing.B) {
+	const entries = 1024
+	cache, _ := New(context.Background(), Config{
+		Shards:             16,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: entries,
+		MaxEntrySize:       64,
+	})
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		cache.Set(keys[i], blob('a', 64))
+	}
+
+	// RunParallel starts parallelism*GOMAXPROCS goroutines
+	b.SetParallelism(max(1, 64/runtime.GOMAXPROCS(0)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.Intn(entries)
+		for pb.Next() {
+			cache.Get(keys[i%entries])
+			i++
+		}
+	})
+}
+
+func TestConcurrentGetWhileWriting(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 64,
+		MaxEntrySize:       64,
+		HardMaxCacheSize:   1,
+	})
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	// every value repeats its key, so a torn or misrouted read is detected
+	valueFor := func(key string, round int) []byte {
+		return []byte(strings.Repeat(key, round%8+1))
+	}
+
+	// when
+	// readers and writers both do a bounded amount of work, so the test does not depend on scheduling
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 50; round++ {
+				for _, key := range keys {
+					cache.Set(key, valueFor(key, round))
+				}
+			}
+		}()
+	}
+	for r := 0; r < 16; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := r; i < r+2000; i++ {
+				key := keys[i%len(keys)]
+				value, err := cache.Get(key)
+				if err != nil {
+					continue
+				}
+				// then
+				if len(value)%len(key) != 0 || !bytes.Equal(value, []byte(strings.Repeat(key, len(value)/len(key)))) {
+					t.Errorf("inconsistent value %q for %q", value, key)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCachedClockExpiry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		CachedClock:        true,
+	}, mock)
+	defer cache.Close()
+	cache.Set("key", []byte("value"))
+
+	// when
+	mock.Add(500 * time.Millisecond)
+
+	// then the cached time only moves once per second
+	assertEqual(t, int64(0), cache.clock.Epoch())
+
+	// when
+	mock.Add(6 * time.Second)
+	deadline := time.Now().Add(time.Second)
+	for cache.clock.Epoch() != mock.Epoch() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// then
+	assertEqual(t, mock.Epoch(), cache.clock.Epoch())
+	_, resp, err := cache.GetWithInfo("key")
+	noError(t, err)
+	assertEqual(t, Expired, resp.EntryStatus)
+	cache.cleanUp(uint64(mock.Now().Unix()))
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func BenchmarkParallelSetCachedClock(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%t", cached), func(b *testing.B) {
+			cache, _ := New(context.Background(), Config{
+				Shards:             16,
+				LifeWindow:         time.Minute,
+				MaxEntriesInWindow: 1024,
+				MaxEntrySize:       64,
+				CachedClock:        cached,
+			})
+			defer cache.Close()
+			value := blob('a', 64)
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key%d", i)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.Intn(1024)
+				for pb.Next() {
+					cache.Set(keys[i%1024], value)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 16,
+		MaxEntrySize:       64,
+		StatsEnabled:       true,
+	})
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), blob('a', 32))
+	}
+	cache.Get("key0")
+	cache.Get("missing")
+	cache.Delete("key1")
+	cache.Delete("missing")
+
+	// when
+	snapshot := cache.Snapshot()
+
+	// then
+	assertEqual(t, cache.Stats(), snapshot.Stats)
+	assertEqual(t, cache.Len(), snapshot.Len)
+	assertEqual(t, 19, snapshot.Len)
+	assertEqual(t, cache.Capacity(), snapshot.Capacity)
+	assertEqual(t, cache.PeakCapacity(), snapshot.PeakCapacity)
+	assertEqual(t, cache.ShardLens(), snapshot.ShardLens)
+	assertEqual(t, true, snapshot.Used > 19*32 && snapshot.Used <= snapshot.Capacity)
+}
+
+func TestDeleteIfExists(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), DefaultConfig(5*time.Second))
+	cache.Set("key", []byte("value"))
+
+	// when
+	existed, err := cache.DeleteIfExists("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, true, existed)
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	// when
+	existed, err = cache.DeleteIfExists("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, false, existed)
+
+	// when
+	cache.Close()
+	existed, err = cache.DeleteIfExists("key")
+
+	// then
+	assertEqual(t, ErrCacheClosed, err)
+	assertEqual(t, false, existed)
+}
+
+func TestAppendSegments(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), DefaultConfig(5*time.Second))
+	segments := [][]byte{[]byte("first"), {}, blob('c', 300)}
+
+	// when
+	for _, segment := range segments {
+		noError(t, cache.AppendSegment("key", segment))
+	}
+	cachedSegments, err := cache.GetSegments("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, len(segments), len(cachedSegments))
+	for i := range segments {
+		assertEqual(t, segments[i], cachedSegments[i])
+	}
+
+	// when
+	_, missingErr := cache.GetSegments("missing")
+	cache.Set("plain", []byte{0xff})
+	_, malformedErr := cache.GetSegments("plain")
+
+	// then
+	assertEqual(t, ErrEntryNotFound, missingErr)
+	assertEqual(t, ErrMalformedSegments, malformedErr)
+}
+
+func TestCanEvict(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var vetoed []RemoveReason
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       100 * 1024,
+		HardMaxCacheSize:   1,
+		CanEvict: func(key string, reason RemoveReason) bool {
+			if key == "pinned" {
+				vetoed = append(vetoed, reason)
+				return false
+			}
+			return true
+		},
+	})
+	value := blob('a', 100*1024)
+	noError(t, cache.Set("pinned", value))
+
+	// when
+	for i := 0; i < 30; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key%d", i), value))
+	}
+
+	// then
+	cachedValue, err := cache.Get("pinned")
+	noError(t, err)
+	assertEqual(t, value, cachedValue)
+	assertEqual(t, true, cache.Len() < 31)
+	assertEqual(t, true, len(vetoed) > 0)
+	assertEqual(t, NoSpace, vetoed[0])
+	_, err = cache.Get("key0")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestCanEvictEverythingPinned(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       100 * 1024,
+		HardMaxCacheSize:   1,
+		CanEvict:           func(string, RemoveReason) bool { return false },
+	})
+	value := blob('a', 100*1024)
+
+	// when
+	var err error
+	for i := 0; i < 30 && err == nil; i++ {
+		err = cache.Set(fmt.Sprintf("key%d", i), value)
+	}
+
+	// then
+	assertEqual(t, true, err != nil)
+	cachedValue, getErr := cache.Get("key0")
+	noError(t, getErr)
+	assertEqual(t, value, cachedValue)
+}
+
+func TestCanEvictExpired(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		CanEvict: func(key string, reason RemoveReason) bool {
+			return key != "pinned"
+		},
+	}, mock)
+	cache.Set("pinned", []byte("value"))
+	cache.Set("other", []byte("value"))
+
+	// when
+	mock.Add(6 * time.Second)
+	cache.cleanUp(uint64(mock.Now().Unix()))
+
+	// then
+	_, err := cache.Get("other")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, resp, err := cache.GetWithInfo("pinned")
+	noError(t, err)
+	assertEqual(t, NotRemoved, resp.EntryStatus)
+}
+
+func TestCollisionLogSampleRate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	core, logs := observer.New(zap.InfoLevel)
+	cache, _ := New(context.Background(), Config{
+		Shards:                 1,
+		LifeWindow:             5 * time.Second,
+		MaxEntriesInWindow:     10,
+		MaxEntrySize:           256,
+		Verbose:                true,
+		CollisionLogSampleRate: 10,
+		StatsEnabled:           true,
+		Logger:                 zap.New(core),
+		Hasher:                 hashStub(5),
+	})
+	cache.Set("a", []byte("1"))
+
+	// when
+	for i := 0; i < 100; i++ {
+		cache.Get(fmt.Sprintf("b%d", i))
+	}
+	cache.Get("a")
+
+	// then
+	stats := cache.Stats()
+	assertEqual(t, int64(100), stats.Collisions)
+	assertEqual(t, 10, logs.FilterMessage("Collision detected").Len())
+	assertEqual(t, 100.0/101.0, stats.CollisionRate())
+	assertEqual(t, 0.0, Stats{}.CollisionRate())
+}
+
+func TestFlushAsyncOnRemove(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed atomic.Int32
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 100,
+		MaxEntrySize:       256,
+		OnRemove: func(key string, entry []byte) {
+			time.Sleep(time.Millisecond)
+			removed.Add(1)
+		},
+		AsyncOnRemove: true,
+	})
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	for i := 0; i < 20; i++ {
+		cache.Delete(fmt.Sprintf("key%d", i))
+	}
+
+	// when
+	err := cache.Flush(context.Background())
+
+	// then
+	noError(t, err)
+	assertEqual(t, int32(20), removed.Load())
+
+	// when
+	cache.Close()
+
+	// then
+	assertEqual(t, ErrCacheClosed, cache.Flush(context.Background()))
+}
+
+func TestFlushSyncOnRemove(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), DefaultConfig(time.Minute))
+	defer cache.Close()
+
+	// then
+	noError(t, cache.Flush(context.Background()))
+}
+
+func TestGetOrSet(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), DefaultConfig(time.Minute))
+	defer cache.Close()
+	var computed atomic.Int32
+	release := make(chan struct{})
+	compute := func() ([]byte, error) {
+		computed.Add(1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	// when
+	var wg sync.WaitGroup
+	results := make([][]byte, 20)
+	errs := make([]error, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cache.GetOrSet("key", compute)
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// then
+	assertEqual(t, int32(1), computed.Load())
+	for i := range results {
+		noError(t, errs[i])
+		assertEqual(t, []byte("value"), results[i])
+	}
+	results[0][0] = 'x'
+	assertEqual(t, []byte("value"), results[1])
+	cachedValue, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+
+	// when the value is cached
+	cachedValue, err = cache.GetOrSet("key", compute)
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+	assertEqual(t, int32(1), computed.Load())
+}
+
+func TestGetOrSetError(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), DefaultConfig(time.Minute))
+	defer cache.Close()
+	boom := errors.New("boom")
+	release := make(chan struct{})
+	var computed atomic.Int32
+
+	// when
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = cache.GetOrSet("key", func() ([]byte, error) {
+				computed.Add(1)
+				<-release
+				return nil, boom
+			})
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// then callers arriving after the failed computation compute again, they are not served a cached error
+	assertEqual(t, true, computed.Load() < int32(len(errs)))
+	for _, err := range errs {
+		assertEqual(t, boom, err)
+	}
+	_, err := cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	// when the error is not cached
+	value, err := cache.GetOrSet("key", func() ([]byte, error) { return []byte("value"), nil })
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+}
+
+func TestSetWithTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	var removed []string
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			assertEqual(t, Expired, reason)
+			removed = append(removed, key)
+		},
+	}, mock)
+	defer cache.Close()
+	noError(t, cache.SetWithTTL("short", []byte("a"), 2*time.Second))
+	noError(t, cache.Set("default", []byte("b")))
+	noError(t, cache.SetWithTTL("long", []byte("c"), time.Minute))
+
+	// when
+	mock.Add(3 * time.Second)
+
+	// then the short entry expired before the global life window
+	_, resp, err := cache.GetWithInfo("short")
+	noError(t, err)
+	assertEqual(t, Expired, resp.EntryStatus)
+	_, resp, err = cache.GetWithInfo("default")
+	noError(t, err)
+	assertEqual(t, NotRemoved, resp.EntryStatus)
+
+	// when
+	mock.Add(10 * time.Second)
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then the long entry outlives the global life window
+	assertEqual(t, []string{"short", "default"}, removed)
+	value, err := cache.Get("long")
+	noError(t, err)
+	assertEqual(t, []byte("c"), value)
+
+	// when
+	mock.Add(time.Minute)
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then
+	assertEqual(t, []string{"short", "default", "long"}, removed)
+}
+
+func TestSetWithTTLNonPositive(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, mock)
+	defer cache.Close()
+
+	// when
+	noError(t, cache.SetWithTTL("key", []byte("value"), 0))
+	mock.Add(6 * time.Second)
+
+	// then the entry falls back to LifeWindow
+	_, resp, err := cache.GetWithInfo("key")
+	noError(t, err)
+	assertEqual(t, Expired, resp.EntryStatus)
+	assertEqual(t, uint32(1), ttlSeconds(time.Millisecond))
+	assertEqual(t, uint32(maxTTLSeconds), ttlSeconds(math.MaxInt64))
+}
+
+func TestGetNonBlocking(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	})
+	defer cache.Close()
+	cache.Set("key", []byte("value"))
+
+	// when
+	value, checked, err := cache.GetNonBlocking("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, true, checked)
+	assertEqual(t, []byte("value"), value)
+	_, checked, err = cache.GetNonBlocking("missing")
+	assertEqual(t, true, checked)
+	assertEqual(t, ErrEntryNotFound, err)
+
+	// when a writer holds the shard lock
+	cache.shards[0].lock.Lock()
+	value, checked, err = cache.GetNonBlocking("key")
+	cache.shards[0].lock.Unlock()
+
+	// then the read backs off instead of reporting a miss
+	noError(t, err)
+	assertEqual(t, false, checked)
+	assertEqual(t, []byte(nil), value)
+}
+
+func TestGetNonBlockingConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	})
+	defer cache.Close()
+	cache.Set("key", []byte("value"))
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; !stop.Load(); i++ {
+			cache.Set(fmt.Sprintf("other%d", i%100), []byte("value"))
+		}
+	}()
+
+	// when
+	var backoffs int
+	for i := 0; i < 10000; i++ {
+		value, checked, err := cache.GetNonBlocking("key")
+		if !checked {
+			backoffs++
+			continue
+		}
+		// then every completed read sees the stored value
+		noError(t, err)
+		assertEqual(t, []byte("value"), value)
+	}
+	stop.Store(true)
+	wg.Wait()
+	t.Logf("%d of 10000 reads backed off", backoffs)
+}
+
+func BenchmarkSetMulti(b *testing.B) {
+	items := make(map[string][]byte, 256)
+	keys := make([]string, 0, 256)
+	for i := 0; i < 256; i++ {
+		key := fmt.Sprintf("key%d", i)
+		items[key] = blob('a', 64)
+		keys = append(keys, key)
+	}
+	for _, multi := range []bool{false, true} {
+		b.Run(fmt.Sprintf("multi=%t", multi), func(b *testing.B) {
+			config := DefaultConfig(5 * time.Minute)
+			config.Shards = 16
+			cache, _ := New(context.Background(), config)
+			defer cache.Close()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if multi {
+					cache.SetMulti(items)
+					cache.GetMulti(keys)
+					continue
+				}
+				for key, value := range items {
+					cache.Set(key, value)
+				}
+				for _, key := range keys {
+					cache.Get(key)
+				}
+			}
+		})
+	}
+}
+
+func TestSetWithAffinity(t *testing.T) {
+	t.Parallel()
+
+	// given keys that hash to different shards on their own
+	cache, _ := New(context.Background(), Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	keys := []string{"user:1:name", "user:1:email"}
+	assertEqual(t, false, cache.getShard(cache.hash.Sum64(keys[0])) == cache.getShard(cache.hash.Sum64(keys[1])))
+
+	// when
+	for _, key := range keys {
+		noError(t, cache.SetWithAffinity("user:1", key, []byte(key)))
+	}
+
+	// then both keys live in the shard of the affinity key
+	shard := cache.getAffinityShard("user:1")
+	for i := range cache.shards {
+		if cache.shards[i] == shard {
+			assertEqual(t, 2, cache.ShardLens()[i])
+		}
+	}
+	for _, key := range keys {
+		value, err := cache.GetWithAffinity("user:1", key)
+		noError(t, err)
+		assertEqual(t, []byte(key), value)
+	}
+
+	// when
+	noError(t, cache.DeleteWithAffinity("user:1", keys[0]))
+
+	// then
+	_, err := cache.GetWithAffinity("user:1", keys[0])
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, 1, cache.Len())
+}
+
+func TestOnRemoveWithMetadataAffinity(t *testing.T) {
+	t.Parallel()
+
+	// given an affinity key that selects another shard than the key itself
+	var mu sync.Mutex
+	counts := make(map[string]uint32)
+	cache, _ := New(context.Background(), Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		StatsEnabled:       true,
+		OnRemoveWithMetadata: func(key string, entry []byte, keyMetadata Metadata) {
+			mu.Lock()
+			counts[key] = keyMetadata.RequestCount
+			mu.Unlock()
+		},
+	})
+	key := "user:1:name"
+	assertEqual(t, false, cache.getShard(cache.hash.Sum64(key)) == cache.getAffinityShard("user:1"))
+	noError(t, cache.SetWithAffinity("user:1", key, []byte("value")))
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetWithAffinity("user:1", key)
+		noError(t, err)
+	}
+
+	// when other keys are read concurrently
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			other := fmt.Sprintf("other-%d", i)
+			cache.Set(other, []byte("value"))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					cache.Get(other)
+				}
+			}
+		}(i)
+	}
+	assertEqual(t, uint32(3), cache.KeyMetadataWithAffinity("user:1", key).RequestCount)
+	assertEqual(t, uint32(0), cache.KeyMetadata(key).RequestCount)
+	noError(t, cache.DeleteWithAffinity("user:1", key))
+	close(done)
+	wg.Wait()
+
+	// then the callback sees the statistics of the shard that held the entry
+	mu.Lock()
+	defer mu.Unlock()
+	assertEqual(t, uint32(3), counts[key])
+}
+
+func TestWriteSnapshotLoad(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	mock.Add(time.Hour)
+	config := Config{
+		Shards:             4,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}
+	cache, _ := newBigCache(context.Background(), config, mock)
+	defer cache.Close()
+	for i := 0; i < 50; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i))))
+	}
+	mock.Add(5 * time.Second)
+	noError(t, cache.SetWithTTL("long", []byte("ttl"), time.Minute))
+	noError(t, cache.Set("young", []byte("young")))
+
+	// when
+	var snapshot bytes.Buffer
+	noError(t, cache.WriteSnapshot(&snapshot))
+	config.Shards = 8
+	restored, err := load(context.Background(), &snapshot, config, mock)
+
+	// then
+	noError(t, err)
+	defer restored.Close()
+	assertEqual(t, 52, restored.Len())
+	for i := 0; i < 50; i++ {
+		value, err := restored.Get(fmt.Sprintf("key%d", i))
+		noError(t, err)
+		assertEqual(t, []byte(fmt.Sprintf("value%d", i)), value)
+	}
+
+	// when
+	mock.Add(6 * time.Second)
+	restored.cleanUp(uint64(mock.Epoch()))
+
+	// then timestamps and TTLs survived the round trip
+	assertEqual(t, 2, restored.Len())
+	value, err := restored.Get("long")
+	noError(t, err)
+	assertEqual(t, []byte("ttl"), value)
+}
+
+func TestWriteSnapshotLoadCompressed(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		src, dst Compressor
+	}{
+		{name: "compressed src", src: SnappyCompressor{}},
+		{name: "compressed dst", dst: SnappyCompressor{}},
+		{name: "compressed src and dst", src: SnappyCompressor{}, dst: SnappyCompressor{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			config := Config{
+				Shards:             4,
+				LifeWindow:         5 * time.Second,
+				MaxEntriesInWindow: 10,
+				MaxEntrySize:       256,
+				Compressor:         tc.src,
+			}
+			cache, _ := New(context.Background(), config)
+			defer cache.Close()
+			noError(t, cache.Set("small", []byte("value")))
+			noError(t, cache.Set("large", blob('a', 1024)))
+
+			// when
+			var snapshot bytes.Buffer
+			noError(t, cache.WriteSnapshot(&snapshot))
+			config.Compressor = tc.dst
+			restored, err := Load(context.Background(), &snapshot, config)
+
+			// then 值按新缓存的 Compressor 重新编码
+			noError(t, err)
+			defer restored.Close()
+			cachedValue, err := restored.Get("small")
+			noError(t, err)
+			assertEqual(t, []byte("value"), cachedValue)
+			cachedValue, err = restored.Get("large")
+			noError(t, err)
+			assertEqual(t, blob('a', 1024), cachedValue)
+		})
+	}
+}
+
+func TestLoadInvalidSnapshot(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	defer cache.Close()
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	var snapshot bytes.Buffer
+	noError(t, cache.WriteSnapshot(&snapshot))
+	valid := snapshot.Bytes()
+
+	wrongVersion := bytes.Clone(valid)
+	wrongVersion[len(snapshotMagic)] = snapshotVersion + 1
+	header := append([]byte(snapshotMagic), snapshotVersion, 1)
+	malformed := append(bytes.Clone(header), "\x03abc"...)
+	hugeLength := binary.AppendUvarint(bytes.Clone(header), 1<<40)
+
+	inputs := map[string][]byte{
+		"empty":         nil,
+		"bad magic":     []byte("JUNK\x01\x00"),
+		"wrong version": wrongVersion,
+		"malformed":     malformed,
+		"huge length":   hugeLength,
+	}
+	for i := 1; i < len(valid); i++ {
+		inputs[fmt.Sprintf("truncated at %d", i)] = valid[:i]
+	}
+
+	for name, input := range inputs {
+		// when
+		restored, err := Load(context.Background(), bytes.NewReader(input), cache.config)
+
+		// then
+		if !errors.Is(err, ErrInvalidSnapshot) {
+			t.Errorf("%s: expected ErrInvalidSnapshot, got %v", name, err)
+		}
+		assertEqual(t, (*BigCache)(nil), restored)
+	}
+
+	// when
+	restored, err := Load(context.Background(), bytes.NewReader(valid), cache.config)
+
+	// then
+	noError(t, err)
+	assertEqual(t, 2, restored.Len())
+	restored.Close()
+	cache.Close()
+	assertEqual(t, ErrCacheClosed, cache.WriteSnapshot(&snapshot))
+}
+
+func TestMaxValueSize(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       16,
+		MaxValueSize:       64,
+	})
+	defer cache.Close()
+
+	// when a value between the sizing hint and the hard limit is set
+	err := cache.Set("between", blob('a', 32))
+
+	// then
+	noError(t, err)
+	value, err := cache.Get("between")
+	noError(t, err)
+	assertEqual(t, blob('a', 32), value)
+	noError(t, cache.Set("limit", blob('a', 64)))
+
+	// when
+	err = cache.Set("huge", blob('a', 65))
+
+	// then
+	assertEqual(t, ErrValueTooLarge, err)
+	_, err = cache.Get("huge")
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, ErrValueTooLarge, cache.SetWithTTL("huge", blob('a', 65), time.Second))
+	assertEqual(t, ErrValueTooLarge, cache.Append("huge", blob('a', 65)))
+	assertEqual(t, true, errors.Is(cache.SetMulti(map[string][]byte{"huge": blob('a', 65)}), ErrValueTooLarge))
+
+	// when growing an existing value past the limit
+	assertEqual(t, ErrValueTooLarge, cache.Append("between", blob('b', 33)))
+	assertEqual(t, ErrValueTooLarge, cache.Update("between", func(old []byte, found bool) ([]byte, error) {
+		return append(old, blob('b', 33)...), nil
+	}))
+
+	// then the old value is kept
+	value, err = cache.Get("between")
+	noError(t, err)
+	assertEqual(t, blob('a', 32), value)
+	assertEqual(t, 2, cache.Len())
+}
+
+func TestMaxValueSizeCompressed(t *testing.T) {
+	t.Parallel()
+
+	// given: 重复的值压缩后远小于上限
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       16,
+		MaxValueSize:       64,
+		Compressor:         SnappyCompressor{},
+	})
+	defer cache.Close()
+
+	// when
+	err := cache.Set("huge", blob('a', 65))
+
+	// then: 上限按调用方的值检查，而不是压缩后的字节
+	assertEqual(t, ErrValueTooLarge, err)
+	assertEqual(t, ErrValueTooLarge, cache.SetWithTTL("huge", blob('a', 65), time.Second))
+	assertEqual(t, true, errors.Is(cache.SetMulti(map[string][]byte{"huge": blob('a', 65)}), ErrValueTooLarge))
+	noError(t, cache.Set("limit", blob('a', 64)))
+
+	// when growing an existing value past the limit
+	assertEqual(t, ErrValueTooLarge, cache.Append("limit", blob('b', 1)))
+	assertEqual(t, ErrValueTooLarge, cache.Update("limit", func(old []byte, found bool) ([]byte, error) {
+		return append(old, 'b'), nil
+	}))
+
+	// then the old value is kept
+	value, err := cache.Get("limit")
+	noError(t, err)
+	assertEqual(t, blob('a', 64), value)
+	assertEqual(t, 1, cache.Len())
+}
+
+func TestGetWithTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, _ := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	defer cache.Close()
+	noError(t, cache.Set("default", []byte("a")))
+	noError(t, cache.SetWithTTL("short", []byte("b"), 3*time.Second))
+
+	// when
+	mock.Add(4 * time.Second)
+	value, ttl, err := cache.GetWithTTL("default")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("a"), value)
+	assertEqual(t, 6*time.Second, ttl)
+
+	// when the entry is past its window but not cleaned up yet
+	value, ttl, err = cache.GetWithTTL("short")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("b"), value)
+	assertEqual(t, -time.Second, ttl)
+
+	_, _, err = cache.GetWithTTL("missing")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestGetWithTTLNeverExpire(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		AllowNeverExpire:   true,
+	})
+	defer cache.Close()
+	noError(t, cache.Set("key", []byte("value")))
+
+	// when
+	_, ttl, err := cache.GetWithTTL("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, NoExpiration, ttl)
+}
+
+func TestColdTierSpillOnEvict(t *testing.T) {
+	t.Parallel()
+
+	// given: 每个分片只能容纳三个条目
+	var evicted []string
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 4,
+		MaxEntrySize:       300 * 1024,
+		HardMaxCacheSize:   1,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			evicted = append(evicted, key)
+		},
+		ColdTier: &ColdTierConfig{Path: filepath.Join(t.TempDir(), "cold"), Size: 4 << 20},
+	})
+	noError(t, err)
+	defer cache.Close()
+
+	// when
+	for _, key := range []string{"a", "b", "c", "d"} {
+		noError(t, cache.Set(key, blob(key[0], 300*1024)))
+	}
+
+	// then: 最旧的条目被写入冷层，淘汰回调照常触发
+	assertEqual(t, []string{"a"}, evicted)
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, 1, cache.cold.len())
+
+	// when: 删除只存在于冷层的键
+	noError(t, cache.Delete("a"))
+
+	// then
+	assertEqual(t, 0, cache.cold.len())
+	_, err = cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestColdTierPromoteOnHit(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := newBigCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 4,
+		MaxEntrySize:       300 * 1024,
+		HardMaxCacheSize:   1,
+		ColdTier:           &ColdTierConfig{Path: filepath.Join(t.TempDir(), "cold"), Size: 4 << 20},
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.SetWithTTL("a", blob('a', 300*1024), 10*time.Second))
+	for _, key := range []string{"b", "c", "d"} {
+		noError(t, cache.Set(key, blob(key[0], 300*1024)))
+	}
+
+	// when
+	mock.Add(5 * time.Second)
+	value, err := cache.Get("a")
+
+	// then: 条目被提升回内存并挤出下一个最旧的条目，生存时间不变
+	noError(t, err)
+	assertEqual(t, blob('a', 300*1024), value)
+	_, ttl, err := cache.GetWithTTL("a")
+	noError(t, err)
+	assertEqual(t, 5*time.Second, ttl)
+	assertEqual(t, 1, cache.cold.len()) // b 被挤到冷层
+
+	// when: 冷层中的条目过期后不再被提升
+	for _, key := range []string{"e", "f", "g"} { // 将 a 再次挤到冷层
+		noError(t, cache.Set(key, blob(key[0], 300*1024)))
+	}
+	mock.Add(6 * time.Second)
+	_, err = cache.Get("a")
+
+	// then
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestColdTierInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(context.Background(), Config{
+		Shards:     1,
+		LifeWindow: time.Minute,
+		ColdTier:   &ColdTierConfig{Path: filepath.Join(t.TempDir(), "cold")},
+	})
+	assertEqual(t, true, err != nil)
+}
+
+// aliasHasher 让键使用另一个键的哈希值，用于构造指定键之间的哈希冲突
+type aliasHasher map[string]string
+
+func (h aliasHasher) Sum64(key string) uint64 {
+	if alias, ok := h[key]; ok {
+		key = alias
+	}
+	return hash.NewFnv64().Sum64(key)
+}
+
+func TestColdTierInvalidatedOnOverwrite(t *testing.T) {
+	t.Parallel()
+
+	// given: a 的旧值被挤到冷层，x 与 a 哈希冲突
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 4,
+		MaxEntrySize:       300 * 1024,
+		HardMaxCacheSize:   1,
+		Hasher:             aliasHasher{"x": "a"},
+		ColdTier:           &ColdTierConfig{Path: filepath.Join(t.TempDir(), "cold"), Size: 4 << 20},
+	})
+	noError(t, err)
+	defer cache.Close()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		noError(t, cache.Set(key, blob('1', 300*1024)))
+	}
+	assertEqual(t, 1, cache.cold.len())
+
+	// when: a 写入新值，挤出 b
+	noError(t, cache.Set("a", blob('2', 300*1024)))
+
+	// then: 冷层中 a 的旧值失效，只剩 b
+	assertEqual(t, 1, cache.cold.len())
+
+	// when: 冲突的 x 覆盖 a 的新值，挤出 c
+	noError(t, cache.Set("x", blob('3', 300*1024)))
+
+	// then: 冷层中没有 a 的任何值
+	assertEqual(t, 2, cache.cold.len())
+	_, err = cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+	value, err := cache.Get("x")
+	noError(t, err)
+	assertEqual(t, blob('3', 300*1024), value)
+}
+
+func TestColdTierCompactsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	// given: 冷层文件只能容纳三个条目
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 4,
+		MaxEntrySize:       300 * 1024,
+		HardMaxCacheSize:   1,
+		ColdTier:           &ColdTierConfig{Path: filepath.Join(t.TempDir(), "cold"), Size: 1 << 20},
+	})
+	noError(t, err)
+	defer cache.Close()
+
+	// when: 第四个被挤出的条目写满冷层
+	for i := 0; i < 7; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key-%d", i), blob(byte('0'+i), 300*1024)))
+	}
+
+	// then: 只丢弃较早的条目，最近被挤出的条目仍然可以取回
+	assertEqual(t, 2, cache.cold.len())
+	_, err = cache.Get("key-0")
+	assertEqual(t, ErrEntryNotFound, err)
+	value, err := cache.Get("key-2")
+	noError(t, err)
+	assertEqual(t, blob('2', 300*1024), value)
+}
+
+func TestNewWithClock(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := NewWithClock(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("key", []byte("value")))
+
+	// when
+	mock.Add(6 * time.Second)
+	noError(t, cache.Set("other", []byte("value"))) // 写入时淘汰按模拟时钟已过期的条目
+
+	// then
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+	value, err := cache.Get("other")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+}
+
+func TestMaxAge(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := NewWithClock(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxAge:             2 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("key", []byte("value")))
+	noError(t, cache.SetWithTTL("ttl", []byte("value"), time.Hour))
+
+	// when
+	mock.Add(2 * time.Second)
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then
+	assertEqual(t, 2, cache.Len())
+
+	// when
+	mock.Add(time.Second)
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then 未被读取的条目在超过 MaxAge 后被清理，比 LifeWindow 和条目自带的生存时间都早
+	assertEqual(t, 0, cache.Len())
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("ttl")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestMaxAgeNeverExpire(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := NewWithClock(context.Background(), Config{
+		Shards:             1,
+		AllowNeverExpire:   true,
+		MaxAge:             time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("key", []byte("value")))
+	_, ttl, err := cache.GetWithTTL("key")
+	noError(t, err)
+	assertEqual(t, time.Minute, ttl)
+
+	// when
+	mock.Add(time.Minute + time.Second)
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then
+	assertEqual(t, 0, cache.Len())
+}
+
+func TestMaxAgeIgnoresTouch(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := NewWithClock(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Hour,
+		MaxAge:             2 * time.Second,
+		SlidingExpiration:  true,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("a", []byte("value")))
+	noError(t, cache.Set("b", []byte("value")))
+
+	// when 不断刷新队首条目 "a"，使其时间戳始终比 "b" 新
+	for i := 0; i < 12; i++ {
+		mock.Add(500 * time.Millisecond)
+		if err := cache.Touch("a"); err != nil {
+			assertEqual(t, ErrEntryNotFound, err)
+		}
+		cache.Get("a")
+	}
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then Touch 和 SlidingExpiration 不会延长 MaxAge，队首之后的条目也会被清理
+	assertEqual(t, 0, cache.Len())
+	_, err = cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("b")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestMaxAgeGetBeforeCleanUp(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := NewWithClock(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Hour,
+		MaxAge:             2 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("key", []byte("value")))
+
+	// when
+	mock.Add(3 * time.Second)
+
+	// then 即使还没有清理，读取也不会返回超过 MaxAge 的条目
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestMaxAgeCleanupLoopUsesClock(t *testing.T) {
+	t.Parallel()
+
+	// given
+	mock := clock.NewMock()
+	cache, err := NewWithClock(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Hour,
+		MaxAge:             2 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("key", []byte("value")))
+
+	// when 真实时间流逝，模拟时钟不动
+	time.Sleep(50 * time.Millisecond)
+
+	// then
+	assertEqual(t, 1, cache.Len())
+
+	// when 第 2 秒的清理时条目刚好达到 MaxAge，第 4 秒的清理时已超过
+	mock.Add(5 * time.Second)
+
+	// then 后台清理由模拟时钟触发，条目最多在超过 MaxAge 一个清理周期后被移除
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("entry past MaxAge was not removed by the clean up loop")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMaxAgeCleanupInterval(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		cleanWindow, maxAge, want time.Duration
+	}{
+		{cleanWindow: 0, maxAge: 0, want: 0},
+		{cleanWindow: time.Second, maxAge: 0, want: time.Second},
+		{cleanWindow: 0, maxAge: time.Minute, want: time.Minute},
+		{cleanWindow: time.Hour, maxAge: time.Minute, want: time.Minute},
+		{cleanWindow: time.Second, maxAge: time.Minute, want: time.Second},
+	} {
+		config := Config{CleanWindow: tc.cleanWindow, MaxAge: tc.maxAge}
+		assertEqual(t, tc.want, config.cleanupInterval(), tc)
+	}
+
+	for _, maxAge := range []time.Duration{-time.Second, time.Millisecond} {
+		_, err := New(context.Background(), Config{
+			Shards:             1,
+			LifeWindow:         time.Second,
+			MaxAge:             maxAge,
+			MaxEntriesInWindow: 10,
+			MaxEntrySize:       256,
+		})
+		assertEqual(t, true, err != nil, maxAge)
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed []string
+	cache, err := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 16,
+		MaxEntrySize:       256,
+		StatsEnabled:       true,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			assertEqual(t, Deleted, reason)
+			removed = append(removed, key)
+		},
+	})
+	noError(t, err)
+	defer cache.Close()
+	for _, key := range []string{"user:1:profile", "user:1:session", "user:12:profile", "user:2:profile"} {
+		noError(t, cache.Set(key, []byte(key)))
+	}
+
+	// when
+	count, err := cache.DeletePrefix("user:1:")
+
+	// then
+	noError(t, err)
+	assertEqual(t, 2, count)
+	slices.Sort(removed)
+	assertEqual(t, []string{"user:1:profile", "user:1:session"}, removed)
+	assertEqual(t, 2, cache.Len())
+	assertEqual(t, int64(2), cache.Stats().DelHits)
+	_, err = cache.Get("user:1:profile")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("user:12:profile")
+	noError(t, err)
+
+	// when: 已删除的条目不会再被计数
+	count, err = cache.DeletePrefix("user:1:")
+
+	// then
+	noError(t, err)
+	assertEqual(t, 0, count)
+}
+
+func TestDeletePrefixColdTier(t *testing.T) {
+	t.Parallel()
+
+	// given: key-0 被挤到冷层
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 4,
+		MaxEntrySize:       300 * 1024,
+		HardMaxCacheSize:   1,
+		ColdTier:           &ColdTierConfig{Path: filepath.Join(t.TempDir(), "cold"), Size: 4 << 20},
+	})
+	noError(t, err)
+	defer cache.Close()
+	for i := 0; i < 4; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key-%d", i), blob('a', 300*1024)))
+	}
+	assertEqual(t, 1, cache.cold.len())
+
+	// when
+	count, err := cache.DeletePrefix("key-")
+
+	// then
+	noError(t, err)
+	assertEqual(t, 4, count)
+	assertEqual(t, 0, cache.cold.len())
+	_, err = cache.Get("key-0")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestAppendBounded(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	noError(t, err)
+	defer cache.Close()
+
+	// when: 追加到恰好等于上限
+	noError(t, cache.AppendBounded("key", []byte("abcd"), 8))
+	noError(t, cache.AppendBounded("key", []byte("efgh"), 8))
+
+	// then
+	value, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("abcdefgh"), value)
+
+	// when: 超过上限
+	err = cache.AppendBounded("key", []byte("i"), 8)
+
+	// then: 已有的值保持不变
+	assertEqual(t, ErrAppendLimitExceeded, err)
+	value, err = cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("abcdefgh"), value)
+
+	// 不存在的键同样受上限约束，不限制时与 Append 相同
+	assertEqual(t, ErrAppendLimitExceeded, cache.AppendBounded("new", []byte("123456789"), 8))
+	_, err = cache.Get("new")
+	assertEqual(t, ErrEntryNotFound, err)
+	noError(t, cache.AppendBounded("key", []byte("i"), 0))
+	value, err = cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("abcdefghi"), value)
+}
+
+func TestAsyncOnRemoveCloseDrains(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed atomic.Int32
+	release := make(chan struct{})
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 16,
+		MaxEntrySize:       256,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			<-release
+			removed.Add(1)
+		},
+		AsyncOnRemove: true,
+	})
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		noError(t, cache.Set(key, []byte("value")))
+		noError(t, cache.Delete(key))
+	}
+
+	// when: 回调仍被阻塞时关闭缓存
+	closed := make(chan error)
+	go func() { closed <- cache.Close() }()
+
+	// then: Close 等待队列中的回调执行完毕
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the queued callbacks ran")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	noError(t, <-closed)
+	assertEqual(t, int32(10), removed.Load())
+}
+
+func TestCleanUpSkipsFreshShards(t *testing.T) {
+	t.Parallel()
+
+	// given: 分片 0 中的条目已过期，分片 1 中只有新条目
+	mock := clock.NewMock()
+	cache, err := newBigCache(context.Background(), Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, mock)
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.shards[0].set("old", 1, []byte("value")))
+	mock.Add(6 * time.Second)
+	noError(t, cache.shards[1].set("fresh", 2, []byte("value")))
+
+	// when: 分片 1 的锁被占用时清理
+	cache.shards[1].lock.Lock()
+	done := make(chan struct{})
+	go func() {
+		cache.cleanUp(uint64(mock.Epoch()))
+		close(done)
+	}()
+
+	// then: 清理不等待分片 1 的锁，分片 0 中的过期条目被淘汰
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanUp locked a shard without expirable entries")
+	}
+	cache.shards[1].lock.Unlock()
+	assertEqual(t, 0, cache.shards[0].len())
+	assertEqual(t, 1, cache.shards[1].len())
+
+	// when: 分片 1 的条目也过期后
+	mock.Add(6 * time.Second)
+	cache.cleanUp(uint64(mock.Epoch()))
+
+	// then
+	assertEqual(t, 0, cache.shards[1].len())
+}
+
+func TestCompressor(t *testing.T) {
+	t.Parallel()
+
+	gzipCompressor, err := NewGzipCompressor(gzip.BestSpeed)
+	noError(t, err)
+	for name, compressor := range map[string]Compressor{"gzip": gzipCompressor, "snappy": SnappyCompressor{}} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// given
+			var removed []byte
+			cache, err := New(context.Background(), Config{
+				Shards:               1,
+				LifeWindow:           time.Minute,
+				MaxEntriesInWindow:   10,
+				MaxEntrySize:         256,
+				Compressor:           compressor,
+				CompressionThreshold: 64,
+				Hasher:               hashStub(5),
+				OnRemove: func(key string, entry []byte) {
+					removed = entry
+				},
+			})
+			noError(t, err)
+			defer cache.Close()
+			large := bytes.Repeat([]byte(`{"name":"value"}`), 100)
+
+			// when
+			noError(t, cache.Set("large", large))
+
+			// then: 大值被压缩存储，读取时解压
+			stored, err := cache.shards[0].getEntry(5)
+			noError(t, err)
+			assertEqual(t, valueCompressed, readEntry(stored)[0])
+			assertEqual(t, true, len(readEntry(stored)) < len(large)/4)
+			value, err := cache.Get("large")
+			noError(t, err)
+			assertEqual(t, large, value)
+
+			// when: 低于阈值的值
+			noError(t, cache.Set("small", []byte("value")))
+
+			// then: 以原始形式存储
+			stored, err = cache.shards[0].getEntry(5)
+			noError(t, err)
+			assertEqual(t, append([]byte{valueRaw}, "value"...), readEntry(stored))
+			value, _, err = cache.GetWithInfo("small")
+			noError(t, err)
+			assertEqual(t, []byte("value"), value)
+
+			// when: 追加和更新读写解压后的值
+			noError(t, cache.Append("small", bytes.Repeat([]byte("x"), 100)))
+			noError(t, cache.Update("small", func(old []byte, found bool) ([]byte, error) {
+				return append(old, '!'), nil
+			}))
+
+			// then
+			expected := append(append([]byte("value"), bytes.Repeat([]byte("x"), 100)...), '!')
+			value, err = cache.Get("small")
+			noError(t, err)
+			assertEqual(t, expected, value)
+			stored, err = cache.shards[0].getEntry(5)
+			noError(t, err)
+			assertEqual(t, valueCompressed, readEntry(stored)[0])
+			assertEqual(t, ErrAppendLimitExceeded, cache.AppendBounded("small", []byte("y"), len(expected)))
+
+			// when: 删除时回调收到解压后的值
+			noError(t, cache.Delete("small"))
+
+			// then
+			assertEqual(t, expected, removed)
+		})
+	}
+}
+
+func TestCompressorCorruptValue(t *testing.T) {
+	t.Parallel()
+
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Compressor:         SnappyCompressor{},
+	})
+	noError(t, err)
+	defer cache.Close()
+	hashedKey := cache.hash.Sum64("key")
+	noError(t, cache.shards[0].set("key", hashedKey, []byte{valueCompressed, 0xff, 0xff}))
+
+	_, err = cache.Get("key")
+	assertEqual(t, true, errors.Is(err, ErrCorruptValue))
+
+	_, err = NewGzipCompressor(42)
+	assertEqual(t, true, err != nil)
+}
+
+func TestAppendKeepsTTL(t *testing.T) {
+	t.Parallel()
+
+	for name, compressor := range map[string]Compressor{"raw": nil, "compressed": SnappyCompressor{}} {
+		t.Run(name, func(t *testing.T) {
+			// given
+			mock := clock.NewMock()
+			cache, _ := newBigCache(context.Background(), Config{
+				Shards:             1,
+				LifeWindow:         time.Hour,
+				MaxEntriesInWindow: 10,
+				MaxEntrySize:       256,
+				Compressor:         compressor,
+			}, mock)
+			defer cache.Close()
+			noError(t, cache.SetWithTTL("key", blob('a', 64), 10*time.Second))
+			mock.Add(5 * time.Second)
+
+			// when
+			noError(t, cache.Append("key", blob('b', 64)))
+
+			// then 设置 Compressor 与否，追加都保留条目自己的生存时间
+			value, ttl, err := cache.GetWithTTL("key")
+			noError(t, err)
+			assertEqual(t, append(blob('a', 64), blob('b', 64)...), value)
+			assertEqual(t, 10*time.Second, ttl)
+		})
+	}
+}
+
+func TestMaxEntriesPerShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var evicted []string
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 3,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			assertEqual(t, NoSpace, reason)
+			evicted = append(evicted, key)
+		},
+	})
+	noError(t, err)
+	defer cache.Close()
+	for _, key := range []string{"a", "b", "c"} {
+		noError(t, cache.Set(key, []byte(key)))
+	}
+
+	// when: 覆盖已有的键不受上限影响
+	noError(t, cache.Set("a", []byte("a2")))
+
+	// then
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, 0, len(evicted))
+
+	// when: 超过上限时淘汰最旧的条目，包括 Append 和 Update 写入的新键
+	noError(t, cache.Set("d", []byte("d")))
+	noError(t, cache.Append("e", []byte("e")))
+	noError(t, cache.Update("f", func(old []byte, found bool) ([]byte, error) {
+		return []byte("f"), nil
+	}))
+
+	// then
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, []string{"b", "c", "a"}, evicted)
+	for _, key := range []string{"d", "e", "f"} {
+		_, err := cache.Get(key)
+		noError(t, err)
+	}
+}
+
+func TestMaxEntriesPerShardRejectOnFull(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, err := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 2,
+		RejectOnFull:       true,
+	})
+	noError(t, err)
+	defer cache.Close()
+	noError(t, cache.Set("a", []byte("a")))
+	noError(t, cache.Set("b", []byte("b")))
+
+	// when
+	err = cache.Set("c", []byte("c"))
+
+	// then: 新键被拒绝，已有的条目不受影响
+	assertEqual(t, ErrShardFull, err)
+	assertEqual(t, ErrShardFull, cache.Append("c", []byte("c")))
+	assertEqual(t, 2, cache.Len())
+	noError(t, cache.Set("a", []byte("a2")))
+	value, err := cache.Get("a")
+	noError(t, err)
+	assertEqual(t, []byte("a2"), value)
+
+	// when: 删除后腾出位置
+	noError(t, cache.Delete("b"))
+
+	// then
+	noError(t, cache.Set("c", []byte("c")))
+}
@@ -2,16 +2,22 @@ package bigcache
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	hash2 "github.com/andrewbytecoder/gokit/encoding/hash"
 	"github.com/andrewbytecoder/gokit/math"
+	"github.com/andrewbytecoder/gokit/swag"
 	"github.com/andrewbytecoder/gokit/timer/clock"
 )
 
 const (
-	minimumEntriesInShard = 10 // Minimum number of entries in single shard
+	minimumEntriesInShard  = 10   // Minimum number of entries in single shard
+	asyncOnRemoveQueueSize = 1024 // Maximum number of pending removal callbacks in AsyncOnRemove mode
 )
 
 // BigCache 是一个快速、并发、可淘汰的缓存，用于存储大量条目而不会影响性能
@@ -23,8 +29,14 @@ type BigCache struct {
 	clock      clock.Clock   // 时钟接口，用于获取时间
 	hash       hash2.Hasher  // 哈希函数接口
 	config     Config        // 缓存配置
-	shardMask  uint64        // 分片掩码，用于快速计算分片索引
 	close      chan struct{} // 关闭信号通道
+	removals   chan func()   // 异步模式下待执行的移除回调队列
+	drained    chan struct{} // 异步模式下 runRemovals 执行完剩余回调并退出后关闭
+	closed     atomic.Bool   // 缓存是否已关闭
+	closeOnce  sync.Once     // 保证只关闭一次
+	closeErr   error         // 关闭时的错误信息
+	flights    flightGroup   // GetOrSet 正在进行的计算
+	cold       *coldTier     // 磁盘冷层，未配置时为nil
 }
 
 // New 初始化 BigCache 的新实例
@@ -54,6 +66,22 @@ func NewBigCache(config Config) (*BigCache, error) {
 	return newBigCache(context.Background(), config, clock.New())
 }
 
+// NewWithClock 使用给定的时钟创建 BigCache 实例，用于在测试中通过推进时钟确定性地控制条目过期
+// 后台清理（CleanWindow 和 MaxAge）的周期同样由该时钟驱动，使用模拟时钟时只有推进时钟才会触发清理
+// 参数:
+//
+//	ctx: 上下文，用于控制清理goroutine的生命周期
+//	config: 缓存配置
+//	clk: 时钟接口，如 clock.NewMock() 返回的模拟时钟
+//
+// 返回值:
+//
+//	*BigCache: BigCache实例指针
+//	error: 错误信息
+func NewWithClock(ctx context.Context, config Config, clk clock.Clock) (*BigCache, error) {
+	return newBigCache(ctx, config, clk)
+}
+
 // newBigCache BigCache 的核心构造函数
 // 参数:
 //
@@ -72,21 +100,51 @@ func newBigCache(ctx context.Context, config Config, clock clock.Clock) (*BigCac
 	if config.MaxEntrySize < 0 {
 		return nil, errors.New("MaxEntrySize must be >= 0")
 	}
+	if config.MaxEntriesPerShard < 0 {
+		return nil, errors.New("MaxEntriesPerShard must be >= 0")
+	}
+	if config.CompressionThreshold < 0 {
+		return nil, errors.New("CompressionThreshold must be >= 0")
+	}
+	if config.MaxValueSize < 0 {
+		return nil, errors.New("MaxValueSize must be >= 0")
+	}
+	if config.Compressor != nil && config.OversizeEntryPolicy == Truncate {
+		return nil, errors.New("OversizeEntryPolicy Truncate can not be used with a Compressor")
+	}
 	if config.MaxEntriesInWindow < 0 {
 		return nil, errors.New("MaxEntriesInWindow must be >= 0")
 	}
 	if config.HardMaxCacheSize < 0 {
 		return nil, errors.New("HardMaxCacheSize must be >= 0")
 	}
+	if config.MaxAge < 0 {
+		return nil, errors.New("MaxAge must be >= 0")
+	}
+	if config.MaxAge > 0 && config.MaxAge < time.Second {
+		return nil, errors.New("MaxAge must be at least one second, bigcache has a one second resolution")
+	}
 
 	lifeWindowSeconds := uint64(config.LifeWindow.Seconds())
 	if config.CleanWindow > 0 && lifeWindowSeconds == 0 {
 		return nil, errors.New("LifeWindow must be > 0 when CleanWindow is set")
 	}
+	if config.LifeWindow == 0 && !config.AllowNeverExpire {
+		return nil, errors.New("LifeWindow must be > 0 unless AllowNeverExpire is set")
+	}
 
 	if config.Hasher == nil {
 		config.Hasher = hash2.NewFnv64()
 	}
+	if config.ShardSelector == nil {
+		config.ShardSelector = maskShardSelector
+	}
+
+	var cached *cachedClock
+	if config.CachedClock {
+		cached = newCachedClock(clock)
+		clock = cached
+	}
 
 	cache := &BigCache{
 		shards:     make([]*cacheShard, config.Shards),
@@ -94,11 +152,10 @@ func newBigCache(ctx context.Context, config Config, clock clock.Clock) (*BigCac
 		clock:      clock,
 		hash:       config.Hasher,
 		config:     config,
-		shardMask:  uint64(config.Shards - 1),
 		close:      make(chan struct{}),
 	}
 
-	var onRemove func(wrappedEntry []byte, reason RemoveReason)
+	var onRemove onRemoveCallback
 	if config.OnRemoveWithMetadata != nil {
 		onRemove = cache.provideOnRemoveWithMetadata
 	} else if config.OnRemove != nil {
@@ -108,20 +165,49 @@ func newBigCache(ctx context.Context, config Config, clock clock.Clock) (*BigCac
 	} else {
 		onRemove = cache.notProvideOnRemove
 	}
+	if config.AsyncOnRemove && (config.OnRemoveWithMetadata != nil || config.OnRemove != nil || config.OnRemoveWithReason != nil) {
+		onRemove = cache.asyncOnRemove(onRemove)
+		cache.removals = make(chan func(), asyncOnRemoveQueueSize)
+		cache.drained = make(chan struct{})
+		go cache.runRemovals()
+	}
+	if config.ColdTier != nil {
+		cold, err := newColdTier(*config.ColdTier, config.Logger)
+		if err != nil {
+			return nil, err
+		}
+		cache.cold = cold
+		onRemove = cold.onRemove(onRemove) // 冷层在异步回调之前同步处理，淘汰的条目在释放分片锁前写入
+	}
 	for i := 0; i < config.Shards; i++ {
-		cache.shards[i] = initNewShard(config, onRemove, clock)
+		shard, err := initNewShard(config, onRemove, cache.cold, clock)
+		if err != nil {
+			for _, s := range cache.shards[:i] {
+				s.close()
+			}
+			if cache.cold != nil {
+				cache.cold.close()
+			}
+			return nil, err
+		}
+		cache.shards[i] = shard
+	}
+
+	if cached != nil {
+		cached.start(ctx, cache.close)
 	}
 
-	if config.CleanWindow > 0 {
+	if interval := config.cleanupInterval(); interval > 0 {
+		// 清理周期由 cache.clock 驱动，计时器在启动goroutine前创建，因此之后推进模拟时钟一定会触发清理
+		ticker := cache.clock.Ticker(interval)
 		go func() {
-			ticker := time.NewTicker(config.CleanWindow)
 			defer ticker.Stop()
 			for {
 				select {
 				case <-ctx.Done():
 					return
-				case t := <-ticker.C: // <-ticker.C 发送当前时间点 time.Time类型数据
-					cache.cleanUp(uint64(t.Unix()))
+				case <-ticker.C:
+					cache.cleanUp(uint64(cache.clock.Epoch()))
 				case <-cache.close:
 					return
 				}
@@ -129,21 +215,90 @@ func newBigCache(ctx context.Context, config Config, clock clock.Clock) (*BigCac
 		}()
 	}
 
+	if ctx.Done() != nil {
+		// 上下文取消时自动关闭缓存
+		go func() {
+			select {
+			case <-ctx.Done():
+				cache.Close()
+			case <-cache.close:
+			}
+		}()
+	}
+
 	return cache, nil
 }
 
 // Close 用于在使用完缓存后发出关闭信号
 // 这允许清理goroutine退出，并确保不保留对缓存的引用，从而允许GC回收条目缓存
+// 启用 OffHeap 时还会释放所有分片的堆外内存，之后缓存中不再有任何条目
+// 启用 AsyncOnRemove 时会等待已入队的移除回调执行完毕后再返回，因此不能在移除回调中调用 Close
+// 关闭后读写操作返回 ErrCacheClosed，New 传入的上下文取消时缓存也会自动关闭，重复调用 Close 是安全的
 // 返回值:
 //
 //	error: 错误信息
 func (c *BigCache) Close() error {
-	close(c.close)
-	return nil
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		close(c.close)
+		if c.drained != nil { // 等待已入队的移除回调执行完毕
+			<-c.drained
+		}
+		var errs []error
+		if c.cold != nil {
+			errs = append(errs, c.cold.close())
+		}
+		if !c.config.OffHeap {
+			c.closeErr = errors.Join(errs...)
+			return
+		}
+		for _, shard := range c.shards {
+			errs = append(errs, shard.close())
+		}
+		c.closeErr = errors.Join(errs...)
+	})
+	return c.closeErr
+}
+
+// Flush 等待 AsyncOnRemove 模式下此前排队的所有移除回调执行完毕，未启用异步回调时直接返回
+// 可用于测试断言前或关闭缓存前确保回调都已执行
+// 参数:
+//
+//	ctx: 上下文，用于限制等待时间
+//
+// 返回值:
+//
+//	error: ctx 先结束时返回 ctx.Err()，缓存已关闭时返回 ErrCacheClosed
+func (c *BigCache) Flush(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	if c.removals == nil {
+		return nil
+	}
+
+	// 回调按入队顺序依次执行，屏障回调执行时之前的回调都已执行完毕
+	done := make(chan struct{})
+	select {
+	case c.removals <- func() { close(done) }:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.close:
+		return ErrCacheClosed
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.close:
+		return ErrCacheClosed
+	}
 }
 
 // Get 根据键读取条目
 // 当给定键不存在条目时返回 ErrEntryNotFound 错误
+// 配置了 ColdTier 时，内存未命中会继续查找冷层，命中的条目被提升回内存
 // 参数:
 //
 //	key: 要查找的键
@@ -153,9 +308,218 @@ func (c *BigCache) Close() error {
 //	[]byte: 条目数据
 //	error: 错误信息
 func (c *BigCache) Get(key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, err := shard.get(key, hashedKey)
+	if c.cold != nil && errors.Is(err, ErrEntryNotFound) {
+		entry, err = c.getCold(shard, key, hashedKey)
+	}
+	return c.decompressResult(entry, err)
+}
+
+// GetNonBlocking 根据键读取条目，分片锁被写者占用时不等待而是立即返回
+// 用于对尾延迟敏感的读取：写入突发时宁可放弃本次读取，也不阻塞在分片锁上
+// 注意：checked 为 false 表示未能检查该键，并不代表条目不存在，调用方不应将其视为未命中
+// 参数:
+//
+//	key: 要查找的键
+//
+// 返回值:
+//
+//	[]byte: 条目数据
+//	bool: 是否完成了查找，为 false 时条目数据为 nil 且错误为 nil
+//	error: 完成查找但条目不存在时返回 ErrEntryNotFound
+func (c *BigCache) GetNonBlocking(key string) ([]byte, bool, error) {
+	if c.closed.Load() {
+		return nil, true, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.get(key, hashedKey)
+	entry, checked, err := shard.getNonBlocking(key, hashedKey)
+	if checked {
+		entry, err = c.decompressResult(entry, err)
+	}
+	return entry, checked, err
+}
+
+// BatchResult 是 GetBatch 中单个键的查询结果
+type BatchResult struct {
+	Key   string // 查询的键
+	Value []byte // 条目数据，查询失败时为nil
+	Err   error  // 该键的错误信息，如 ErrEntryNotFound
+}
+
+// GetBatch 批量读取多个键，结果顺序与 keys 一致，每个键的未命中或错误记录在对应结果的 Err 中
+// 属于同一分片的键只获取一次该分片的读锁
+// 参数:
+//
+//	keys: 要查找的键
+//
+// 返回值:
+//
+//	[]BatchResult: 每个键的查询结果
+//	error: 整体失败时的错误信息，如缓存已关闭时返回 ErrCacheClosed
+func (c *BigCache) GetBatch(keys []string) ([]BatchResult, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+
+	results := make([]BatchResult, len(keys))
+	batch := make([]batchItem, len(keys))
+	for i, key := range keys {
+		results[i].Key = key
+		key = c.normalizeKey(key)
+		hashedKey := c.hash.Sum64(key)
+		batch[i] = batchItem{shard: c.config.ShardSelector(hashedKey, len(c.shards)), index: i, key: key, hashedKey: hashedKey}
+	}
+
+	c.forEachShardBatch(batch, func(shard *cacheShard, items []batchItem) {
+		shard.getBatch(results, items)
+	})
+	if c.config.Compressor != nil {
+		for i := range results {
+			results[i].Value, results[i].Err = c.decompressResult(results[i].Value, results[i].Err)
+		}
+	}
+	return results, nil
+}
+
+// GetMulti 批量读取多个键，只返回命中的条目，不存在的键被跳过
+// 与 GetBatch 相同，属于同一分片的键只获取一次该分片的读锁
+// 参数:
+//
+//	keys: 要查找的键
+//
+// 返回值:
+//
+//	map[string][]byte: 命中的键及其条目数据
+//	error: 缓存已关闭时返回 ErrCacheClosed，其他读取错误合并后返回，命中的条目仍然有效
+func (c *BigCache) GetMulti(keys []string) (map[string][]byte, error) {
+	results, err := c.GetBatch(keys)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string][]byte, len(results))
+	var errs []error
+	for _, result := range results {
+		switch {
+		case result.Err == nil:
+			found[result.Key] = result.Value
+		case !errors.Is(result.Err, ErrEntryNotFound):
+			errs = append(errs, fmt.Errorf("key %q: %w", result.Key, result.Err))
+		}
+	}
+	return found, errors.Join(errs...)
+}
+
+// SetMulti 批量写入多个键值对，属于同一分片的键只获取一次该分片的写锁
+// 某个键写入失败不影响其他键，失败的键的错误合并后返回
+// 参数:
+//
+//	items: 要写入的键值对
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) SetMulti(items map[string][]byte) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	var errs []error
+	batch := make([]batchItem, 0, len(items))
+	for key, entry := range items {
+		key = c.normalizeKey(key)
+		hashedKey := c.hash.Sum64(key)
+		stored, err := c.compress(entry)
+		if err != nil { // 无效的值被跳过，不影响其他键
+			errs = append(errs, fmt.Errorf("key %q: %w", key, err))
+			continue
+		}
+		batch = append(batch, batchItem{shard: c.config.ShardSelector(hashedKey, len(c.shards)), key: key, hashedKey: hashedKey, entry: stored})
+	}
+
+	c.forEachShardBatch(batch, func(shard *cacheShard, items []batchItem) {
+		if err := shard.setBatch(items); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// forEachShardBatch 按分片下标对批量操作的键做计数排序，使同一分片的键相邻，然后对每个分片调用一次 fn
+// 相比按分片建立 map 分组或比较排序，整批只需固定次数的分配和线性时间
+// 参数:
+//
+//	batch: 批量操作的键
+//	fn: 处理属于同一分片的键
+func (c *BigCache) forEachShardBatch(batch []batchItem, fn func(shard *cacheShard, items []batchItem)) {
+	ends := make([]int, len(c.shards)) // 计数排序后 ends[i] 为分片 i 的键的结束位置
+	for _, item := range batch {
+		ends[item.shard]++
+	}
+	for i := 1; i < len(ends); i++ {
+		ends[i] += ends[i-1]
+	}
+	sorted := make([]batchItem, len(batch))
+	for i := len(batch) - 1; i >= 0; i-- { // 倒序放置，保持同一分片内键的原始顺序
+		ends[batch[i].shard]--
+		sorted[ends[batch[i].shard]] = batch[i]
+	}
+	// 此时 ends[i] 为分片 i 的起始位置
+	for start := 0; start < len(sorted); {
+		shard := sorted[start].shard
+		end := len(sorted)
+		if shard+1 < len(ends) {
+			end = ends[shard+1]
+		}
+		fn(c.shards[shard], sorted[start:end])
+		start = end
+	}
+}
+
+// GetOrSet 根据键读取条目，条目不存在时调用 compute 计算并保存其结果
+// 同一个键上并发的调用只会执行一次 compute，其余调用等待并共享其结果，避免缓存击穿
+// compute 返回的错误会返回给所有等待的调用方，且不会被缓存，下一次调用会重新计算
+// 参数:
+//
+//	key: 键
+//	compute: 条目不存在时计算值的函数
+//
+// 返回值:
+//
+//	[]byte: 条目数据，每个调用方得到独立的副本
+//	error: 错误信息，compute 或保存失败时返回其错误
+func (c *BigCache) GetOrSet(key string, compute func() ([]byte, error)) ([]byte, error) {
+	entry, err := c.Get(key)
+	if !errors.Is(err, ErrEntryNotFound) {
+		return entry, err
+	}
+
+	entry, err = c.flights.do(c.normalizeKey(key), func() ([]byte, error) {
+		// 未命中后到开始计算之间，其他调用方的计算可能已经保存了结果
+		if entry, err := c.Get(key); !errors.Is(err, ErrEntryNotFound) {
+			return entry, err
+		}
+		entry, err := compute()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(key, entry); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 计算结果由所有等待的调用方共享，每个调用方复制一份，修改时互不影响
+	return append([]byte(nil), entry...), nil
 }
 
 // GetWithInfo 根据键读取条目并返回响应信息
@@ -170,9 +534,68 @@ func (c *BigCache) Get(key string) ([]byte, error) {
 //	Response: 响应信息
 //	error: 错误信息
 func (c *BigCache) GetWithInfo(key string) ([]byte, Response, error) {
+	if c.closed.Load() {
+		return nil, Response{}, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.getWithInfo(key, hashedKey)
+	entry, resp, err := shard.getWithInfo(key, hashedKey)
+	entry, err = c.decompressResult(entry, err)
+	return entry, resp, err
+}
+
+// NoExpiration is returned by GetWithTTL for entries that never expire
+const NoExpiration time.Duration = 1<<63 - 1
+
+// GetWithTTL 根据键读取条目，并返回条目的剩余生存时间，便于调用方在条目过期前主动刷新
+// 剩余时间以秒为精度，根据条目的时间戳、生存时间窗口和当前时间计算；
+// 为 0 表示条目在当前这一秒内仍然有效，为负数表示条目已经过期但尚未被清理，
+// 条目永不过期（LifeWindow 为 0 且设置了 AllowNeverExpire）时返回 NoExpiration
+// 参数:
+//
+//	key: 要查找的键
+//
+// 返回值:
+//
+//	[]byte: 条目数据
+//	time.Duration: 剩余生存时间
+//	error: 错误信息，条目不存在时返回 ErrEntryNotFound
+func (c *BigCache) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	if c.closed.Load() {
+		return nil, 0, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, ttl, err := shard.getWithTTL(key, hashedKey)
+	entry, err = c.decompressResult(entry, err)
+	return entry, ttl, err
+}
+
+// TryGet 根据键读取条目并返回条目是否新鲜
+// 当条目已超过 LifeWindow 但尚未被清理时，仍返回条目数据且 fresh 为 false，
+// 调用方可以据此在一次查找中决定先返回旧值再刷新
+// 当给定键不存在条目时返回 ErrEntryNotFound 错误
+// 参数:
+//
+//	key: 要查找的键
+//
+// 返回值:
+//
+//	[]byte: 条目数据
+//	bool: 条目是否仍在生存时间窗口内
+//	error: 错误信息
+func (c *BigCache) TryGet(key string) ([]byte, bool, error) {
+	if c.closed.Load() {
+		return nil, false, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, fresh, err := shard.tryGet(key, hashedKey)
+	entry, err = c.decompressResult(entry, err)
+	return entry, fresh, err
 }
 
 // Set 在键下保存条目
@@ -185,11 +608,199 @@ func (c *BigCache) GetWithInfo(key string) ([]byte, Response, error) {
 //
 //	error: 错误信息
 func (c *BigCache) Set(key string, entry []byte) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, err := c.compress(entry)
+	if err != nil {
+		return err
+	}
+	return shard.set(key, hashedKey, entry)
+}
+
+// GetSet 在键下保存条目并返回之前的值，读取和写入在同一次分片写锁内完成，
+// 避免 Get 后再 Set 时其他写入插入到两者之间，适用于版本号等需要交换语义的场景
+// 与 Get 相同，未被清理的过期条目也作为旧值返回；冷层中的旧值不作为旧值返回
+// 参数:
+//
+//	key: 键
+//	entry: 要保存的条目数据
+//
+// 返回值:
+//
+//	[]byte: 之前的值，键不存在时为 nil
+//	bool: 键之前是否存在
+//	error: 错误信息，写入失败时缓存保持不变
+func (c *BigCache) GetSet(key string, entry []byte) (old []byte, existed bool, err error) {
+	if c.closed.Load() {
+		return nil, false, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if entry, err = c.compress(entry); err != nil {
+		return nil, false, err
+	}
+	old, existed, err = shard.getSet(key, hashedKey, entry)
+	if err != nil || !existed {
+		return old, existed, err
+	}
+	// 新值已经写入，旧值无法解码时仍报告错误，调用方据此知道旧值不可用
+	old, err = c.decompress(old)
+	return old, existed, err
+}
+
+// SetWithTTL 在键下保存条目，条目使用自己的生存时间而不是 LifeWindow
+// 生存时间以秒为精度，不足一秒按一秒计算；ttl 小于等于 0 时与 Set 相同
+// 注意：过期清理仍按写入顺序进行，生存时间较短的条目在读取时立即视为过期，
+// 但其占用的空间要等排在前面的条目被清理后才会释放
+// 参数:
+//
+//	key: 键
+//	entry: 要保存的条目数据
+//	ttl: 条目的生存时间
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) SetWithTTL(key string, entry []byte, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
+	entry, err := c.compress(entry)
+	if err != nil {
+		return err
+	}
+	return shard.setWithTTL(key, hashedKey, entry, ttlSeconds(ttl))
+}
+
+// ttlSeconds 将生存时间转换为条目头部中的秒数，向上取整并限制在 uint32 范围内
+func ttlSeconds(ttl time.Duration) uint32 {
+	if ttl <= 0 {
+		return 0
+	}
+	seconds := ttl / time.Second
+	if ttl%time.Second != 0 {
+		seconds++
+	}
+	return uint32(min(seconds, maxTTLSeconds))
+}
+
+// SetWithAffinity 在键下保存条目，但按 affinityKey 而不是 key 选择分片
+// 具有相同 affinityKey 的键总是落在同一个分片中，便于在一个分片锁内批量操作相关的键
+// 注意：这些条目只能通过 GetWithAffinity、DeleteWithAffinity 和 KeyMetadataWithAffinity 以相同的 affinityKey 访问，
+// Get 和 Delete 按 key 选择分片，通常找不到它们；同一 affinityKey 下的键过多时会使该分片
+// 远大于其他分片，该分片更早触发淘汰，锁竞争也集中在该分片上
+// 参数:
+//
+//	affinityKey: 用于选择分片的键
+//	key: 键
+//	entry: 要保存的条目数据
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) SetWithAffinity(affinityKey, key string, entry []byte) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getAffinityShard(affinityKey)
+	entry, err := c.compress(entry)
+	if err != nil {
+		return err
+	}
 	return shard.set(key, hashedKey, entry)
 }
 
+// GetWithAffinity 读取由 SetWithAffinity 以相同 affinityKey 保存的条目
+// 参数:
+//
+//	affinityKey: 用于选择分片的键
+//	key: 要查找的键
+//
+// 返回值:
+//
+//	[]byte: 条目数据
+//	error: 错误信息，条目不存在时返回 ErrEntryNotFound
+func (c *BigCache) GetWithAffinity(affinityKey, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getAffinityShard(affinityKey)
+	return c.decompressResult(shard.get(key, hashedKey))
+}
+
+// DeleteWithAffinity 删除由 SetWithAffinity 以相同 affinityKey 保存的条目
+// 参数:
+//
+//	affinityKey: 用于选择分片的键
+//	key: 要删除的键
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) DeleteWithAffinity(affinityKey, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getAffinityShard(affinityKey)
+	return c.delCold(shard.del(hashedKey), hashedKey)
+}
+
+// SetLarge 在键下保存条目，条目大于分片的最大容量时允许该分片一次性扩容，
+// 扩容后分片的最大容量不超过 HardMaxCacheSize，超过时仍返回错误
+// 注意：扩容后的分片会一直保持更大的最大容量，单个大条目可能独占整个分片，
+// 导致同一分片中的其他条目被淘汰，且总内存可能超过 HardMaxCacheSize
+// 参数:
+//
+//	key: 键
+//	entry: 要保存的条目数据
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) SetLarge(key string, entry []byte) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, err := c.compress(entry)
+	if err != nil {
+		return err
+	}
+	if c.config.HardMaxCacheSize > 0 {
+		shard.growFor(key, entry, swag.ConvertMBToBytes(c.config.HardMaxCacheSize))
+	}
+	return shard.set(key, hashedKey, entry)
+}
+
+// Reserve 为批量加载预留空间，按 totalEntries/Shards 重建每个分片的hashmap，
+// 使之后写入这些条目时不再触发哈希表扩容
+// 注意：字节队列不会预先扩容，重建期间会持有各分片的写锁
+// 参数:
+//
+//	totalEntries: 预计写入的条目总数
+func (c *BigCache) Reserve(totalEntries int) {
+	perShard := (totalEntries + len(c.shards) - 1) / len(c.shards)
+	for _, shard := range c.shards {
+		shard.reserve(perShard)
+	}
+}
+
 // Append 如果键存在则在键下追加条目，否则行为与 Set() 相同
 // 使用 Append() 可以以锁优化的方式在同一个键下连接多个条目
 // 参数:
@@ -201,11 +812,137 @@ func (c *BigCache) Set(key string, entry []byte) error {
 //
 //	error: 错误信息
 func (c *BigCache) Append(key string, entry []byte) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
+	if c.config.Compressor != nil {
+		return c.appendCompressed(shard, key, hashedKey, entry, 0)
+	}
 	return shard.append(key, hashedKey, entry)
 }
 
+// AppendBounded 与 Append 相同，但追加后的值超过 maxTotal 字节时返回 ErrAppendLimitExceeded 且不修改已有的值
+// 长度检查和追加在同一次分片写锁内完成，可以为持续追加的键设置可预期的大小上限
+// 参数:
+//
+//	key: 键
+//	entry: 要追加的条目数据
+//	maxTotal: 追加后值的最大字节数，<= 0 表示不限制
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) AppendBounded(key string, entry []byte, maxTotal int) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.config.Compressor != nil {
+		return c.appendCompressed(shard, key, hashedKey, entry, maxTotal)
+	}
+	return shard.appendBounded(key, hashedKey, entry, maxTotal)
+}
+
+// AppendSegment 在键下追加一个带长度前缀的分段，键不存在时创建只含该分段的条目
+// 同一个键只应通过 AppendSegment 写入，之后可以用 GetSegments 按追加顺序取回各个分段
+// 参数:
+//
+//	key: 键
+//	segment: 要追加的分段数据
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) AppendSegment(key string, segment []byte) error {
+	framed := make([]byte, 0, binary.MaxVarintLen64+len(segment))
+	framed = binary.AppendUvarint(framed, uint64(len(segment)))
+	framed = append(framed, segment...)
+	return c.Append(key, framed)
+}
+
+// GetSegments 读取键下通过 AppendSegment 追加的所有分段，顺序与追加顺序一致
+// 参数:
+//
+//	key: 要查找的键
+//
+// 返回值:
+//
+//	[][]byte: 各个分段，与缓存中的数据互不影响
+//	error: 错误信息，键不存在时返回 ErrEntryNotFound，值不是分段序列时返回 ErrMalformedSegments
+func (c *BigCache) GetSegments(key string) ([][]byte, error) {
+	value, err := c.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments [][]byte
+	for len(value) > 0 {
+		length, n := binary.Uvarint(value)
+		if n <= 0 || length > uint64(len(value)-n) {
+			return nil, ErrMalformedSegments
+		}
+		value = value[n:]
+		// 限制容量，避免调用方 append 时改写后面的分段
+		segments = append(segments, value[:length:length])
+		value = value[length:]
+	}
+	return segments, nil
+}
+
+// Update 原子地读取-修改-写回键下的条目
+// 在分片写锁内读取当前值（不存在时 found 为 false，old 为 nil），调用 fn 并保存其返回的新值，
+// fn 返回 nil, nil 时删除该键，返回错误时缓存保持不变并将错误原样返回
+// 写回的条目保留通过 SetWithTTL 设置的生存时间，过期时间从写回时重新计算
+// 注意：fn 在持有分片写锁时执行，应当尽快返回，且不能再调用同一个缓存的方法，否则可能死锁
+// 参数:
+//
+//	key: 键
+//	fn: 根据旧值计算新值的函数
+//
+// 返回值:
+//
+//	error: 错误信息
+func (c *BigCache) Update(key string, fn func(old []byte, found bool) ([]byte, error)) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.config.Compressor != nil {
+		fn = c.compressUpdate(fn)
+	}
+	return shard.update(key, hashedKey, fn)
+}
+
+// Touch 将键下条目的时间戳更新为当前时间，使其生存期重新计算
+// 只原地改写条目头部的时间戳，不复制值，对大条目比 Get+Set 开销小得多
+// 注意：条目在队列中的位置不变，清理按队列顺序进行并在遇到第一个未过期条目时停止，
+// 因此被 Touch 的条目会推迟排在它后面的过期条目的清理；
+// 空间不足时仍按写入顺序淘汰，Touch 只延长生存期，不能让条目在容量淘汰中存活更久；
+// 通过 SetWithTTL 写入的条目按其自己的生存时间重新计算
+// 参数:
+//
+//	key: 键
+//
+// 返回值:
+//
+//	error: 错误信息，键不存在时返回 ErrEntryNotFound
+func (c *BigCache) Touch(key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	return shard.touch(key, hashedKey)
+}
+
 // Delete 删除指定键
 // 参数:
 //
@@ -215,9 +952,57 @@ func (c *BigCache) Append(key string, entry []byte) error {
 //
 //	error: 错误信息
 func (c *BigCache) Delete(key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	key = c.normalizeKey(key)
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.del(hashedKey)
+	return c.delCold(shard.del(hashedKey), hashedKey)
+}
+
+// DeleteIfExists 删除指定键，键不存在时不视为错误，适用于需要幂等删除的场景
+// 参数:
+//
+//	key: 要删除的键
+//
+// 返回值:
+//
+//	bool: 键存在并被删除时返回true
+//	error: 错误信息，键不存在时为nil
+func (c *BigCache) DeleteIfExists(key string) (bool, error) {
+	err := c.Delete(key)
+	if errors.Is(err, ErrEntryNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeletePrefix 删除所有键以 prefix 开头的条目，如使用 "user:123:" 删除某个用户的全部键
+// 逐个分片加写锁遍历所有条目，分片越大持锁时间越长，不适合在热路径上频繁调用；
+// 每个被删除的条目都会以 Deleted 原因触发删除回调。prefix 同样经过 KeyNormalizer 处理，
+// 因此只适用于保持前缀关系的规范化函数，如 strings.ToLower
+// 参数:
+//
+//	prefix: 键的前缀，为空时删除所有条目
+//
+// 返回值:
+//
+//	int: 删除的条目数量，包括冷层中的条目
+//	error: 缓存已关闭时返回 ErrCacheClosed
+func (c *BigCache) DeletePrefix(prefix string) (int, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	prefix = c.normalizeKey(prefix)
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.deletePrefix(prefix)
+	}
+	if c.cold != nil {
+		removed += c.cold.removePrefix(prefix)
+	}
+	return removed, nil
 }
 
 // Reset 清空所有缓存分片
@@ -225,9 +1010,15 @@ func (c *BigCache) Delete(key string) error {
 //
 //	error: 错误信息
 func (c *BigCache) Reset() error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
 	for _, shard := range c.shards {
 		shard.reset(c.config)
 	}
+	if c.cold != nil {
+		return c.cold.reset()
+	}
 	return nil
 }
 
@@ -236,6 +1027,9 @@ func (c *BigCache) Reset() error {
 //
 //	error: 错误信息
 func (c *BigCache) ResetStats() error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
 	for _, shard := range c.shards {
 		shard.resetStats()
 	}
@@ -254,6 +1048,18 @@ func (c *BigCache) Len() int {
 	return cacheLen
 }
 
+// ShardLens 返回每个分片中的条目数量，下标与分片序号一致
+// 返回值:
+//
+//	[]int: 各分片的条目数量
+func (c *BigCache) ShardLens() []int {
+	lens := make([]int, len(c.shards))
+	for i, shard := range c.shards {
+		lens[i] = shard.len()
+	}
+	return lens
+}
+
 // Capacity 返回缓存中存储的字节数
 // 返回值:
 //
@@ -266,6 +1072,19 @@ func (c *BigCache) Capacity() int {
 	return cacheLen
 }
 
+// PeakCapacity 返回缓存曾经分配过的最大字节数，即所有分片历史最大容量之和
+// 可用于判断缓存是否过度预分配或已经达到了上限
+// 返回值:
+//
+//	int: 所有分片历史最大容量之和
+func (c *BigCache) PeakCapacity() int {
+	var peak int
+	for _, shard := range c.shards {
+		peak += shard.peakCapacity()
+	}
+	return peak
+}
+
 // Stats 返回缓存的统计信息
 // 返回值:
 //
@@ -283,6 +1102,29 @@ func (c *BigCache) Stats() Stats {
 	return s
 }
 
+// Snapshot 一次性返回缓存的统计信息、条目数量和内存占用
+// 每个分片只加锁一次，其各项数值来自同一时刻，分片之间则依次读取，并发写入时不同分片的数值可能来自不同时刻
+// 返回值:
+//
+//	Snapshot: 缓存的统计信息和大小
+func (c *BigCache) Snapshot() Snapshot {
+	snapshot := Snapshot{ShardLens: make([]int, len(c.shards))}
+	for i, shard := range c.shards {
+		s := shard.stateSnapshot()
+		snapshot.ShardLens[i] = s.len
+		snapshot.Len += s.len
+		snapshot.Capacity += s.capacity
+		snapshot.Used += s.used
+		snapshot.PeakCapacity += s.peakCapacity
+		snapshot.Stats.Hits += s.stats.Hits
+		snapshot.Stats.Misses += s.stats.Misses
+		snapshot.Stats.DelHits += s.stats.DelHits
+		snapshot.Stats.DelMisses += s.stats.DelMisses
+		snapshot.Stats.Collisions += s.stats.Collisions
+	}
+	return snapshot
+}
+
 // KeyMetadata 返回缓存资源被请求的次数
 // 参数:
 //
@@ -292,11 +1134,29 @@ func (c *BigCache) Stats() Stats {
 //
 //	Metadata: 键的元数据信息
 func (c *BigCache) KeyMetadata(key string) Metadata {
+	key = c.normalizeKey(key)
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
 	return shard.getKeyMetadataWithLock(hashedKey)
 }
 
+// KeyMetadataWithAffinity 返回由 SetWithAffinity 以相同 affinityKey 保存的键被请求的次数，
+// 这些键不在 key 的哈希值对应的分片中，KeyMetadata 查不到它们的统计信息
+// 参数:
+//
+//	affinityKey: 用于选择分片的键
+//	key: 要查询的键
+//
+// 返回值:
+//
+//	Metadata: 键的元数据信息
+func (c *BigCache) KeyMetadataWithAffinity(affinityKey, key string) Metadata {
+	key = c.normalizeKey(key)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getAffinityShard(affinityKey)
+	return shard.getKeyMetadataWithLock(hashedKey)
+}
+
 // Iterator 返回迭代器函数，用于遍历整个缓存中的 EntryInfo
 // 返回值:
 //
@@ -305,6 +1165,68 @@ func (c *BigCache) Iterator() *EntryInfoIterator {
 	return newIterator(c)
 }
 
+// ForEachSnapshot 对缓存中的每个条目调用 fn，fn 返回 false 时停止遍历
+// 逐个分片在读锁内复制该分片所有条目的键和值，释放锁后再对副本调用 fn，
+// 因此 fn 执行期间不持有任何锁，可以安全地读写同一个缓存
+// 注意：每个分片的条目会被完整复制一次，额外内存约等于最大分片中所有键和值的大小；
+// 遍历结果是各分片在复制时刻的快照，不反映遍历过程中的修改
+// 参数:
+//
+//	fn: 对每个条目调用的函数，value 是副本，可以保留
+//
+// 返回值:
+//
+//	error: 缓存已关闭时返回 ErrCacheClosed
+func (c *BigCache) ForEachSnapshot(fn func(key string, value []byte) bool) error {
+	for _, shard := range c.shards {
+		if c.closed.Load() {
+			return ErrCacheClosed
+		}
+		for _, entry := range shard.snapshot() {
+			if !fn(entry.key, c.readStoredValue(entry.value)) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Migrate 将 src 中的所有条目复制到 dst 中，保留条目的原始时间戳和 SetWithTTL 设置的生存时间
+// dst 可以使用与 src 不同的配置（例如分片数量），没有自己生存时间的条目在 dst 中按 dst 的 LifeWindow 计算过期时间
+// 值以解码后的形式复制，src 和 dst 可以使用不同的 Compressor
+// 参数:
+//
+//	dst: 目标缓存
+//	src: 源缓存
+//
+// 返回值:
+//
+//	int: 复制的条目数量
+//	error: 错误信息
+func Migrate(dst, src *BigCache) (int, error) {
+	var copied int
+	iterator := src.Iterator()
+	for iterator.SetNext() {
+		entry, err := iterator.Value()
+		if err != nil {
+			return copied, err
+		}
+		key := dst.normalizeKey(entry.Key()) // 按 dst 的 KeyNormalizer 规范化，否则 dst 的查找找不到这些键
+		hashedKey := dst.hash.Sum64(key)
+		shard := dst.getShard(hashedKey)
+		currentTimestamp := uint64(shard.clock.Epoch())
+		value, err := dst.compress(entry.Value()) // 迭代器返回解码后的值，按 dst 的 Compressor 重新编码
+		if err == nil {
+			err = shard.setWithTimestamp(key, hashedKey, value, currentTimestamp, entry.Timestamp(), entry.ttl)
+		}
+		if err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
+
 // onEvict 检查条目是否过期并执行淘汰操作
 // 参数:
 //
@@ -320,7 +1242,11 @@ func (c *BigCache) onEvict(oldestEntry []byte, currentTimestamp uint64, evict fu
 	if currentTimestamp < oldestTimestamp {
 		return false
 	}
-	if currentTimestamp-oldestTimestamp > c.lifeWindow {
+	lifeWindow := c.lifeWindow
+	if ttl := readTTLFromEntry(oldestEntry); ttl != 0 {
+		lifeWindow = uint64(ttl)
+	}
+	if currentTimestamp-oldestTimestamp > lifeWindow {
 		err := evict(Expired)
 		if err != nil {
 			return false
@@ -335,9 +1261,46 @@ func (c *BigCache) onEvict(oldestEntry []byte, currentTimestamp uint64, evict fu
 //
 //	currentTimestamp: 当前时间戳
 func (c *BigCache) cleanUp(currentTimestamp uint64) {
-	for _, shard := range c.shards {
-		shard.cleanUp(currentTimestamp)
+	workers := min(c.config.CleanupConcurrency, len(c.shards))
+	if workers <= 1 {
+		for _, shard := range c.shards {
+			shard.cleanUp(currentTimestamp)
+		}
+		return
 	}
+
+	// 多个 worker 依次领取分片进行清理，每个分片仍然独立加锁
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				index := int(next.Add(1) - 1)
+				if index >= len(c.shards) {
+					return
+				}
+				c.shards[index].cleanUp(currentTimestamp)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// normalizeKey 使用 KeyNormalizer 规范化键，未设置时原样返回
+// 参数:
+//
+//	key: 调用方传入的键
+//
+// 返回值:
+//
+//	string: 用于哈希和存储的键
+func (c *BigCache) normalizeKey(key string) string {
+	if c.config.KeyNormalizer == nil {
+		return key
+	}
+	return c.config.KeyNormalizer(key)
 }
 
 // getShard 根据哈希键获取对应的分片
@@ -349,7 +1312,25 @@ func (c *BigCache) cleanUp(currentTimestamp uint64) {
 //
 //	*cacheShard: 对应的缓存分片
 func (c *BigCache) getShard(hashedKey uint64) (shard *cacheShard) {
-	return c.shards[hashedKey&c.shardMask]
+	return c.shards[c.config.ShardSelector(hashedKey, len(c.shards))]
+}
+
+// getAffinityShard 根据 affinityKey 的哈希值选择分片
+func (c *BigCache) getAffinityShard(affinityKey string) *cacheShard {
+	return c.getShard(c.hash.Sum64(c.normalizeKey(affinityKey)))
+}
+
+// maskShardSelector 默认的分片选择策略，使用哈希键的低位作为分片索引
+// 参数:
+//
+//	hashedKey: 哈希键
+//	numShards: 分片数量，必须是2的幂
+//
+// 返回值:
+//
+//	int: 分片索引
+func maskShardSelector(hashedKey uint64, numShards int) int {
+	return int(hashedKey & uint64(numShards-1))
 }
 
 // providedOnRemove 处理条目移除的回调函数（基础版本）
@@ -357,8 +1338,8 @@ func (c *BigCache) getShard(hashedKey uint64) (shard *cacheShard) {
 //
 //	wrappedEntry: 包装的条目
 //	reason: 移除原因
-func (c *BigCache) providedOnRemove(wrappedEntry []byte, reason RemoveReason) {
-	c.config.OnRemove(readKeyFromEntry(wrappedEntry), readEntry(wrappedEntry))
+func (c *BigCache) providedOnRemove(wrappedEntry []byte, reason RemoveReason, _ Metadata) {
+	c.config.OnRemove(readKeyFromEntry(wrappedEntry), c.readValue(wrappedEntry))
 }
 
 // providedOnRemoveWithReason 处理条目移除的回调函数（带原因版本）
@@ -366,9 +1347,51 @@ func (c *BigCache) providedOnRemove(wrappedEntry []byte, reason RemoveReason) {
 //
 //	wrappedEntry: 包装的条目
 //	reason: 移除原因
-func (c *BigCache) providedOnRemoveWithReason(wrappedEntry []byte, reason RemoveReason) {
+func (c *BigCache) providedOnRemoveWithReason(wrappedEntry []byte, reason RemoveReason, _ Metadata) {
 	if c.config.onRemoveFilter == 0 || (1<<uint(reason))&c.config.onRemoveFilter > 0 {
-		c.config.OnRemoveWithReason(readKeyFromEntry(wrappedEntry), readEntry(wrappedEntry), reason)
+		c.config.OnRemoveWithReason(readKeyFromEntry(wrappedEntry), c.readValue(wrappedEntry), reason)
+	}
+}
+
+// asyncOnRemove 将移除回调包装为异步版本，在持有分片锁时只复制条目并入队，回调由 runRemovals 在锁外执行
+// 队列已满时入队会阻塞，缓存关闭后入队的回调会被丢弃，关闭前已入队的回调仍会执行
+// 参数:
+//
+//	onRemove: 同步的移除回调函数
+//
+// 返回值:
+//
+//	onRemoveCallback: 异步的移除回调函数
+func (c *BigCache) asyncOnRemove(onRemove onRemoveCallback) onRemoveCallback {
+	return func(wrappedEntry []byte, reason RemoveReason, metadata Metadata) {
+		entry := make([]byte, len(wrappedEntry)) // 复制条目，队列中的数据可能随时被覆盖
+		copy(entry, wrappedEntry)
+		notify := func() { onRemove(entry, reason, metadata) } // 元数据已由分片在持有锁时读取
+
+		select {
+		case c.removals <- notify:
+		case <-c.close:
+		}
+	}
+}
+
+// runRemovals 在锁外依次执行异步模式下的移除回调，缓存关闭后执行完队列中剩余的回调再退出
+func (c *BigCache) runRemovals() {
+	defer close(c.drained)
+	for {
+		select {
+		case notify := <-c.removals:
+			notify()
+		case <-c.close:
+			for {
+				select {
+				case notify := <-c.removals:
+					notify()
+				default:
+					return
+				}
+			}
+		}
 	}
 }
 
@@ -377,17 +1400,14 @@ func (c *BigCache) providedOnRemoveWithReason(wrappedEntry []byte, reason Remove
 //
 //	wrappedEntry: 包装的条目
 //	reason: 移除原因
-func (c *BigCache) notProvideOnRemove(wrappedEntry []byte, reason RemoveReason) {}
+func (c *BigCache) notProvideOnRemove(wrappedEntry []byte, reason RemoveReason, _ Metadata) {}
 
 // provideOnRemoveWithMetadata 处理条目移除的回调函数（带元数据版本）
 // 参数:
 //
 //	wrappedEntry: 包装的条目
 //	reason: 移除原因
-func (c *BigCache) provideOnRemoveWithMetadata(wrappedEntry []byte, reason RemoveReason) {
-	key := readKeyFromEntry(wrappedEntry)
-
-	hashKey := c.hash.Sum64(key)
-	shard := c.getShard(hashKey)
-	c.config.OnRemoveWithMetadata(key, readEntry(wrappedEntry), shard.getKeyMetadata(hashKey))
+//	metadata: 执行移除的分片中该条目的元数据
+func (c *BigCache) provideOnRemoveWithMetadata(wrappedEntry []byte, reason RemoveReason, metadata Metadata) {
+	c.config.OnRemoveWithMetadata(readKeyFromEntry(wrappedEntry), c.readValue(wrappedEntry), metadata)
 }
@@ -0,0 +1,59 @@
+package bigcache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// cachedClock 缓存当前的秒级时间戳，由后台goroutine每秒刷新一次，
+// 分片读写时只需一次原子读取，避免每次操作都调用 time.Now()
+// 除 Epoch 外的方法都委托给被包装的时钟
+type cachedClock struct {
+	clock.Clock
+	epoch atomic.Int64 // 缓存的时间戳（秒）
+}
+
+// newCachedClock 创建包装 c 的 cachedClock，初始时间戳取自 c
+// 参数:
+//
+//	c: 被包装的时钟
+//
+// 返回值:
+//
+//	*cachedClock: cachedClock实例指针
+func newCachedClock(c clock.Clock) *cachedClock {
+	cc := &cachedClock{Clock: c}
+	cc.epoch.Store(c.Epoch())
+	return cc
+}
+
+// Epoch 返回缓存的时间戳，最多落后于被包装的时钟一秒
+func (c *cachedClock) Epoch() int64 {
+	return c.epoch.Load()
+}
+
+// start 启动后台goroutine，每秒刷新一次缓存的时间戳，直到上下文取消或 done 关闭
+// 计时器在返回前创建，因此之后推进时钟一定会触发刷新
+// 参数:
+//
+//	ctx: 上下文
+//	done: 关闭信号通道
+func (c *cachedClock) start(ctx context.Context, done <-chan struct{}) {
+	ticker := c.Clock.Ticker(time.Second)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				c.epoch.Store(c.Clock.Epoch())
+			}
+		}
+	}()
+}
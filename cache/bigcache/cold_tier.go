@@ -0,0 +1,339 @@
+package bigcache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/andrewbytecoder/gokit/fileutil/mmap"
+	"go.uber.org/zap"
+)
+
+// ColdTierConfig configures the on-disk tier that entries evicted for lack of space are spilled to
+type ColdTierConfig struct {
+	// Path of the file backing the tier. An existing file is truncated when the cache is created,
+	// the tier does not survive a restart.
+	Path string
+	// Size of the file in bytes, i.e. the maximum number of bytes of spilled entries, including their headers.
+	Size int
+}
+
+// coldTier 基于内存映射文件的冷层，保存因空间不足被淘汰的包装条目
+//
+// 日志只追加，条目被取回、删除或在内存中被覆盖后只从索引中移除，占用的空间留待压缩回收；
+// 文件写满时重新创建日志，只保留最近写入的、合计不超过文件一半大小的条目，更早的冷条目被丢弃。
+type coldTier struct {
+	mu     sync.RWMutex
+	config ColdTierConfig
+	logger *zap.Logger
+	log    *mmap.Log
+	index  map[uint64]int // 键的哈希值 -> 包装条目在日志中的偏移
+}
+
+// newColdTier 创建冷层，config.Path 处已有的文件会被截断
+// 参数:
+//
+//	config: 冷层配置
+//	logger: 日志记录器，为 nil 时不记录日志
+//
+// 返回值:
+//
+//	*coldTier: 冷层实例
+//	error: 配置无效或文件创建失败时返回错误
+func newColdTier(config ColdTierConfig, logger *zap.Logger) (*coldTier, error) {
+	if config.Path == "" {
+		return nil, errors.New("ColdTier.Path must be set")
+	}
+	if config.Size <= 0 {
+		return nil, errors.New("ColdTier.Size must be > 0")
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	log, err := mmap.OpenLog(config.Path, config.Size, logger)
+	if err != nil {
+		return nil, fmt.Errorf("opening cold tier: %w", err)
+	}
+	return &coldTier{config: config, logger: logger, log: log, index: make(map[uint64]int)}, nil
+}
+
+// put 将被淘汰的包装条目追加到冷层，同一哈希值的旧条目被覆盖
+// 文件写满时压缩冷层后重试一次，仍然失败（条目大于压缩后的剩余空间）时丢弃该条目
+// 参数:
+//
+//	wrappedEntry: 包装条目，追加时会被复制，调用返回后可以被覆盖
+func (t *coldTier) put(wrappedEntry []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.log == nil { // 之前重新创建日志文件失败，冷层已不可用
+		return
+	}
+	hashedKey := readHashFromEntry(wrappedEntry)
+	offset, err := t.log.Append(wrappedEntry)
+	if errors.Is(err, mmap.ErrLogFull) {
+		if err = t.compactLocked(); err == nil {
+			offset, err = t.log.Append(wrappedEntry)
+		}
+	}
+	if err != nil {
+		delete(t.index, hashedKey) // 旧条目已经过时，不能再被取回
+		t.logger.Warn("cold tier: dropping evicted entry", zap.String("key", readKeyFromEntry(wrappedEntry)), zap.Error(err))
+		return
+	}
+	t.index[hashedKey] = offset
+}
+
+// take 取回并移除键对应的包装条目
+// 参数:
+//
+//	key: 要查找的键
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	[]byte: 包装条目的副本
+//	bool: 冷层中是否存在该键
+func (t *coldTier) take(key string, hashedKey uint64) ([]byte, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	offset, ok := t.index[hashedKey]
+	if !ok {
+		return nil, false
+	}
+	wrappedEntry, _, err := t.log.ReadAt(offset)
+	if err != nil || !compareKeyFromEntry(wrappedEntry, key) { // 哈希冲突时保留另一个键的条目
+		return nil, false
+	}
+	delete(t.index, hashedKey)
+	return wrappedEntry, true
+}
+
+// remove 从冷层中移除哈希值对应的条目
+// 每次写入都会调用，先在读锁下检查，冷层中没有该条目时不与其他分片争用写锁
+// 参数:
+//
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	bool: 冷层中是否存在该条目
+func (t *coldTier) remove(hashedKey uint64) bool {
+	t.mu.RLock()
+	_, ok := t.index[hashedKey]
+	t.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok = t.index[hashedKey]
+	delete(t.index, hashedKey)
+	return ok
+}
+
+// removePrefix 从冷层中移除所有键以 prefix 开头的条目，需要读取每个条目的键
+// 参数:
+//
+//	prefix: 键的前缀
+//
+// 返回值:
+//
+//	int: 移除的条目数量
+func (t *coldTier) removePrefix(prefix string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	removed := 0
+	for hashedKey, offset := range t.index {
+		wrappedEntry, _, err := t.log.ReadAt(offset)
+		if err == nil && strings.HasPrefix(readKeyFromEntry(wrappedEntry), prefix) {
+			delete(t.index, hashedKey)
+			removed++
+		}
+	}
+	return removed
+}
+
+// reset 清空冷层
+func (t *coldTier) reset() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resetLocked()
+}
+
+// compactLocked 重新创建日志文件，只写回最近写入的、合计不超过文件一半大小的条目
+// 每次压缩至少腾出一半的空间，复制的开销被之后的写入均摊；写回的条目在压缩期间暂存在堆上
+// 调用前必须持有锁
+func (t *coldTier) compactLocked() error {
+	offsets := make([]int, 0, len(t.index))
+	for _, offset := range t.index {
+		offsets = append(offsets, offset)
+	}
+	slices.Sort(offsets)
+
+	var kept [][]byte // 由新到旧
+	budget := t.log.Capacity() / 2
+	for _, offset := range slices.Backward(offsets) {
+		wrappedEntry, _, err := t.log.ReadAt(offset)
+		if err != nil || int64(len(wrappedEntry)) > budget {
+			break
+		}
+		budget -= int64(len(wrappedEntry))
+		kept = append(kept, wrappedEntry)
+	}
+
+	if err := t.resetLocked(); err != nil {
+		return err
+	}
+	for _, wrappedEntry := range slices.Backward(kept) { // 按原来的顺序由旧到新写回
+		offset, err := t.log.Append(wrappedEntry)
+		if err != nil {
+			return err
+		}
+		t.index[readHashFromEntry(wrappedEntry)] = offset
+	}
+	return nil
+}
+
+// resetLocked 重新创建日志文件并清空索引，调用前必须持有锁
+func (t *coldTier) resetLocked() error {
+	clear(t.index)
+	if t.log != nil {
+		if err := t.log.Close(); err != nil {
+			t.logger.Warn("cold tier: closing log", zap.Error(err))
+		}
+	}
+	log, err := mmap.OpenLog(t.config.Path, t.config.Size, t.logger)
+	if err != nil {
+		t.log = nil
+		return fmt.Errorf("reopening cold tier: %w", err)
+	}
+	t.log = log
+	return nil
+}
+
+// len 返回冷层中的条目数
+func (t *coldTier) len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.index)
+}
+
+// close 关闭冷层的日志文件
+func (t *coldTier) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.index)
+	if t.log == nil {
+		return nil
+	}
+	err := t.log.Close()
+	t.log = nil
+	return err
+}
+
+// onRemove 包装移除回调：因空间不足淘汰的条目写入冷层，其他原因移除的条目使冷层中的旧值失效
+// 参数:
+//
+//	onRemove: 原来的移除回调
+//
+// 返回值:
+//
+//	onRemoveCallback: 包装后的移除回调
+func (t *coldTier) onRemove(onRemove onRemoveCallback) onRemoveCallback {
+	return func(wrappedEntry []byte, reason RemoveReason, metadata Metadata) {
+		if reason == NoSpace {
+			t.put(wrappedEntry)
+		} else {
+			t.remove(readHashFromEntry(wrappedEntry))
+		}
+		onRemove(wrappedEntry, reason, metadata)
+	}
+}
+
+// getCold 在内存未命中时从冷层取回条目并提升回内存
+// 参数:
+//
+//	shard: 键所属的分片
+//	key: 要查找的键
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	[]byte: 条目数据
+//	error: 冷层中也不存在或已过期时返回 ErrEntryNotFound
+func (c *BigCache) getCold(shard *cacheShard, key string, hashedKey uint64) ([]byte, error) {
+	wrappedEntry, ok := c.cold.take(key, hashedKey)
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return shard.promote(key, hashedKey, wrappedEntry)
+}
+
+// promote 将从冷层取回的包装条目重新写入分片，保留原来的时间戳、生存时间和写入时间
+// 取回期间键被重新写入时以内存中较新的值为准
+// 参数:
+//
+//	key: 条目的键
+//	hashedKey: 键的哈希值
+//	wrappedEntry: 从冷层取回的包装条目
+//
+// 返回值:
+//
+//	[]byte: 条目数据
+//	error: 条目已经过期时返回 ErrEntryNotFound
+func (s *cacheShard) promote(key string, hashedKey uint64, wrappedEntry []byte) ([]byte, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+	if s.isExpired(wrappedEntry, currentTimestamp) { // 冷层中的条目不会被清理，取回时才检查是否过期
+		return nil, ErrEntryNotFound
+	}
+	entry := readEntry(wrappedEntry)
+
+	s.lock.Lock()
+	if s.hashmap[hashedKey] != 0 { // 取回期间键被重新写入
+		s.lock.Unlock()
+		return s.get(key, hashedKey)
+	}
+	err := s.initEntriesWithoutLock() // 延迟创建字节队列
+	if err == nil {
+		err = s.setWithoutLock(key, hashedKey, entry, currentTimestamp,
+			readTimestampFromEntry(wrappedEntry), readTTLFromEntry(wrappedEntry))
+	}
+	if err == nil { // 重新包装时写入时间取自时间戳，恢复原来的写入时间，使 MaxAge 不因 Touch 后被淘汰而重新计算
+		if promoted, getErr := s.entries.Get(int(s.hashmap[hashedKey])); getErr == nil {
+			writeWrittenAtToEntry(promoted, readWrittenAtFromEntry(wrappedEntry))
+			lowerTo(&s.nextExpiry, s.expiryOf(promoted))
+			lowerTo(&s.nextMaxAgeExpiry, s.maxAgeExpiryOf(promoted))
+		}
+	}
+	s.lock.Unlock()
+	if err != nil && s.isVerbose { // 提升失败不影响本次读取，条目只是不再被缓存
+		s.logger.Warn("cold tier: promoting entry", zap.String("key", key), zap.Error(err))
+	}
+	return entry, nil
+}
+
+// delCold 在删除键后移除冷层中的旧值，键只存在于冷层时也视为删除成功
+// 参数:
+//
+//	err: 从内存中删除的结果
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	error: 内存和冷层中都不存在时返回 ErrEntryNotFound
+func (c *BigCache) delCold(err error, hashedKey uint64) error {
+	if c.cold == nil {
+		return err
+	}
+	if c.cold.remove(hashedKey) && errors.Is(err, ErrEntryNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+package bigcache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// 设置了 Compressor 时，存储的值以一个字节的标志开头，标志之后是原始值或压缩后的值
+const (
+	valueRaw        byte = 0 // 值未压缩，低于阈值或压缩后没有变小
+	valueCompressed byte = 1 // 值经过 Compressor 压缩
+)
+
+// ErrCorruptValue is returned by reads when a value stored with Config.Compressor set has an unknown
+// compression flag or can not be decompressed
+var ErrCorruptValue = errors.New("stored value can not be decompressed")
+
+// Compressor compresses values before they are stored and decompresses them when they are read,
+// see Config.Compressor. Implementations must be safe for concurrent use.
+type Compressor interface {
+	// Compress returns the compressed form of value, it must not modify or retain value.
+	Compress(value []byte) []byte
+	// Decompress returns the value that Compress was called with.
+	Decompress(compressed []byte) ([]byte, error)
+}
+
+// GzipCompressor 使用 compress/gzip 压缩值，压缩率高但速度较慢，适合内存紧张而值可压缩性好的场景
+// 压缩和解压使用的 gzip.Writer、gzip.Reader 被复用，避免每次分配几百 KB 的压缩状态
+type GzipCompressor struct {
+	level   int
+	writers sync.Pool
+	readers sync.Pool
+}
+
+// NewGzipCompressor 创建使用给定压缩级别的 GzipCompressor
+// 参数:
+//
+//	level: compress/gzip 的压缩级别，如 gzip.BestSpeed、gzip.DefaultCompression
+//
+// 返回值:
+//
+//	*GzipCompressor: 压缩器实例
+//	error: 压缩级别无效时返回错误
+func NewGzipCompressor(level int) (*GzipCompressor, error) {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+	return &GzipCompressor{level: level}, nil
+}
+
+// Compress 实现 Compressor
+func (g *GzipCompressor) Compress(value []byte) []byte {
+	var buf bytes.Buffer
+	w, ok := g.writers.Get().(*gzip.Writer)
+	if ok {
+		w.Reset(&buf)
+	} else {
+		w, _ = gzip.NewWriterLevel(&buf, g.level) // 级别已在 NewGzipCompressor 中校验
+	}
+	w.Write(value) // 写入 bytes.Buffer 不会失败
+	w.Close()
+	g.writers.Put(w)
+	return buf.Bytes()
+}
+
+// Decompress 实现 Compressor
+func (g *GzipCompressor) Decompress(compressed []byte) ([]byte, error) {
+	r, ok := g.readers.Get().(*gzip.Reader)
+	var err error
+	if ok {
+		err = r.Reset(bytes.NewReader(compressed))
+	} else {
+		r, err = gzip.NewReader(bytes.NewReader(compressed))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer g.readers.Put(r)
+	return io.ReadAll(r)
+}
+
+// SnappyCompressor 使用 Snappy 格式压缩值，速度快但压缩率低于 gzip，适合读写频繁的场景
+type SnappyCompressor struct{}
+
+// Compress 实现 Compressor
+func (SnappyCompressor) Compress(value []byte) []byte {
+	return snappy.Encode(nil, value)
+}
+
+// Decompress 实现 Compressor
+func (SnappyCompressor) Decompress(compressed []byte) ([]byte, error) {
+	return snappy.Decode(nil, compressed)
+}
+
+// compress 按 Compressor 编码要存储的值，未设置 Compressor 时原样返回
+// 低于 CompressionThreshold 或压缩后没有变小的值以原始形式存储；
+// 分片只能看到存储的字节，因此设置了 Compressor 时由这里按调用方的值检查 MaxValueSize
+// 参数:
+//
+//	value: 调用方传入的值
+//
+// 返回值:
+//
+//	[]byte: 要存储的值
+//	error: 值超过 MaxValueSize 时返回 ErrValueTooLarge
+func (c *BigCache) compress(value []byte) ([]byte, error) {
+	if c.config.Compressor == nil {
+		return value, nil
+	}
+	if c.config.MaxValueSize > 0 && len(value) > c.config.MaxValueSize {
+		return nil, ErrValueTooLarge
+	}
+	flag, payload := valueRaw, value
+	if len(value) >= c.config.CompressionThreshold {
+		if compressed := c.config.Compressor.Compress(value); len(compressed) < len(value) {
+			flag, payload = valueCompressed, compressed
+		}
+	}
+	stored := make([]byte, 1+len(payload))
+	stored[0] = flag
+	copy(stored[1:], payload)
+	return stored, nil
+}
+
+// decompress 解码 compress 存储的值，未设置 Compressor 时原样返回
+// 参数:
+//
+//	stored: 存储的值
+//
+// 返回值:
+//
+//	[]byte: 调用方写入的值
+//	error: 标志未知或解压失败时返回 ErrCorruptValue
+func (c *BigCache) decompress(stored []byte) ([]byte, error) {
+	if c.config.Compressor == nil {
+		return stored, nil
+	}
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("%w: missing compression flag", ErrCorruptValue)
+	}
+	switch stored[0] {
+	case valueRaw:
+		return stored[1:], nil
+	case valueCompressed:
+		value, err := c.config.Compressor.Decompress(stored[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCorruptValue, err)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown compression flag %d", ErrCorruptValue, stored[0])
+	}
+}
+
+// decompressResult 解码读取操作的结果，读取失败时原样返回
+func (c *BigCache) decompressResult(stored []byte, err error) ([]byte, error) {
+	if err != nil {
+		return stored, err
+	}
+	return c.decompress(stored)
+}
+
+// readValue 读取包装条目中的值并解码，用于移除回调和迭代器，解码失败时返回存储的字节
+func (c *BigCache) readValue(wrappedEntry []byte) []byte {
+	return c.readStoredValue(readEntry(wrappedEntry))
+}
+
+// readStoredValue 解码存储的值，解码失败时返回存储的字节
+func (c *BigCache) readStoredValue(stored []byte) []byte {
+	if value, err := c.decompress(stored); err == nil {
+		return value
+	}
+	return stored
+}
+
+// decompressEntry 返回值被解码的包装条目，其余头部信息不变，未设置 Compressor 时原样返回
+// 参数:
+//
+//	wrappedEntry: 存储的包装条目
+//
+// 返回值:
+//
+//	[]byte: 值为调用方写入的值的包装条目
+//	error: 值无法解码时返回 ErrCorruptValue，此时返回原条目
+func (c *BigCache) decompressEntry(wrappedEntry []byte) ([]byte, error) {
+	if c.config.Compressor == nil {
+		return wrappedEntry, nil
+	}
+	value, err := c.decompress(readEntry(wrappedEntry))
+	if err != nil {
+		return wrappedEntry, err
+	}
+	var buffer []byte
+	entry := wrapEntryWithTTL(readTimestampFromEntry(wrappedEntry), readHashFromEntry(wrappedEntry),
+		readTTLFromEntry(wrappedEntry), readKeyFromEntry(wrappedEntry), value, &buffer)
+	writeWrittenAtToEntry(entry, readWrittenAtFromEntry(wrappedEntry))
+	return entry, nil
+}
+
+// compressUpdate 包装 Update 的 fn，使其读写解码后的值
+// 参数:
+//
+//	fn: 调用方传入的更新函数
+//
+// 返回值:
+//
+//	func(old []byte, found bool) ([]byte, error): 读写存储的值的更新函数
+func (c *BigCache) compressUpdate(fn func(old []byte, found bool) ([]byte, error)) func(old []byte, found bool) ([]byte, error) {
+	return func(old []byte, found bool) ([]byte, error) {
+		if found {
+			value, err := c.decompress(old)
+			if err != nil {
+				return nil, err
+			}
+			old = value
+		}
+		value, err := fn(old, found)
+		if err != nil || value == nil { // 返回 nil, nil 表示删除该键
+			return value, err
+		}
+		return c.compress(value)
+	}
+}
+
+// appendCompressed 在设置了 Compressor 时实现 Append：压缩后的值不能直接拼接，
+// 因此在写锁内解压旧值，拼接后重新压缩；与未压缩时的 Append 一样，条目保留通过 SetWithTTL 设置的生存时间
+// 参数:
+//
+//	shard: 键所属的分片
+//	key: 键
+//	hashedKey: 键的哈希值
+//	entry: 要追加的数据
+//	maxTotal: 追加后值的最大字节数，<= 0 表示不限制
+//
+// 返回值:
+//
+//	error: 超过 maxTotal 时返回 ErrAppendLimitExceeded
+func (c *BigCache) appendCompressed(shard *cacheShard, key string, hashedKey uint64, entry []byte, maxTotal int) error {
+	return shard.update(key, hashedKey, c.compressUpdate(func(old []byte, found bool) ([]byte, error) {
+		value := make([]byte, 0, len(old)+len(entry)) // 非 nil，追加空数据也不会被当作删除
+		value = append(append(value, old...), entry...)
+		if maxTotal > 0 && len(value) > maxTotal {
+			return nil, ErrAppendLimitExceeded
+		}
+		return value, nil
+	}))
+}
@@ -15,20 +15,69 @@ type Config struct {
 	Shards int
 	// Time after which entry can be evicted
 	LifeWindow time.Duration
+	// AllowNeverExpire must be set to true to construct a cache with zero LifeWindow.
+	// It makes the decision to keep entries forever explicit instead of silently accepting a missing LifeWindow.
+	AllowNeverExpire bool
 	// Interval between removing expired entries (clean up).
 	// If set to <= 0 then no action is performed. Setting to < 1 second is counterproductive — bigcache has a one second resolution.
 	CleanWindow time.Duration
+	// MaxAge is a hard limit on how long an entry is kept after it was written. It caps LifeWindow and
+	// the lifetime given to SetWithTTL, and also applies to entries that would never expire with AllowNeverExpire.
+	// The age is measured from the last write of the entry (Set, Append, Update...), Touch and SlidingExpiration
+	// do not extend it, and CanEvict can not veto it. Reads stop returning an entry as soon as it exceeds MaxAge.
+	// The clean up runs at least every MaxAge, even when CleanWindow is not set, and removes every entry past
+	// MaxAge, not only those at the head of the queue, so an entry is removed at most one clean up interval
+	// after it exceeds MaxAge; finding entries behind the head needs a pass over the whole shard.
+	// A MaxAge longer than LifeWindow only affects entries with a longer TTL. Like LifeWindow it has
+	// a one second resolution. 0 means no limit.
+	MaxAge time.Duration
+	// CleanupConcurrency is the number of goroutines cleaning up shards in parallel during a clean up pass,
+	// every shard is still locked on its own. Values <= 1 clean up the shards sequentially.
+	CleanupConcurrency int
 	// Max number of entries in life window. Used only to calculate initial size for cache shards.
 	// When proper value is set then additional memory allocation does not occur.
 	MaxEntriesInWindow int
 	// Max size of entry in bytes. Used only to calculate initial size for cache shards.
 	MaxEntrySize int
+	// MaxValueSize is the hard limit on the size of a value in bytes. Set and the other writes
+	// reject larger values with ErrValueTooLarge. 0 means unlimited.
+	MaxValueSize int
+	// MaxEntriesPerShard is the hard limit on the number of keys of every shard, which bounds the hashmap
+	// even when the values are tiny. Adding a key to a full shard evicts its oldest entry with reason NoSpace,
+	// or fails with ErrShardFull if RejectOnFull is set. Overwriting an existing key is always allowed.
+	// 0 means unlimited.
+	MaxEntriesPerShard int
+	// RejectOnFull makes writes of new keys to a shard holding MaxEntriesPerShard entries fail with ErrShardFull
+	// instead of evicting the oldest entry.
+	RejectOnFull bool
+	// Compressor, when set, compresses values before they are stored and decompresses them when they are read,
+	// trading CPU time for memory, e.g. NewGzipCompressor or SnappyCompressor. Every stored value starts with a
+	// one byte flag telling whether it was compressed, so that values shorter than CompressionThreshold or that
+	// do not shrink are stored raw. MaxValueSize applies to the values passed in, before compression,
+	// while the HardMaxCacheSize accounting applies to the stored bytes.
+	// Append and AppendBounded decompress and recompress the whole value under the shard lock.
+	// The removal callbacks, the iterator and snapshots see decompressed values, so a snapshot can be loaded
+	// into a cache with a different Compressor or none.
+	Compressor Compressor `json:"-"`
+	// CompressionThreshold is the minimum length in bytes of a value that Compressor is applied to.
+	CompressionThreshold int
 	// StatsEnabled if true calculate the number of times a cached resource was requested.
 	StatsEnabled bool
 	// Verbose mode prints information about new memory allocation
 	Verbose bool
+	// CollisionLogSampleRate makes Verbose mode log only every N-th hash collision of a shard, starting with the first,
+	// so that a flood of collisions, e.g. from crafted keys, does not flood the log. Values <= 1 log every collision.
+	// Stats.Collisions still counts all of them.
+	CollisionLogSampleRate int
 	// Hasher used to calculate hash values for cache keys.
 	Hasher hash2.Hasher `json:"-"`
+	// KeyNormalizer, when set, is applied to every key passed to the cache before it is hashed,
+	// e.g. strings.ToLower for case-insensitive keys. It affects both the stored key and lookups,
+	// so keys returned by the iterator and passed to the OnRemove callbacks are the normalized ones.
+	KeyNormalizer func(key string) string `json:"-"`
+	// ShardSelector maps a hashed key to the index of its shard, the result must be in [0, numShards).
+	// Default value is nil which means hashedKey & (numShards - 1), this relies on the Hasher having good low bits.
+	ShardSelector func(hashedKey uint64, numShards int) int `json:"-"`
 	// HardMaxCacheSize is a limit for BytesQueue size in MB.
 	// It can protect application from consuming all available memory on machine, therefore from running OOM Killer.
 	// Default value is 0 which means unlimited size. When the limit is higher than 0 and reached then
@@ -36,7 +85,30 @@ type Config struct {
 	// HardMaxCacheSize due to Shards' s additional memory. Every Shard consumes additional memory for map of keys
 	// and statistics (map[uint64]uint32) the size of this map is equal to number of entries in
 	// cache ~ 2×(64+32)×n bits + overhead or map itself.
+	// Besides its key and value every entry stores a 30 byte header: timestamp, hash and key length (18 bytes),
+	// plus the per-entry TTL (4 bytes) that SetWithTTL needs and the write time (8 bytes) that MaxAge is
+	// measured from. Filling a 16 MB shard with 15 byte keys, the 12 bytes for TTL and write time cut the number
+	// of entries held by 15% for 32 byte values, 8% for 100 byte values, 2% for 500 byte values and 0.3%
+	// for 4 KB values, so caches of very small values should budget for them.
 	HardMaxCacheSize int
+	// OffHeap allocates the entries of every shard outside the Go heap via an anonymous mmap,
+	// so that large caches add no work to the GC. The memory is released by Close,
+	// after which the cache holds no entries.
+	OffHeap bool
+	// SlidingExpiration makes a successful read reset the entry's expiration time to now. Only Get,
+	// GetNonBlocking, GetWithAffinity and the reads built on Get (GetOrSet on a hit, GetSegments) slide;
+	// GetBatch, GetMulti, GetWithInfo, GetWithTTL, TryGet and the iterators leave the expiration time alone.
+	// The sliding reads then take the shard write lock instead of the read lock, so concurrent reads of one shard
+	// are serialized. Eviction still follows insertion order, so entries inserted after a refreshed entry
+	// are only removed once it expires or is evicted itself.
+	SlidingExpiration bool
+	// LazyShards defers the allocation of every shard's entries until the first write to that shard,
+	// which cuts the startup memory of caches with many shards that are mostly unused.
+	LazyShards bool
+	// OnAllocate is called whenever a shard reallocates its entries with the old and new capacity in bytes
+	// and the time the reallocation took, e.g. to route it to metrics. It is called while the shard lock is held.
+	// When set it replaces the reallocation output of Verbose mode.
+	OnAllocate func(oldCap, newCap int, dur time.Duration) `json:"-"`
 	// OnRemove is a callback fired when the oldest entry is removed because of its expiration time or no space left
 	// for the new entry, or because delete was called.
 	// Default value is nil which means no callback and it prevents from unwrapping the oldest entry.
@@ -53,12 +125,57 @@ type Config struct {
 	OnRemoveWithReason func(key string, entry []byte, reason RemoveReason)
 
 	onRemoveFilter int
+	// AsyncOnRemove dispatches the removal callbacks to a bounded queue served by a background goroutine,
+	// so that a slow callback does not stall the shard it was fired from. The key and entry are copied first.
+	// In async mode the callbacks run after the removing operation returned, so their ordering relative to
+	// other cache operations is no longer guaranteed. Removals block once the queue is full.
+	// Close waits until the callbacks queued before it ran, so a callback must not call Close itself.
+	AsyncOnRemove bool
+	// OversizeEntryPolicy decides what Set and SetLarge do with an entry that does not fit into its shard
+	// even after all other entries of the shard were evicted. Default value is Reject which returns an error.
+	OversizeEntryPolicy OversizeEntryPolicy
+	// CanEvict, when set, is asked before the oldest entry of a shard is removed because it expired or to make room
+	// for a new entry, and can veto the removal of e.g. pinned keys by returning false. A vetoed entry is moved to
+	// the back of the queue: for NoSpace the next oldest entry is tried instead, up to a bounded number of vetoes
+	// after which Set fails as if the entry did not fit; for Expired the entry's lifetime restarts.
+	// Vetoing too many entries, in the worst case all of them, makes every Set rotate the shard and fail,
+	// so CanEvict should only protect a small part of the cache. It is called while the shard lock is held.
+	CanEvict func(key string, reason RemoveReason) bool `json:"-"`
+	// CachedClock makes the shards read the current time from a value refreshed once per second by a
+	// background goroutine instead of calling the clock on every operation, which saves a time syscall
+	// per Get and Set under high load. Entries may then expire up to one second later than configured.
+	CachedClock bool
+	// ColdTier, when set, spills entries evicted because a shard ran out of space to a memory-mapped file
+	// instead of dropping them, and Get falls through to it on an in-memory miss and promotes the entry
+	// back into memory. It trades latency for hit ratio: a spill costs a copy into the file while the shard
+	// lock is held, and a cold hit costs a page-in from disk plus a Set. Spilling under the lock keeps a
+	// concurrent Set of the same key from being overtaken by its stale evicted value; every write drops the
+	// key's cold copy, including the copy of a key displaced by a hash collision. The tier is best effort,
+	// not a store: it is truncated on start; when the file is full it is rewritten with the most recently
+	// spilled entries filling up to half of it and older ones are dropped; only Get, Delete, DeletePrefix
+	// and Reset consult it; and a Get racing with a Delete of the same key may promote the deleted value.
+	ColdTier *ColdTierConfig `json:"-"`
 
 	// Logger is a logging interface and used in combination with `Verbose`
 	// Defaults to `DefaultLogger()`
 	Logger *zap.Logger
 }
 
+// OversizeEntryPolicy is the handling of entries bigger than the maximum shard size
+type OversizeEntryPolicy int
+
+const (
+	// Reject makes Set return an error for an entry bigger than the maximum shard size
+	Reject OversizeEntryPolicy = iota
+	// Skip makes Set return nil without storing the entry, which suits best-effort caches
+	Skip
+	// Truncate stores only as many leading bytes of the entry as fit into the shard.
+	// The stored value is a prefix of what was set, which is usually not a meaningful value any more,
+	// so readers must be able to detect or tolerate truncated entries. A prefix of compressed bytes can
+	// not be decompressed at all, so New rejects Truncate when Config.Compressor is set.
+	Truncate
+)
+
 // DefaultConfig initializes config with default values.
 // When load for BigCache can be predicted in advance then it is better to use custom config.
 func DefaultConfig(eviction time.Duration) Config {
@@ -94,6 +211,24 @@ func (c Config) initialShardSize() int {
 	return max(c.MaxEntriesInWindow/c.Shards, minimumEntriesInShard)
 }
 
+// cleanupInterval computes the interval between clean ups, CleanWindow shortened to MaxAge when it is longer
+// or not set. 0 means no clean up.
+func (c Config) cleanupInterval() time.Duration {
+	if c.MaxAge > 0 && (c.CleanWindow <= 0 || c.CleanWindow > c.MaxAge) {
+		return c.MaxAge
+	}
+	return max(c.CleanWindow, 0)
+}
+
+// shardMaxValueSize computes the limit shards check stored values against. With a Compressor the shards
+// only see compressed bytes, so MaxValueSize is checked before compressing instead and shards get no limit.
+func (c Config) shardMaxValueSize() int {
+	if c.Compressor != nil {
+		return 0
+	}
+	return c.MaxValueSize
+}
+
 // maximumShardSizeInBytes computes maximum shard size in bytes
 func (c Config) maximumShardSizeInBytes() int {
 	maxShardSize := 0
@@ -4,13 +4,20 @@ import "encoding/binary"
 
 // 定义各种头部信息在条目中的字节大小
 const (
-	timestampSizeInBytes = 8                                                       // 时间戳占用的字节数
-	hashSizeInBytes      = 8                                                       // 哈希值占用的字节数
-	keySizeInBytes       = 2                                                       // 键长度信息占用的字节数
-	headersSizeInBytes   = timestampSizeInBytes + hashSizeInBytes + keySizeInBytes // 所有头部信息总共占用的字节数
+	timestampSizeInBytes = 8                                                                                               // 时间戳占用的字节数
+	hashSizeInBytes      = 8                                                                                               // 哈希值占用的字节数
+	keySizeInBytes       = 2                                                                                               // 键长度信息占用的字节数
+	ttlSizeInBytes       = 4                                                                                               // 条目生存时间占用的字节数
+	writtenAtSizeInBytes = 8                                                                                               // 写入时间占用的字节数
+	headersSizeInBytes   = timestampSizeInBytes + hashSizeInBytes + keySizeInBytes + ttlSizeInBytes + writtenAtSizeInBytes // 所有头部信息总共占用的字节数
+
+	ttlOffset       = timestampSizeInBytes + hashSizeInBytes + keySizeInBytes // 生存时间在条目中的偏移
+	writtenAtOffset = ttlOffset + ttlSizeInBytes                              // 写入时间在条目中的偏移
+	maxKeySize      = 1<<(8*keySizeInBytes) - 1                               // 键长度信息能表示的最大键长度
+	maxTTLSeconds   = 1<<(8*ttlSizeInBytes) - 1                               // 生存时间能表示的最大秒数
 )
 
-// wrapEntry 将时间戳、哈希值、键和值打包成一个字节切片
+// wrapEntry 将时间戳、哈希值、键和值打包成一个字节切片，条目使用全局的生存时间窗口
 // 参数:
 //
 //	timestamp: 条目的时间戳
@@ -21,6 +28,21 @@ const (
 //
 // 返回值: 包含所有信息的字节切片
 func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *[]byte) []byte {
+	return wrapEntryWithTTL(timestamp, hash, 0, key, entry, buffer)
+}
+
+// wrapEntryWithTTL 将时间戳、哈希值、生存时间、键和值打包成一个字节切片，写入时间与时间戳相同
+// 参数:
+//
+//	timestamp: 条目的时间戳
+//	hash: 键的哈希值
+//	ttl: 条目的生存时间（秒），0 表示使用全局的生存时间窗口
+//	key: 条目的键
+//	entry: 条目的值
+//	buffer: 用于存储打包数据的缓冲区
+//
+// 返回值: 包含所有信息的字节切片
+func wrapEntryWithTTL(timestamp uint64, hash uint64, ttl uint32, key string, entry []byte, buffer *[]byte) []byte {
 	keyLength := len(key)                                     // 获取键的长度
 	blobLength := len(entry) + headersSizeInBytes + keyLength // 计算整个条目需要的总字节数
 
@@ -33,6 +55,8 @@ func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *
 	binary.LittleEndian.PutUint64(blob, timestamp)                                                // 在缓冲区开头写入时间戳(8字节)
 	binary.LittleEndian.PutUint64(blob[timestampSizeInBytes:], hash)                              // 在时间戳后写入哈希值(8字节)
 	binary.LittleEndian.PutUint16(blob[timestampSizeInBytes+hashSizeInBytes:], uint16(keyLength)) // 在哈希值后写入键长度(2字节)
+	binary.LittleEndian.PutUint32(blob[ttlOffset:], ttl)                                          // 在键长度后写入生存时间(4字节)
+	binary.LittleEndian.PutUint64(blob[writtenAtOffset:], timestamp)                              // 在生存时间后写入写入时间(8字节)
 	copy(blob[headersSizeInBytes:], key)                                                          // 在头部信息后写入键内容
 	copy(blob[headersSizeInBytes+keyLength:], entry)                                              // 在键内容后写入值内容
 	return blob[:blobLength]                                                                      // 返回完整的条目数据
@@ -46,7 +70,7 @@ func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *
 //	entry: 要追加的数据
 //	buffer: 用于存储结果的缓冲区
 //
-// 返回值: 包含新时间戳和追加数据的字节切片
+// 返回值: 包含新时间戳和追加数据的字节切片，追加也是一次写入，写入时间同样更新为新的时间戳
 func appendToWrappedEntry(timestamp uint64, wrappedEntry []byte, entry []byte, buffer *[]byte) []byte {
 	blobLength := len(wrappedEntry) + len(entry) // 计算新条目需要的总字节数
 	if blobLength > len(*buffer) {               // 如果缓冲区不够大
@@ -58,6 +82,7 @@ func appendToWrappedEntry(timestamp uint64, wrappedEntry []byte, entry []byte, b
 	binary.LittleEndian.PutUint64(blob, timestamp)                         // 在缓冲区开头写入新的时间戳
 	copy(blob[timestampSizeInBytes:], wrappedEntry[timestampSizeInBytes:]) // 复制原条目中除时间戳外的所有数据
 	copy(blob[len(wrappedEntry):], entry)                                  // 在原条目后追加新数据
+	binary.LittleEndian.PutUint64(blob[writtenAtOffset:], timestamp)       // 更新写入时间
 
 	return blob[:blobLength] // 返回完整的条目数据
 }
@@ -70,11 +95,11 @@ func appendToWrappedEntry(timestamp uint64, wrappedEntry []byte, entry []byte, b
 // 返回值: 条目中的值数据
 func readEntry(data []byte) []byte {
 	// timestamp + hash + key length + key + value
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:]) // 读取键长度(2字节)
+	length := int(binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])) // 读取键长度(2字节)
 	// 去除 timestamp hash key-length + key 之后就是value了
-	dst := make([]byte, len(data)-int(headersSizeInBytes+length)) // 计算并分配值数据所需的空间
+	dst := make([]byte, len(data)-(headersSizeInBytes+length)) // 计算并分配值数据所需的空间
 	copy(dst, data[headersSizeInBytes+length:])
-	
+
 	return dst // 返回值数据(注意:此处未实际复制值数据)
 }
 
@@ -89,6 +114,37 @@ func readTimestampFromEntry(data []byte) uint64 {
 	return binary.LittleEndian.Uint64(data) // 读取前8个字节作为时间戳
 }
 
+// readTTLFromEntry 从包装的条目中读取生存时间
+// 参数:
+//
+//	data: 包含完整条目信息的字节切片
+//
+// 返回值: 条目的生存时间（秒），0 表示使用全局的生存时间窗口
+func readTTLFromEntry(data []byte) uint32 {
+	// timestamp + hash + key length + ttl + key + value
+	return binary.LittleEndian.Uint32(data[ttlOffset:]) // 读取键长度后的4个字节作为生存时间
+}
+
+// readWrittenAtFromEntry 从包装的条目中读取写入时间
+// 与时间戳不同，写入时间不会被 Touch 和 SlidingExpiration 刷新，用于 MaxAge
+// 参数:
+//
+//	data: 包含完整条目信息的字节切片
+//
+// 返回值: 条目的写入时间（秒）
+func readWrittenAtFromEntry(data []byte) uint64 {
+	return binary.LittleEndian.Uint64(data[writtenAtOffset:]) // 读取生存时间后的8个字节作为写入时间
+}
+
+// writeWrittenAtToEntry 原地改写包装条目中的写入时间
+// 参数:
+//
+//	data: 包含完整条目信息的字节切片
+//	writtenAt: 新的写入时间（秒）
+func writeWrittenAtToEntry(data []byte, writtenAt uint64) {
+	binary.LittleEndian.PutUint64(data[writtenAtOffset:], writtenAt)
+}
+
 // readKeyFromEntry 从包装的条目中读取键
 // 参数:
 //
@@ -97,7 +153,7 @@ func readTimestampFromEntry(data []byte) uint64 {
 // 返回值: 条目中的键字符串
 func readKeyFromEntry(data []byte) string {
 	// timestamp + hash + key length + key + value
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:]) // 读取键长度(2字节)
+	length := int(binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])) // 读取键长度(2字节)
 
 	// copy on read
 	dst := make([]byte, length)                                   // 分配存储键数据的空间
@@ -115,7 +171,7 @@ func readKeyFromEntry(data []byte) string {
 // 返回值: 如果条目中的键与给定键相等则返回true，否则返回false
 func compareKeyFromEntry(data []byte, key string) bool {
 	// timestamp + hash + key length + key + value
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:]) // 读取键长度(2字节)
+	length := int(binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])) // 读取键长度(2字节)
 
 	return bytesToString(data[headersSizeInBytes:headersSizeInBytes+length]) == key // 将条目中的键与给定键进行比较
 }
@@ -139,3 +195,13 @@ func resetHashFromEntry(data []byte) {
 	// timestamp + hash + key length + key + value
 	binary.LittleEndian.PutUint64(data[timestampSizeInBytes:], 0) // 将哈希值位置的数据设置为0
 }
+
+// writeTimestampToEntry 原地更新条目中的时间戳，条目长度不变
+// 参数:
+//
+//	data: 包含完整条目信息的字节切片
+//	timestamp: 新的时间戳
+func writeTimestampToEntry(data []byte, timestamp uint64) {
+	// timestamp + hash + key length + key + value
+	binary.LittleEndian.PutUint64(data, timestamp) // 覆盖前8个字节的时间戳
+}
@@ -206,3 +206,22 @@ func TestResetHashFromEntry(t *testing.T) {
 		t.Error("Hash was not reset to 0")
 	}
 }
+
+func TestReadTTLFromEntry(t *testing.T) {
+	// 准备测试数据
+	var buffer []byte
+
+	// 创建包装条目
+	wrappedEntry := wrapEntryWithTTL(1234567890, 9876543210, 300, "testkey", []byte("testvalue"), &buffer)
+
+	// 验证结果
+	if ttl := readTTLFromEntry(wrappedEntry); ttl != 300 {
+		t.Errorf("Expected ttl %d, got %d", 300, ttl)
+	}
+	if key := readKeyFromEntry(wrappedEntry); key != "testkey" {
+		t.Errorf("Expected key %s, got %s", "testkey", key)
+	}
+	if ttl := readTTLFromEntry(wrapEntry(1234567890, 9876543210, "testkey", nil, &buffer)); ttl != 0 {
+		t.Errorf("Expected ttl %d, got %d", 0, ttl)
+	}
+}
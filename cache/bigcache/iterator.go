@@ -3,6 +3,7 @@ package bigcache
 import (
 	"errors"
 	"sync"
+	"time"
 )
 
 type iteratorError string
@@ -22,7 +23,9 @@ var emptyEntryInfo = EntryInfo{}
 // EntryInfo holds informations about entry in the cache
 type EntryInfo struct {
 	timestamp uint64
+	ttl       uint32
 	hash      uint64
+	shard     int
 	key       string
 	value     []byte
 	err       error
@@ -38,11 +41,21 @@ func (e EntryInfo) Hash() uint64 {
 	return e.hash
 }
 
+// Shard returns the index of the shard holding the entry
+func (e EntryInfo) Shard() int {
+	return e.shard
+}
+
 // Timestamp returns entry's timestamp (time of insertion)
 func (e EntryInfo) Timestamp() uint64 {
 	return e.timestamp
 }
 
+// TTL returns the entry's own lifetime set with SetWithTTL, 0 when the entry uses LifeWindow
+func (e EntryInfo) TTL() time.Duration {
+	return time.Duration(e.ttl) * time.Second
+}
+
 // Value returns entry's underlying value
 func (e EntryInfo) Value() []byte {
 	return e.value
@@ -116,9 +129,11 @@ func (it *EntryInfoIterator) setCurrentEntry() bool {
 	} else {
 		it.currentEntryInfo = EntryInfo{
 			timestamp: readTimestampFromEntry(entry),
+			ttl:       readTTLFromEntry(entry),
 			hash:      readHashFromEntry(entry),
+			shard:     it.currentShard,
 			key:       readKeyFromEntry(entry),
-			value:     readEntry(entry),
+			value:     it.cache.readValue(entry),
 			err:       err,
 		}
 	}
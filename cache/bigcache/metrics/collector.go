@@ -0,0 +1,104 @@
+// Package metrics 将 BigCache 的统计信息以 prometheus.Collector 的形式导出
+// 放在独立的子包中，使 bigcache 核心包不依赖 prometheus 客户端
+package metrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/andrewbytecoder/gokit/cache/bigcache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const subsystem = "bigcache"
+
+// Collector 在每次抓取时读取 BigCache 的 Stats、Len、Capacity 和各分片长度
+//
+// Stats 会被 ResetStats 清零，而 counter 不能回退，因此命中等计数按两次抓取之间的增量累加：
+// 某项计数比上次抓取时小说明统计被重置过，当前值全部计为增量。重置后在下一次抓取前
+// 又超过上次抓取值的计数无法识别出重置，重置前未被抓取的增量会被少计。
+type Collector struct {
+	cache *bigcache.BigCache
+
+	mu    sync.Mutex     // 保护 last 和 total，抓取可能并发进行
+	last  bigcache.Stats // 上次抓取时读取的统计信息
+	total bigcache.Stats // 导出的单调递增累计值
+
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	delHits     *prometheus.Desc
+	delMisses   *prometheus.Desc
+	collisions  *prometheus.Desc
+	entries     *prometheus.Desc
+	capacity    *prometheus.Desc
+	shardLength *prometheus.Desc
+}
+
+// NewCollector 创建导出 c 统计信息的 Collector，指标名形如 <namespace>_bigcache_hits_total
+// 返回的 Collector 需要由调用方注册，例如 prometheus.MustRegister(NewCollector(c, "app"))
+func NewCollector(c *bigcache.BigCache, namespace string) prometheus.Collector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+	}
+	return &Collector{
+		cache:       c,
+		hits:        desc("hits_total", "Number of successfully found keys."),
+		misses:      desc("misses_total", "Number of not found keys."),
+		delHits:     desc("delete_hits_total", "Number of successfully deleted keys."),
+		delMisses:   desc("delete_misses_total", "Number of not deleted keys."),
+		collisions:  desc("collisions_total", "Number of key collisions."),
+		entries:     desc("entries", "Number of entries in the cache."),
+		capacity:    desc("capacity_bytes", "Bytes allocated by the cache's byte queues."),
+		shardLength: desc("shard_entries", "Number of entries in each shard.", "shard"),
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.delHits
+	ch <- c.delMisses
+	ch <- c.collisions
+	ch <- c.entries
+	ch <- c.capacity
+	ch <- c.shardLength
+}
+
+// Collect 实现 prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.totals()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.delHits, prometheus.CounterValue, float64(stats.DelHits))
+	ch <- prometheus.MustNewConstMetric(c.delMisses, prometheus.CounterValue, float64(stats.DelMisses))
+	ch <- prometheus.MustNewConstMetric(c.collisions, prometheus.CounterValue, float64(stats.Collisions))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(c.cache.Len()))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(c.cache.Capacity()))
+	for i, n := range c.cache.ShardLens() {
+		ch <- prometheus.MustNewConstMetric(c.shardLength, prometheus.GaugeValue, float64(n), strconv.Itoa(i))
+	}
+}
+
+// totals 读取当前的统计信息并累加到单调递增的累计值中
+func (c *Collector) totals() bigcache.Stats {
+	stats := c.cache.Stats()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total.Hits += increase(stats.Hits, c.last.Hits)
+	c.total.Misses += increase(stats.Misses, c.last.Misses)
+	c.total.DelHits += increase(stats.DelHits, c.last.DelHits)
+	c.total.DelMisses += increase(stats.DelMisses, c.last.DelMisses)
+	c.total.Collisions += increase(stats.Collisions, c.last.Collisions)
+	c.last = stats
+	return c.total
+}
+
+// increase 返回计数自上次抓取以来的增量，计数变小时说明统计被重置，当前值即为增量
+func increase(current, last int64) int64 {
+	if current < last {
+		return current
+	}
+	return current - last
+}
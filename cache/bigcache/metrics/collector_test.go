@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/cache/bigcache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	cache, err := bigcache.New(context.Background(), bigcache.Config{
+		Shards:             2,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 16,
+		MaxEntrySize:       64,
+		StatsEnabled:       true,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, cache.Set(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+	_, _ = cache.Get("key0")
+	_, _ = cache.Get("key1")
+	_, _ = cache.Get("missing")
+	require.NoError(t, cache.Delete("key4"))
+	assert.Error(t, cache.Delete("missing"))
+
+	var expected strings.Builder
+	expected.WriteString(`
+# HELP app_bigcache_collisions_total Number of key collisions.
+# TYPE app_bigcache_collisions_total counter
+app_bigcache_collisions_total 0
+# HELP app_bigcache_delete_hits_total Number of successfully deleted keys.
+# TYPE app_bigcache_delete_hits_total counter
+app_bigcache_delete_hits_total 1
+# HELP app_bigcache_delete_misses_total Number of not deleted keys.
+# TYPE app_bigcache_delete_misses_total counter
+app_bigcache_delete_misses_total 1
+# HELP app_bigcache_entries Number of entries in the cache.
+# TYPE app_bigcache_entries gauge
+app_bigcache_entries 4
+# HELP app_bigcache_hits_total Number of successfully found keys.
+# TYPE app_bigcache_hits_total counter
+app_bigcache_hits_total 2
+# HELP app_bigcache_misses_total Number of not found keys.
+# TYPE app_bigcache_misses_total counter
+app_bigcache_misses_total 1
+# HELP app_bigcache_shard_entries Number of entries in each shard.
+# TYPE app_bigcache_shard_entries gauge
+`)
+	total := 0
+	for i, n := range cache.ShardLens() {
+		fmt.Fprintf(&expected, "app_bigcache_shard_entries{shard=\"%d\"} %d\n", i, n)
+		total += n
+	}
+	assert.Equal(t, 4, total)
+
+	collector := NewCollector(cache, "app")
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected.String()),
+		"app_bigcache_collisions_total",
+		"app_bigcache_delete_hits_total",
+		"app_bigcache_delete_misses_total",
+		"app_bigcache_entries",
+		"app_bigcache_hits_total",
+		"app_bigcache_misses_total",
+		"app_bigcache_shard_entries",
+	)
+	assert.NoError(t, err)
+
+	// capacity 取决于字节队列的初始分配，只校验与 Capacity() 一致
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	var capacity float64 = -1
+	for _, family := range families {
+		if family.GetName() == "app_bigcache_capacity_bytes" {
+			capacity = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, float64(cache.Capacity()), capacity)
+}
+
+func TestCollectorSamplesAtScrape(t *testing.T) {
+	cache, err := bigcache.New(context.Background(), bigcache.Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 16,
+		MaxEntrySize:       64,
+		StatsEnabled:       true,
+	})
+	require.NoError(t, err)
+	collector := NewCollector(cache, "")
+
+	expected := func(hits int) *strings.Reader {
+		return strings.NewReader(fmt.Sprintf(`
+# HELP bigcache_hits_total Number of successfully found keys.
+# TYPE bigcache_hits_total counter
+bigcache_hits_total %d
+`, hits))
+	}
+	assert.NoError(t, testutil.CollectAndCompare(collector, expected(0), "bigcache_hits_total"))
+
+	// 两次抓取之间的操作只反映在下一次抓取中
+	require.NoError(t, cache.Set("key", []byte("value")))
+	_, err = cache.Get("key")
+	require.NoError(t, err)
+	assert.NoError(t, testutil.CollectAndCompare(collector, expected(1), "bigcache_hits_total"))
+
+	// ResetStats 不能让 counter 回退，重置后的命中继续累加
+	require.NoError(t, cache.ResetStats())
+	assert.NoError(t, testutil.CollectAndCompare(collector, expected(1), "bigcache_hits_total"))
+	_, err = cache.Get("key")
+	require.NoError(t, err)
+	assert.NoError(t, testutil.CollectAndCompare(collector, expected(2), "bigcache_hits_total"))
+}
@@ -0,0 +1,104 @@
+package bigcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	hash2 "github.com/andrewbytecoder/gokit/encoding/hash"
+	"github.com/andrewbytecoder/gokit/math"
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// Option 配置 BigCache 的选项，输入不合法时返回错误
+type Option func(c *Config) error
+
+// NewWithOptions 使用选项模式创建 BigCache 实例
+// 未通过选项设置的字段使用 DefaultConfig(10 * time.Minute) 的默认值
+// 参数:
+//
+//	ctx: 上下文，用于控制清理goroutine的生命周期
+//	opts: 配置选项
+//
+// 返回值:
+//
+//	*BigCache: BigCache实例指针
+//	error: 错误信息
+func NewWithOptions(ctx context.Context, opts ...Option) (*BigCache, error) {
+	config := DefaultConfig(10 * time.Minute)
+	for _, opt := range opts {
+		if err := opt(&config); err != nil {
+			return nil, err
+		}
+	}
+	return newBigCache(ctx, config, clock.New())
+}
+
+// WithShards 设置分片数量，不是2的幂次方时向上取整为2的幂次方
+func WithShards(shards int) Option {
+	return func(c *Config) error {
+		if shards <= 0 {
+			return errors.New("shards number must be > 0")
+		}
+		c.Shards = math.NextPowerOfTwo(shards)
+		if c.Shards == 0 {
+			return errors.New("shards number is too large")
+		}
+		return nil
+	}
+}
+
+// WithLifeWindow 设置条目的生存时间
+func WithLifeWindow(lifeWindow time.Duration) Option {
+	return func(c *Config) error {
+		if lifeWindow <= 0 {
+			return errors.New("LifeWindow must be > 0")
+		}
+		c.LifeWindow = lifeWindow
+		return nil
+	}
+}
+
+// WithCleanWindow 设置清理过期条目的间隔，为0时不进行清理
+func WithCleanWindow(cleanWindow time.Duration) Option {
+	return func(c *Config) error {
+		if cleanWindow < 0 {
+			return errors.New("CleanWindow must be >= 0")
+		}
+		c.CleanWindow = cleanWindow
+		return nil
+	}
+}
+
+// WithMaxEntrySize 设置条目的最大字节数，用于计算分片的初始大小
+func WithMaxEntrySize(maxEntrySize int) Option {
+	return func(c *Config) error {
+		if maxEntrySize < 0 {
+			return errors.New("MaxEntrySize must be >= 0")
+		}
+		c.MaxEntrySize = maxEntrySize
+		return nil
+	}
+}
+
+// WithMaxValueSize 设置值的最大字节数，超过时写入返回 ErrValueTooLarge，0 表示不限制
+func WithMaxValueSize(maxValueSize int) Option {
+	return func(c *Config) error {
+		if maxValueSize < 0 {
+			return errors.New("MaxValueSize must be >= 0")
+		}
+		c.MaxValueSize = maxValueSize
+		return nil
+	}
+}
+
+// WithHasher 设置计算键哈希值的哈希函数
+func WithHasher(hasher hash2.Hasher) Option {
+	return func(c *Config) error {
+		if hasher == nil {
+			return errors.New("Hasher must not be nil")
+		}
+		c.Hasher = hasher
+		return nil
+	}
+}
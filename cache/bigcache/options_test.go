@@ -0,0 +1,83 @@
+package bigcache
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptionsDefaults(t *testing.T) {
+	t.Parallel()
+
+	// when
+	cache, err := NewWithOptions(context.Background())
+
+	// then 与 DefaultConfig 一致
+	noError(t, err)
+	defer cache.Close()
+	defaults := DefaultConfig(10 * time.Minute)
+	assertEqual(t, defaults.Shards, len(cache.shards))
+	assertEqual(t, defaults.LifeWindow, cache.config.LifeWindow)
+	assertEqual(t, defaults.CleanWindow, cache.config.CleanWindow)
+	assertEqual(t, defaults.MaxEntriesInWindow, cache.config.MaxEntriesInWindow)
+	assertEqual(t, defaults.MaxEntrySize, cache.config.MaxEntrySize)
+	assertEqual(t, defaults.Verbose, cache.config.Verbose)
+	assertEqual(t, true, cache.config.Hasher != nil)
+	assertEqual(t, true, cache.config.Logger != nil)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	t.Parallel()
+
+	// when
+	cache, err := NewWithOptions(context.Background(),
+		WithShards(100),
+		WithLifeWindow(time.Minute),
+		WithCleanWindow(0),
+		WithMaxEntrySize(256),
+		WithMaxValueSize(1024),
+		WithHasher(hashStub(5)),
+	)
+
+	// then
+	noError(t, err)
+	assertEqual(t, 128, len(cache.shards))
+	assertEqual(t, time.Minute, cache.config.LifeWindow)
+	assertEqual(t, time.Duration(0), cache.config.CleanWindow)
+	assertEqual(t, 256, cache.config.MaxEntrySize)
+	assertEqual(t, 1024, cache.config.MaxValueSize)
+	assertEqual(t, hashStub(5), cache.config.Hasher)
+
+	// when
+	cache.Set("key", []byte("value"))
+	cachedValue, err := cache.Get("key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), cachedValue)
+}
+
+func TestNewWithOptionsValidation(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		opt  Option
+		want string
+	}{
+		{opt: WithShards(0), want: "shards number must be > 0"},
+		{opt: WithShards(math.MaxInt), want: "shards number is too large"},
+		{opt: WithLifeWindow(0), want: "LifeWindow must be > 0"},
+		{opt: WithCleanWindow(-time.Second), want: "CleanWindow must be >= 0"},
+		{opt: WithMaxEntrySize(-1), want: "MaxEntrySize must be >= 0"},
+		{opt: WithMaxValueSize(-1), want: "MaxValueSize must be >= 0"},
+		{opt: WithHasher(nil), want: "Hasher must not be nil"},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			cache, err := NewWithOptions(context.Background(), tc.opt)
+
+			assertEqual(t, (*BigCache)(nil), cache)
+			assertEqual(t, tc.want, err.Error())
+		})
+	}
+}
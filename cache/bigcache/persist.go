@@ -0,0 +1,167 @@
+package bigcache
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// 快照格式：[magic][version][uvarint 条目数量]，之后每个条目为 [uvarint 长度][包装条目]
+// 包装条目保留了时间戳、生存时间和键，恢复后过期时间按原来的时间戳继续计算
+// 条目中的值是解压后的值，快照与写入和恢复时的 Compressor 无关
+const (
+	snapshotMagic   = "BCSN" // 快照文件头，用于识别快照格式
+	snapshotVersion = 3      // 快照格式版本，格式变化时递增
+)
+
+// ErrInvalidSnapshot is returned by Load when the input is not a snapshot, has an unsupported version,
+// or is corrupt or truncated
+var ErrInvalidSnapshot = errors.New("invalid cache snapshot")
+
+// WriteSnapshot 将缓存中的所有条目序列化到 w，之后可以通过 Load 恢复
+// 条目按时间戳从旧到新写入，恢复后仍按原来的顺序过期
+// 设置了 Compressor 时值以解压后的形式写入，恢复时按新缓存的 Compressor 重新编码
+// 注意：写入前会复制所有分片中的条目，额外内存约等于缓存中所有条目的大小；
+// 每个分片只在复制时持有读锁，快照不反映复制之后的修改
+// 参数:
+//
+//	w: 快照的写入目标
+//
+// 返回值:
+//
+//	error: 缓存已关闭时返回 ErrCacheClosed，值无法解压时返回 ErrCorruptValue，写入失败时返回 w 的错误
+func (c *BigCache) WriteSnapshot(w io.Writer) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	var entries [][]byte
+	for _, shard := range c.shards {
+		for _, entry := range shard.wrappedEntries() {
+			entry, err := c.decompressEntry(entry)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", readKeyFromEntry(entry), err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b []byte) int {
+		return cmp.Compare(readTimestampFromEntry(a), readTimestampFromEntry(b))
+	})
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(snapshotMagic)
+	bw.WriteByte(snapshotVersion)
+	header := make([]byte, 0, binary.MaxVarintLen64)
+	bw.Write(binary.AppendUvarint(header, uint64(len(entries))))
+	for _, entry := range entries {
+		bw.Write(binary.AppendUvarint(header[:0], uint64(len(entry))))
+		bw.Write(entry)
+	}
+	return bw.Flush() // bufio.Writer 会记住第一次写入错误，Flush 时返回
+}
+
+// Load 使用 config 创建缓存，并从 r 中恢复 WriteSnapshot 写入的条目
+// config 可以与写入快照的缓存不同，条目会按新缓存的哈希函数和分片数量重新分布
+// 参数:
+//
+//	ctx: 控制缓存清理协程生命周期的上下文
+//	r: 快照的读取来源
+//	config: 缓存配置
+//
+// 返回值:
+//
+//	*BigCache: 恢复后的缓存
+//	error: 输入不是有效的快照、版本不支持、数据损坏或被截断时返回 ErrInvalidSnapshot
+func Load(ctx context.Context, r io.Reader, config Config) (*BigCache, error) {
+	return load(ctx, r, config, clock.New())
+}
+
+func load(ctx context.Context, r io.Reader, config Config, clock clock.Clock) (*BigCache, error) {
+	cache, err := newBigCache(ctx, config, clock)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.restore(bufio.NewReader(r)); err != nil {
+		cache.Close()
+		return nil, err
+	}
+	return cache, nil
+}
+
+// restore 读取快照并写入所有条目
+func (c *BigCache) restore(r *bufio.Reader) error {
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: reading header: %w", ErrInvalidSnapshot, err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: bad magic %q", ErrInvalidSnapshot, header[:len(snapshotMagic)])
+	}
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("%w: reading entry count: %w", ErrInvalidSnapshot, noEOF(err))
+	}
+	var buf bytes.Buffer
+	for i := uint64(0); i < count; i++ {
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return fmt.Errorf("%w: reading entry %d: %w", ErrInvalidSnapshot, i, noEOF(err))
+		}
+		// 按实际读到的数据增长缓冲区，损坏的长度不会导致一次性分配大量内存
+		buf.Reset()
+		if n, err := io.CopyN(&buf, r, int64(min(length, 1<<62))); err != nil {
+			return fmt.Errorf("%w: entry %d truncated after %d of %d bytes", ErrInvalidSnapshot, i, n, length)
+		}
+		entry := buf.Bytes()
+		if !validWrappedEntry(entry) {
+			return fmt.Errorf("%w: entry %d is malformed", ErrInvalidSnapshot, i)
+		}
+
+		key := readKeyFromEntry(entry)
+		hashedKey := c.hash.Sum64(key)
+		shard := c.getShard(hashedKey)
+		currentTimestamp := uint64(shard.clock.Epoch())
+		if shard.isExpired(entry, currentTimestamp) { // 快照保存后已经过期的条目不再恢复
+			continue
+		}
+		stored, err := c.compress(readEntry(entry))
+		if err == nil {
+			err = shard.setWithTimestamp(key, hashedKey, stored, currentTimestamp,
+				readTimestampFromEntry(entry), readTTLFromEntry(entry))
+		}
+		if err != nil {
+			return fmt.Errorf("restoring key %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// validWrappedEntry 检查条目是否包含完整的头部和键
+func validWrappedEntry(entry []byte) bool {
+	if len(entry) < headersSizeInBytes {
+		return false
+	}
+	keyLength := int(binary.LittleEndian.Uint16(entry[timestampSizeInBytes+hashSizeInBytes:]))
+	return len(entry) >= headersSizeInBytes+keyLength
+}
+
+// noEOF 将读到一半遇到的 io.EOF 转换为 io.ErrUnexpectedEOF，快照中条目数量之前的任何 EOF 都意味着截断
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
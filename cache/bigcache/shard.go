@@ -1,9 +1,14 @@
 package bigcache
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/andrewbytecoder/gokit/container/bytesqyeye"
 	"github.com/andrewbytecoder/gokit/timer/clock"
@@ -15,6 +20,8 @@ type RemoveReason uint32
 
 // 定义移除原因的常量
 const (
+	// NotRemoved 是零值，表示条目没有被移除，例如 GetWithInfo 读到未过期条目时 Response 的 EntryStatus
+	NotRemoved = RemoveReason(0)
 	// Expired 表示条目因过期而被移除
 	Expired = RemoveReason(1)
 	// NoSpace 表示由于空间不足而移除条目
@@ -23,8 +30,31 @@ const (
 	Deleted = RemoveReason(3)
 )
 
+// String 返回移除原因的名称，未知的原因返回 "Unknown(n)"
+func (r RemoveReason) String() string {
+	switch r {
+	case NotRemoved:
+		return "NotRemoved"
+	case Expired:
+		return "Expired"
+	case NoSpace:
+		return "NoSpace"
+	case Deleted:
+		return "Deleted"
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint32(r))
+	}
+}
+
+// MarshalText 实现 encoding.TextMarshaler，使移除原因在日志和 JSON 中以名称输出
+func (r RemoveReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
 // onRemoveCallback 定义了当缓存条目被移除时调用的回调函数类型
-type onRemoveCallback func(wrappedEntry []byte, reason RemoveReason)
+// metadata 由执行移除的分片在持有写锁时读取，回调不能再按键的哈希值查找分片：
+// SetWithAffinity 写入的条目不在键的哈希值对应的分片中
+type onRemoveCallback func(wrappedEntry []byte, reason RemoveReason, metadata Metadata)
 
 // Metadata 包含特定缓存条目的信息
 type Metadata struct {
@@ -32,6 +62,12 @@ type Metadata struct {
 	RequestCount uint32
 }
 
+// snapshotEntry 是 snapshot 复制出的单个条目
+type snapshotEntry struct {
+	key   string // 条目的键
+	value []byte // 条目数据的副本
+}
+
 // Response will contain metadata about the entry for witch GetWithInfo(key) was called
 type Response struct {
 	EntryStatus RemoveReason
@@ -40,8 +76,28 @@ type Response struct {
 var (
 	// ErrEntryNotFound is an error type struct which is returned when entry was not found for provided key
 	ErrEntryNotFound = errors.New("entry not found")
+	// ErrKeyTooLong is returned when the key is longer than the 2 bytes key length header can hold
+	ErrKeyTooLong = errors.New("key is too long")
+	// ErrValueTooLarge is returned when the value is larger than Config.MaxValueSize
+	ErrValueTooLarge = errors.New("value is too large")
+	// ErrAppendLimitExceeded is returned by AppendBounded when the value would grow beyond the given limit
+	ErrAppendLimitExceeded = errors.New("append would exceed the value size limit")
+	// ErrCacheClosed is returned by the operations of a cache that was closed or whose context was cancelled
+	ErrCacheClosed = errors.New("cache is closed")
+	// ErrMalformedSegments is returned by GetSegments when the value was not written by AppendSegment alone
+	ErrMalformedSegments = errors.New("value is not a sequence of segments")
+
+	// ErrShardFull is returned when a new key is added to a shard holding Config.MaxEntriesPerShard entries
+	// and Config.RejectOnFull is set
+	ErrShardFull = errors.New("shard reached the maximum number of entries")
+
+	// errEvictionVetoed is returned by removeOldestEntry when CanEvict vetoed every candidate
+	errEvictionVetoed = errors.New("eviction vetoed by CanEvict")
 )
 
+// maxEvictionVetoes bounds the number of entries removeOldestEntry skips because CanEvict vetoed them
+const maxEvictionVetoes = 128
+
 // cacheShard 表示缓存的一个分片，用于存储实际的缓存数据
 type cacheShard struct {
 	// hashmap 存储键的哈希值到条目在队列中位置的映射
@@ -50,13 +106,24 @@ type cacheShard struct {
 	entries bytesqyeye.BytesQueue
 	// lock 用于保护分片的并发访问
 	lock sync.RWMutex
+	// newEntries 启用 LazyShards 时用于在第一次写入时创建字节队列，创建后置为nil
+	newEntries func() (*bytesqyeye.BytesQueue, error)
+	// closed 表示 close 已释放字节队列，受 lock 保护；
+	// 通过 BigCache 关闭检查的操作可能在 close 之后才取得锁，必须在持锁时检查它再访问 entries
+	closed bool
 	// entryBuffer 用于临时存储条目数据的缓冲区
 	entryBuffer []byte
 	// onRemove 是条目被移除时调用的回调函数
 	onRemove onRemoveCallback
+	// cold 是磁盘冷层，未配置时为nil；写入键时使冷层中同一哈希值的旧条目失效
+	cold *coldTier
 
 	// isVerbose 指示是否启用详细日志记录
 	isVerbose bool
+	// collisionLogSampleRate 详细日志模式下每多少次哈希冲突记录一次日志
+	collisionLogSampleRate uint64
+	// collisionSamples 哈希冲突的采样计数器
+	collisionSamples atomic.Uint64
 	// statsEnabled 指示是否启用统计信息收集
 	statsEnabled bool
 	// logger 用于记录日志
@@ -65,6 +132,28 @@ type cacheShard struct {
 	clock clock.Clock
 	// lifeWindow 定义条目的生存时间窗口（以秒为单位）
 	lifeWindow uint64
+	// slidingExpiration 指示读取条目时是否重置其过期时间
+	slidingExpiration bool
+	// maxAge 条目的最长保留时间（以秒为单位），限制 lifeWindow 和条目自带的生存时间，0 表示不限制
+	maxAge uint64
+	// neverExpire 指示条目是否永不过期（LifeWindow 为 0 且显式设置了 AllowNeverExpire）
+	neverExpire bool
+	// oversizePolicy 决定 set 如何处理分片放不下的条目
+	oversizePolicy OversizeEntryPolicy
+	// maxValueSize 存储的值的最大字节数，0 表示不限制；设置了 Compressor 时为 0，由 BigCache 在压缩前检查
+	maxValueSize int
+	// maxEntries 分片的最大条目数，0 表示不限制
+	maxEntries int
+	// rejectOnFull 达到 maxEntries 时拒绝新键而不是淘汰最旧的条目
+	rejectOnFull bool
+	// canEvict 返回false时阻止淘汰最旧的条目，为nil时总是允许淘汰
+	canEvict func(key string, reason RemoveReason) bool
+	// nextExpiry 队列头部条目最早可能过期的时间戳（不大于其实际过期时间），
+	// 清理时当前时间不超过它的分片无需加锁即可跳过
+	nextExpiry atomic.Uint64
+	// nextMaxAgeExpiry 分片中所有条目因 maxAge 最早可能过期的时间戳（不大于其实际值），
+	// 只在超过它时清理才需要遍历整个分片，查找不在队列头部的超过 maxAge 的条目
+	nextMaxAgeExpiry atomic.Uint64
 
 	// hashmapStats 存储每个哈希值的统计信息
 	hashmapStats map[uint64]uint32
@@ -95,9 +184,8 @@ func (s *cacheShard) getWithInfo(key string, hashedKey uint64) (entry []byte, re
 	}
 
 	if entryKey := readKeyFromEntry(wrappedEntry); key != entryKey { // 比较键是否匹配（处理哈希冲突）
-		s.lock.RUnlock() // 释放读锁
-		s.collision()    // 记录哈希冲突统计
-		if s.isVerbose { // 如果启用了详细日志
+		s.lock.RUnlock()   // 释放读锁
+		if s.collision() { // 记录哈希冲突统计，按采样率决定是否记录日志
 			s.logger.Info("Collision detected", zap.String("key", key), zap.Uint64("hashedKey", hashedKey),
 				zap.String("entryKey", entryKey)) // 记录哈希冲突日志
 		}
@@ -114,6 +202,84 @@ func (s *cacheShard) getWithInfo(key string, hashedKey uint64) (entry []byte, re
 	return entry, resp, nil // 返回条目数据、响应信息和nil错误
 }
 
+// getWithTTL 根据键和哈希值获取缓存条目，并返回条目的剩余生存时间
+// 参数:
+//
+//	key: 要查找的键字符串
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	entry: 找到的条目数据
+//	ttl: 剩余生存时间，已过期但尚未清理的条目为负数，永不过期的条目为 NoExpiration
+//	err: 错误信息，如果查找失败则返回相应错误
+func (s *cacheShard) getWithTTL(key string, hashedKey uint64) (entry []byte, ttl time.Duration, err error) {
+	currentTime := uint64(s.clock.Epoch())            // 获取当前时间戳（秒）
+	s.lock.RLock()                                    // 获取读锁以保证并发安全
+	wrappedEntry, err := s.getWrappedEntry(hashedKey) // 根据哈希值获取包装的条目
+	if err != nil {                                   // 如果获取条目失败
+		s.lock.RUnlock()   // 释放读锁
+		return nil, 0, err // 返回错误
+	}
+
+	if !compareKeyFromEntry(wrappedEntry, key) { // 原地比较键是否匹配（处理哈希冲突）
+		var entryKey string
+		logCollision := s.collision() // 记录哈希冲突统计，按采样率决定是否记录日志
+		if logCollision {
+			entryKey = readKeyFromEntry(wrappedEntry)
+		}
+		s.lock.RUnlock() // 释放读锁
+		if logCollision {
+			s.logger.Info("Collision detected", zap.String("key", key), zap.Uint64("hashedKey", hashedKey),
+				zap.String("entryKey", entryKey)) // 记录哈希冲突日志
+		}
+		return nil, 0, ErrEntryNotFound // 返回条目未找到错误
+	}
+
+	entry = readEntry(wrappedEntry)                  // 从包装条目中提取实际数据
+	ttl = s.remainingLife(wrappedEntry, currentTime) // 计算剩余生存时间
+	s.lock.RUnlock()                                 // 释放读锁
+	s.hit(hashedKey)                                 // 记录命中统计
+	return entry, ttl, nil
+}
+
+// tryGet 根据键和哈希值获取缓存条目，并返回条目是否仍在生存时间窗口内
+// 参数:
+//
+//	key: 要查找的键字符串
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	entry: 找到的条目数据
+//	fresh: 条目未超过生存时间窗口时为true，已过期但仍存在时为false
+//	err: 错误信息，如果查找失败则返回相应错误
+func (s *cacheShard) tryGet(key string, hashedKey uint64) (entry []byte, fresh bool, err error) {
+	currentTime := uint64(s.clock.Epoch())            // 获取当前时间戳（秒）
+	s.lock.RLock()                                    // 获取读锁以保证并发安全
+	wrappedEntry, err := s.getWrappedEntry(hashedKey) // 根据哈希值获取包装的条目
+	if err != nil {                                   // 如果获取条目失败
+		s.lock.RUnlock()       // 释放读锁
+		return nil, false, err // 返回错误
+	}
+
+	if entryKey := readKeyFromEntry(wrappedEntry); key != entryKey { // 比较键是否匹配（处理哈希冲突）
+		s.lock.RUnlock()   // 释放读锁
+		if s.collision() { // 记录哈希冲突统计，按采样率决定是否记录日志
+			s.logger.Info("Collision detected", zap.String("key", key), zap.Uint64("hashedKey", hashedKey),
+				zap.String("entryKey", entryKey)) // 记录哈希冲突日志
+		}
+		return nil, false, ErrEntryNotFound // 返回条目未找到错误
+	}
+
+	entry = readEntry(wrappedEntry)                 // 从包装条目中提取实际数据
+	fresh = !s.isExpired(wrappedEntry, currentTime) // 检查条目是否仍在生存时间窗口内
+
+	s.lock.RUnlock()         // 释放读锁
+	s.hit(hashedKey)         // 记录命中统计
+	return entry, fresh, nil // 返回条目数据、新鲜度和nil错误
+}
+
 // get 根据键和哈希值获取缓存条目
 // 参数:
 //
@@ -125,17 +291,67 @@ func (s *cacheShard) getWithInfo(key string, hashedKey uint64) (entry []byte, re
 //	[]byte: 找到的条目数据
 //	error: 错误信息，如果查找失败则返回相应错误
 func (s *cacheShard) get(key string, hashedKey uint64) ([]byte, error) {
-	s.lock.RLock()                                    // 获取读锁以保证并发安全
+	if s.slidingExpiration { // 滑动过期模式下读取需要更新时间戳
+		return s.getAndTouch(key, hashedKey)
+	}
+
+	s.lock.RLock() // 获取读锁以保证并发安全
+	return s.getAndRUnlock(key, hashedKey)
+}
+
+// getNonBlocking 与 get 相同，但分片锁被占用时不等待，直接返回 checked 为 false
+// 参数:
+//
+//	key: 要查找的键字符串
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	[]byte: 找到的条目数据
+//	bool: 是否拿到锁并完成了查找
+//	error: 错误信息，如果查找失败则返回相应错误
+func (s *cacheShard) getNonBlocking(key string, hashedKey uint64) (entry []byte, checked bool, err error) {
+	if s.slidingExpiration { // 滑动过期模式下读取需要写锁更新时间戳
+		if !s.lock.TryLock() {
+			return nil, false, nil
+		}
+		defer s.lock.Unlock()
+		entry, err = s.getAndTouchWithoutLock(key, hashedKey)
+		return entry, true, err
+	}
+
+	if !s.lock.TryRLock() { // 有写者持有或等待锁
+		return nil, false, nil
+	}
+	entry, err = s.getAndRUnlock(key, hashedKey)
+	return entry, true, err
+}
+
+// getAndRUnlock 在持有读锁时查找条目，返回前释放读锁，命中统计和冲突日志在释放读锁后记录
+// 参数:
+//
+//	key: 要查找的键字符串
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	[]byte: 找到的条目数据
+//	error: 错误信息，如果查找失败则返回相应错误
+func (s *cacheShard) getAndRUnlock(key string, hashedKey uint64) ([]byte, error) {
 	wrappedEntry, err := s.getWrappedEntry(hashedKey) // 根据哈希值获取包装的条目
 	if err != nil {                                   // 如果获取条目失败
 		s.lock.RUnlock() // 释放读锁
 		return nil, err  // 返回错误
 	}
 
-	if entryKey := readKeyFromEntry(wrappedEntry); key != entryKey { // 比较键是否匹配（处理哈希冲突）
-		s.lock.RUnlock() // 释放读锁
-		s.collision()    // 记录哈希冲突统计
-		if s.isVerbose { // 如果启用了详细日志
+	if !compareKeyFromEntry(wrappedEntry, key) { // 原地比较键是否匹配（处理哈希冲突），命中时不复制键
+		var entryKey string
+		logCollision := s.collision() // 记录哈希冲突统计，按采样率决定是否记录日志
+		if logCollision {             // 只有需要记录日志时才复制条目中的键
+			entryKey = readKeyFromEntry(wrappedEntry)
+		}
+		s.lock.RUnlock()  // 释放读锁
+		if logCollision { // 记录日志
 			s.logger.Info("Collision detected", zap.String("key", key), zap.Uint64("hashedKey", hashedKey),
 				zap.String("entryKey", entryKey)) // 记录哈希冲突日志
 		}
@@ -147,6 +363,89 @@ func (s *cacheShard) get(key string, hashedKey uint64) ([]byte, error) {
 	return entry, nil                // 返回条目数据和nil错误
 }
 
+// getBatch 在一次读锁内查找多个键，并将结果写入 results 中对应的位置
+// 参数:
+//
+//	results: 批量查询结果
+//	items: 属于本分片的键，index 为其在 results 中的位置
+func (s *cacheShard) getBatch(results []BatchResult, items []batchItem) {
+	s.lock.RLock() // 获取读锁以保证并发安全
+	for _, item := range items {
+		result := &results[item.index]
+		wrappedEntry, err := s.getWrappedEntry(item.hashedKey) // 根据哈希值获取包装的条目
+		if err != nil {                                        // 如果获取条目失败
+			result.Err = err
+			continue
+		}
+		if !compareKeyFromEntry(wrappedEntry, item.key) { // 原地比较键是否匹配（处理哈希冲突），命中时不复制键
+			if s.collision() { // 记录哈希冲突统计，按采样率决定是否记录日志
+				s.logger.Info("Collision detected", zap.String("key", item.key), zap.Uint64("hashedKey", item.hashedKey),
+					zap.String("entryKey", readKeyFromEntry(wrappedEntry))) // 记录哈希冲突日志
+			}
+			result.Err = ErrEntryNotFound
+			continue
+		}
+		result.Value = readEntry(wrappedEntry) // 从包装条目中提取实际数据
+	}
+	s.lock.RUnlock() // 释放读锁
+
+	for _, item := range items {
+		if results[item.index].Err == nil {
+			s.hit(item.hashedKey) // 释放读锁后记录命中统计
+		}
+	}
+}
+
+// getAndTouch 根据键和哈希值获取缓存条目，并将条目的时间戳原地更新为当前时间
+// 由于要修改条目，需要持有写锁
+// 参数:
+//
+//	key: 要查找的键字符串
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	[]byte: 找到的条目数据
+//	error: 错误信息，如果查找失败则返回相应错误
+func (s *cacheShard) getAndTouch(key string, hashedKey uint64) ([]byte, error) {
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	return s.getAndTouchWithoutLock(key, hashedKey)
+}
+
+// getAndTouchWithoutLock 读取条目并将其时间戳更新为当前时间
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) getAndTouchWithoutLock(key string, hashedKey uint64) ([]byte, error) {
+	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey) // 获取键匹配的包装条目，同时记录命中或冲突统计
+	if err != nil {
+		return nil, err
+	}
+	writeTimestampToEntry(wrappedEntry, uint64(s.clock.Epoch())) // 重置条目的过期时间
+	return readEntry(wrappedEntry), nil                          // 返回条目数据
+}
+
+// touch 将条目的时间戳原地更新为当前时间，延长其生存期但不读取值
+// 参数:
+//
+//	key: 要更新的键
+//	hashedKey: 键的哈希值
+//
+// 返回值:
+//
+//	error: 错误信息，如果条目不存在则返回 ErrEntryNotFound
+func (s *cacheShard) touch(key string, hashedKey uint64) error {
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey) // 获取键匹配的包装条目
+	if err != nil {
+		return err
+	}
+	writeTimestampToEntry(wrappedEntry, uint64(s.clock.Epoch())) // 时间戳头部长度固定，原地覆盖即可
+	return nil
+}
+
 // getWrappedEntry 根据哈希键获取包装的条目数据
 // 参数:
 //
@@ -157,6 +456,9 @@ func (s *cacheShard) get(key string, hashedKey uint64) ([]byte, error) {
 //	[]byte: 包装的条目数据
 //	error: 错误信息，如果找不到条目或获取失败则返回相应错误
 func (s *cacheShard) getWrappedEntry(hashedKey uint64) ([]byte, error) {
+	if s.closed { // 字节队列已释放
+		return nil, ErrCacheClosed
+	}
 	itemIndex := s.hashmap[hashedKey] // 从hashmap中获取条目在队列中的索引
 
 	if itemIndex == 0 { // 如果索引为0，表示条目不存在
@@ -169,6 +471,10 @@ func (s *cacheShard) getWrappedEntry(hashedKey uint64) ([]byte, error) {
 		s.miss()        // 记录未命中统计
 		return nil, err // 返回错误
 	}
+	if s.maxAge > 0 && s.pastMaxAge(wrappedEntry, uint64(s.clock.Epoch())) { // 超过最长保留时间的条目在被清理前也不再返回
+		s.miss()                     // 记录未命中统计
+		return nil, ErrEntryNotFound // 返回条目未找到错误
+	}
 	return wrappedEntry, nil // 返回找到的包装条目数据
 }
 
@@ -189,8 +495,7 @@ func (s *cacheShard) getValidWrapEntry(key string, hashedKey uint64) ([]byte, er
 	}
 
 	if !compareKeyFromEntry(wrappedEntry, key) { // 比较条目中的键与提供的键是否匹配
-		s.collision()    // 记录哈希冲突统计
-		if s.isVerbose { // 如果启用了详细日志
+		if s.collision() { // 记录哈希冲突统计，按采样率决定是否记录日志
 			// hash 冲突
 			s.logger.Info("Collision detected", zap.String("key", key),
 				zap.String("wrappedKey", readKeyFromEntry(wrappedEntry))) // 记录哈希冲突日志
@@ -213,9 +518,130 @@ func (s *cacheShard) getValidWrapEntry(key string, hashedKey uint64) ([]byte, er
 //
 //	error: 错误信息，如果设置失败则返回相应错误
 func (s *cacheShard) set(key string, hashedKey uint64, entry []byte) error {
-	currentTimestamp := uint64(s.clock.Epoch()) // 获取当前时间戳
+	currentTimestamp := uint64(s.clock.Epoch())                                             // 获取当前时间戳
+	return s.setWithTimestamp(key, hashedKey, entry, currentTimestamp, currentTimestamp, 0) // 以当前时间作为条目时间戳写入
+}
 
-	s.lock.Lock() // 获取写锁以保证并发安全
+// setWithTTL 在缓存中设置键值对，并使用条目自己的生存时间
+// 参数:
+//
+//	key: 要设置的键
+//	hashedKey: 键的哈希值
+//	entry: 要存储的值
+//	ttl: 条目的生存时间（秒），0 表示使用全局的生存时间窗口
+//
+// 返回值:
+//
+//	error: 错误信息，如果设置失败则返回相应错误
+func (s *cacheShard) setWithTTL(key string, hashedKey uint64, entry []byte, ttl uint32) error {
+	currentTimestamp := uint64(s.clock.Epoch())
+	return s.setWithTimestamp(key, hashedKey, entry, currentTimestamp, currentTimestamp, ttl)
+}
+
+// setWithTimestamp 在缓存中设置键值对，并使用指定的条目时间戳
+// 参数:
+//
+//	key: 要设置的键
+//	hashedKey: 键的哈希值
+//	entry: 要存储的值
+//	currentTimestamp: 当前时间戳，用于淘汰过期条目
+//	entryTimestamp: 写入条目的时间戳，用于计算条目的过期时间
+//	ttl: 条目的生存时间（秒），0 表示使用全局的生存时间窗口
+//
+// 返回值:
+//
+//	error: 错误信息，如果设置失败则返回相应错误
+func (s *cacheShard) setWithTimestamp(key string, hashedKey uint64, entry []byte, currentTimestamp, entryTimestamp uint64, ttl uint32) error {
+	if err := s.validate(key, entry); err != nil { // 在加锁和包装条目之前拒绝无效的键值
+		return err
+	}
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	if err := s.initEntriesWithoutLock(); err != nil { // 延迟创建字节队列
+		return err
+	}
+	return s.setWithoutLock(key, hashedKey, entry, currentTimestamp, entryTimestamp, ttl)
+}
+
+// batchItem 是批量操作中的单个键
+type batchItem struct {
+	shard     int    // 所属分片的下标，用于按分片分组
+	index     int    // 在 GetBatch 结果中的位置
+	key       string // 规范化后的键
+	hashedKey uint64 // 键的哈希值
+	entry     []byte // SetMulti 要存储的值
+}
+
+// setBatch 在一次写锁内写入多个属于本分片的键值对
+// 参数:
+//
+//	items: 要写入的键值对
+//
+// 返回值:
+//
+//	error: 写入失败的键的错误合并后的结果，其余键照常写入
+func (s *cacheShard) setBatch(items []batchItem) error {
+	currentTimestamp := uint64(s.clock.Epoch()) // 整批条目使用同一个时间戳
+
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	if err := s.initEntriesWithoutLock(); err != nil { // 延迟创建字节队列
+		return err
+	}
+	var errs []error
+	for _, item := range items {
+		err := s.validate(item.key, item.entry) // 无效的键值被跳过，不影响其他键
+		if err == nil {
+			err = s.setWithoutLock(item.key, item.hashedKey, item.entry, currentTimestamp, currentTimestamp, 0)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key %q: %w", item.key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate 检查键和值能否写入
+// 参数:
+//
+//	key: 要设置的键
+//	entry: 要存储的值
+//
+// 返回值:
+//
+//	error: 键超过头部能表示的长度时返回 ErrKeyTooLong，值超过 MaxValueSize 时返回 ErrValueTooLarge
+func (s *cacheShard) validate(key string, entry []byte) error {
+	if len(key) > maxKeySize { // 键长度超过头部能表示的范围
+		return ErrKeyTooLong
+	}
+	if s.maxValueSize > 0 && len(entry) > s.maxValueSize { // 值超过硬性上限
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+// setWithoutLock 写入条目，必要时淘汰最旧的条目腾出空间
+// 参数:
+//
+//	key: 要设置的键
+//	hashedKey: 键的哈希值
+//	entry: 要存储的值
+//	currentTimestamp: 当前时间戳，用于淘汰过期条目
+//	entryTimestamp: 写入条目的时间戳
+//	ttl: 条目的生存时间（秒）
+//
+// 返回值:
+//
+//	error: 错误信息，如果设置失败则返回相应错误
+//
+// 注意: 调用此函数前必须已经持有写锁，且字节队列已经创建
+func (s *cacheShard) setWithoutLock(key string, hashedKey uint64, entry []byte, currentTimestamp, entryTimestamp uint64, ttl uint32) error {
+	if err := s.ensureEntryLimitWithoutLock(hashedKey); err != nil { // 新键需要在条目数上限内
+		return err
+	}
+	s.invalidateColdWithoutLock(hashedKey) // 冷层中的旧值即将被覆盖
 
 	if previousIndex := s.hashmap[hashedKey]; previousIndex != 0 { // 如果已存在相同哈希键的条目
 		if previousEntry, err := s.entries.Get(int(previousIndex)); err == nil { // 获取旧条目
@@ -231,20 +657,96 @@ func (s *cacheShard) set(key string, hashedKey uint64, entry []byte) error {
 		}
 	}
 
-	w := wrapEntry(currentTimestamp, hashedKey, key, entry, &s.entryBuffer) // 包装条目数据
+	w := wrapEntryWithTTL(entryTimestamp, hashedKey, ttl, key, entry, &s.entryBuffer) // 包装条目数据
 
 	for {
-		if index, err := s.entries.Push(w); err == nil { // 尝试将包装条目推入队列
+		index, err := s.push(w) // 尝试将包装条目推入队列
+		if err == nil {
 			s.hashmap[hashedKey] = uint64(index) // 更新hashmap中的索引
-			s.lock.Unlock()                      // 释放写锁
 			return nil                           // 返回成功
 		}
 		// 上面push失败，这里还pop不了，只能是容量不够
 		if s.removeOldestEntry(NoSpace) != nil { // 尝试删除最旧条目以腾出空间
-			s.lock.Unlock()                                          // 释放写锁
-			return errors.New("entry is bigger than max shard size") // 返回条目过大错误
+			return s.setOversizeWithoutLock(key, hashedKey, entry, entryTimestamp, ttl, err) // 按策略处理超大条目
+		}
+	}
+}
+
+// setOversizeWithoutLock 按 oversizePolicy 处理清空分片后仍然放不下的条目
+// 参数:
+//
+//	key: 要设置的键
+//	hashedKey: 键的哈希值
+//	entry: 要存储的值
+//	entryTimestamp: 写入条目的时间戳
+//	ttl: 条目的生存时间（秒）
+//	pushErr: 字节队列返回的错误
+//
+// 返回值:
+//
+//	error: Reject 策略或截断后仍无法写入时返回条目过大错误
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) setOversizeWithoutLock(key string, hashedKey uint64, entry []byte, entryTimestamp uint64, ttl uint32, pushErr error) error {
+	switch s.oversizePolicy {
+	case Skip: // 不保存条目，静默返回
+		return nil
+	case Truncate: // 只保存能放下的前缀
+		if n := s.entries.MaxPushSize() - headersSizeInBytes - len(key); n >= 0 && n < len(entry) {
+			w := wrapEntryWithTTL(entryTimestamp, hashedKey, ttl, key, entry[:n], &s.entryBuffer) // 包装截断后的条目
+			if index, err := s.push(w); err == nil {
+				s.hashmap[hashedKey] = uint64(index) // 更新hashmap中的索引
+				return nil
+			}
 		}
 	}
+	return fmt.Errorf("entry is bigger than max shard size: %w", pushErr) // 返回条目过大错误，包装字节队列的错误
+}
+
+// initEntriesWithoutLock 启用 LazyShards 时在第一次写入前创建字节队列
+// 返回值:
+//
+//	error: 分片已关闭时返回 ErrCacheClosed，字节队列分配失败时返回错误
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) initEntriesWithoutLock() error {
+	if s.closed { // 字节队列已释放，不能再写入，也不能重新分配不会被释放的堆外内存
+		return ErrCacheClosed
+	}
+	if s.newEntries == nil { // 字节队列已经创建
+		return nil
+	}
+	entries, err := s.newEntries()
+	if err != nil {
+		return err
+	}
+	s.entries = *entries
+	s.newEntries = nil
+	return nil
+}
+
+// growFor 必要时提高字节队列的最大容量，使其能够容纳指定的条目
+// 参数:
+//
+//	key: 要设置的键
+//	entry: 要存储的值
+//	limit: 最大容量允许提高到的上限（字节）
+func (s *cacheShard) growFor(key string, entry []byte, limit int) {
+	need := headersSizeInBytes + len(key) + len(entry) + binary.MaxVarintLen32 // 计算条目在队列中最多需要的字节数
+
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	if err := s.initEntriesWithoutLock(); err != nil { // 延迟创建字节队列，失败时由随后的写入返回错误
+		return
+	}
+	maxCapacity := s.entries.MaxCapacity()                           // 获取当前的最大容量
+	if maxCapacity == 0 || s.entries.Capacity()+need < maxCapacity { // 如果不限制容量或者容量足够
+		return
+	}
+	if newMaxCapacity := min(limit, s.entries.Capacity()+need+1); newMaxCapacity > maxCapacity { // 在上限内提高最大容量
+		s.entries.SetMaxCapacity(newMaxCapacity)
+	}
 }
 
 // addNewWithoutLock 在不持有写锁的情况下添加新的缓存条目
@@ -260,6 +762,16 @@ func (s *cacheShard) set(key string, hashedKey uint64, entry []byte) error {
 //
 // 注意: 调用此函数前必须已经持有写锁
 func (s *cacheShard) addNewWithoutLock(key string, hashedKey uint64, entry []byte) error {
+	if err := s.validate(key, entry); err != nil { // 检查键和值能否写入
+		return err
+	}
+	if err := s.initEntriesWithoutLock(); err != nil { // 延迟创建字节队列
+		return err
+	}
+	if err := s.ensureEntryLimitWithoutLock(hashedKey); err != nil { // 新键需要在条目数上限内
+		return err
+	}
+	s.invalidateColdWithoutLock(hashedKey)      // 冷层中的旧值即将被覆盖
 	currentTimestamp := uint64(s.clock.Epoch()) // 获取当前时间戳
 
 	if !s.cleanEnabled { // 如果未启用自动清理
@@ -271,15 +783,56 @@ func (s *cacheShard) addNewWithoutLock(key string, hashedKey uint64, entry []byt
 	w := wrapEntry(currentTimestamp, hashedKey, key, entry, &s.entryBuffer) // 包装条目数据
 
 	for {
-		if index, err := s.entries.Push(w); err == nil { // 尝试将包装条目推入队列
+		index, err := s.push(w) // 尝试将包装条目推入队列
+		if err == nil {
 			s.hashmap[hashedKey] = uint64(index) // 更新hashmap中的索引
 			return nil                           // 返回成功
 		}
 		// 上面push失败，这里还pop不了，只能是容量不够
 		if s.removeOldestEntry(NoSpace) != nil { // 尝试删除最旧条目以腾出空间
-			return errors.New("entry is bigger than max shard size") // 返回条目过大错误
+			return fmt.Errorf("entry is bigger than max shard size: %w", err) // 返回条目过大错误，包装字节队列的错误
+		}
+	}
+}
+
+// invalidateColdWithoutLock 在写入键之前移除冷层中同一哈希值的条目
+// 冷层中的副本此后已经过时；哈希冲突时被覆盖的是另一个键，它的旧值同样不能再从冷层取回
+// 参数:
+//
+//	hashedKey: 要写入的键的哈希值
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) invalidateColdWithoutLock(hashedKey uint64) {
+	if s.cold != nil {
+		s.cold.remove(hashedKey)
+	}
+}
+
+// ensureEntryLimitWithoutLock 在写入新键前保证分片的条目数低于 maxEntries，
+// 覆盖已有的键不会增加条目数，无需检查
+// 参数:
+//
+//	hashedKey: 要写入的键的哈希值
+//
+// 返回值:
+//
+//	error: 设置了 rejectOnFull 时返回 ErrShardFull，淘汰失败时返回相应错误
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) ensureEntryLimitWithoutLock(hashedKey uint64) error {
+	if s.maxEntries <= 0 || s.hashmap[hashedKey] != 0 {
+		return nil
+	}
+	for len(s.hashmap) >= s.maxEntries {
+		if s.rejectOnFull {
+			return ErrShardFull
+		}
+		// 被删除的条目也会被弹出但不减少条目数，循环直到真正淘汰了一个条目
+		if err := s.removeOldestEntry(NoSpace); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 // setWrappedEntryWithoutLock 在不持有写锁的情况下设置包装条目
@@ -295,6 +848,11 @@ func (s *cacheShard) addNewWithoutLock(key string, hashedKey uint64, entry []byt
 //
 // 注意: 调用此函数前必须已经持有写锁
 func (s *cacheShard) setWrappedEntryWithoutLock(currentTimestamp uint64, w []byte, hashedKey uint64) error {
+	if err := s.ensureEntryLimitWithoutLock(hashedKey); err != nil { // 新键需要在条目数上限内
+		return err
+	}
+	s.invalidateColdWithoutLock(hashedKey) // 冷层中的旧值即将被覆盖
+
 	if previousIndex := s.hashmap[hashedKey]; previousIndex != 0 { // 如果已存在相同哈希键的条目
 		if previousEntry, err := s.entries.Get(int(previousIndex)); err == nil { // 获取旧条目
 			resetHashFromEntry(previousEntry) // 重置旧条目的哈希值
@@ -309,13 +867,14 @@ func (s *cacheShard) setWrappedEntryWithoutLock(currentTimestamp uint64, w []byt
 
 	for {
 		// 将新的地址索引放到对应的hash中
-		if index, err := s.entries.Push(w); err == nil { // 尝试将包装条目推入队列
+		index, err := s.push(w) // 尝试将包装条目推入队列
+		if err == nil {
 			s.hashmap[hashedKey] = uint64(index) // 更新hashmap中的索引
 			return nil                           // 返回成功
 		}
 		// 上面push失败，这里还pop不了，只能是容量不够
 		if s.removeOldestEntry(NoSpace) != nil { // 尝试删除最旧条目以腾出空间
-			return errors.New("entry is bigger than max shard size") // 返回条目过大错误
+			return fmt.Errorf("entry is bigger than max shard size: %w", err) // 返回条目过大错误，包装字节队列的错误
 		}
 	}
 }
@@ -331,9 +890,33 @@ func (s *cacheShard) setWrappedEntryWithoutLock(currentTimestamp uint64, w []byt
 //
 //	error: 错误信息，如果追加失败则返回相应错误
 func (s *cacheShard) append(key string, hashedKey uint64, entry []byte) error {
+	return s.appendBounded(key, hashedKey, entry, 0)
+}
+
+// appendBounded 与 append 相同，但追加后的值超过 maxTotal 字节时不做任何修改
+// 参数:
+//
+//	key: 要追加数据的键
+//	hashedKey: 键的哈希值
+//	entry: 要追加的数据
+//	maxTotal: 追加后值的最大字节数，<= 0 表示不限制
+//
+// 返回值:
+//
+//	error: 超过 maxTotal 时返回 ErrAppendLimitExceeded
+func (s *cacheShard) appendBounded(key string, hashedKey uint64, entry []byte, maxTotal int) error {
 	s.lock.Lock()                                            // 获取写锁以保证并发安全
 	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey) // 获取有效的包装条目
 
+	valueLength := len(entry) // 追加后值的长度
+	if err == nil {
+		valueLength += len(wrappedEntry) - headersSizeInBytes - len(key)
+	}
+	if maxTotal > 0 && valueLength > maxTotal && (err == nil || errors.Is(err, ErrEntryNotFound)) {
+		s.lock.Unlock()               // 释放写锁
+		return ErrAppendLimitExceeded // 在修改之前拒绝，旧值保持不变
+	}
+
 	if errors.Is(err, ErrEntryNotFound) { // 如果条目不存在
 		err = s.addNewWithoutLock(key, hashedKey, entry) // 添加新条目
 		s.lock.Unlock()                                  // 释放写锁
@@ -344,6 +927,11 @@ func (s *cacheShard) append(key string, hashedKey uint64, entry []byte) error {
 		return err      // 返回错误
 	}
 
+	if s.maxValueSize > 0 && valueLength > s.maxValueSize {
+		s.lock.Unlock()         // 释放写锁
+		return ErrValueTooLarge // 追加后的值超过硬性上限
+	}
+
 	currentTimestamp := uint64(s.clock.Epoch()) // 获取当前时间戳
 	// 将新内容追加到旧内容后面
 	w := appendToWrappedEntry(currentTimestamp, wrappedEntry, entry, &s.entryBuffer) // 将新数据追加到现有条目
@@ -354,6 +942,105 @@ func (s *cacheShard) append(key string, hashedKey uint64, entry []byte) error {
 	return err // 返回结果
 }
 
+// update 在写锁内读取键的当前值，调用 fn 计算新值并写回，整个过程对该键是原子的
+// 参数:
+//
+//	key: 要更新的键
+//	hashedKey: 键的哈希值
+//	fn: 根据旧值计算新值的函数，返回 nil, nil 表示删除该键
+//
+// 返回值:
+//
+//	error: fn 返回的错误或写入失败的错误，fn 返回错误时缓存保持不变
+//
+// 注意: 写回的条目保留旧条目通过 SetWithTTL 设置的生存时间
+func (s *cacheShard) update(key string, hashedKey uint64, fn func(old []byte, found bool) ([]byte, error)) error {
+	if len(key) > maxKeySize { // 键长度超过头部能表示的范围
+		return ErrKeyTooLong
+	}
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	if err := s.initEntriesWithoutLock(); err != nil { // 延迟创建字节队列
+		return err
+	}
+
+	var old []byte
+	var ttl uint32                                           // 已有条目通过 SetWithTTL 设置的生存时间，写回时保留
+	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey) // 获取键匹配的包装条目
+	found := err == nil
+	if found {
+		old = readEntry(wrappedEntry) // 复制旧值，fn 可以安全持有
+		ttl = readTTLFromEntry(wrappedEntry)
+	} else if !errors.Is(err, ErrEntryNotFound) { // 除条目不存在以外的错误直接返回
+		return err
+	}
+
+	entry, err := fn(old, found) // 计算新值
+	if err != nil {
+		return err
+	}
+
+	if entry == nil { // 约定返回 nil, nil 时删除条目
+		if !found {
+			return nil
+		}
+		delete(s.hashmap, hashedKey)          // 从hashmap中删除条目索引
+		s.notifyRemove(wrappedEntry, Deleted) // 调用删除回调函数
+		if s.statsEnabled {                   // 如果启用了统计
+			delete(s.hashmapStats, hashedKey) // 删除统计信息
+		}
+		resetHashFromEntry(wrappedEntry) // 重置条目中的哈希值
+		s.delhit()                       // 记录删除命中统计
+		return nil
+	}
+
+	if s.maxValueSize > 0 && len(entry) > s.maxValueSize { // 新值超过硬性上限时保持旧值不变
+		return ErrValueTooLarge
+	}
+
+	currentTimestamp := uint64(s.clock.Epoch())                                         // 获取当前时间戳
+	w := wrapEntryWithTTL(currentTimestamp, hashedKey, ttl, key, entry, &s.entryBuffer) // 包装条目数据
+	return s.setWrappedEntryWithoutLock(currentTimestamp, w, hashedKey)                 // 写入新条目并使旧条目失效
+}
+
+// getSet 在一次写锁内读取键的旧值并写入新值，期间其他写入不会插入到读取和写入之间
+// 参数:
+//
+//	key: 键
+//	hashedKey: 键的哈希值
+//	entry: 要存储的新值
+//
+// 返回值:
+//
+//	[]byte: 旧值的副本，键不存在时为 nil
+//	bool: 键是否存在
+//	error: 写入失败时返回错误，此时缓存保持不变
+func (s *cacheShard) getSet(key string, hashedKey uint64, entry []byte) (old []byte, existed bool, err error) {
+	if err := s.validate(key, entry); err != nil { // 在加锁之前拒绝无效的键值
+		return nil, false, err
+	}
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	if err := s.initEntriesWithoutLock(); err != nil { // 延迟创建字节队列
+		return nil, false, err
+	}
+
+	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey) // 获取键匹配的包装条目
+	if err == nil {
+		old, existed = readEntry(wrappedEntry), true // 复制旧值，写入新值后原条目所在的空间可能被覆盖
+	} else if !errors.Is(err, ErrEntryNotFound) { // 除条目不存在以外的错误直接返回
+		return nil, false, err
+	}
+
+	currentTimestamp := uint64(s.clock.Epoch())
+	if err := s.setWithoutLock(key, hashedKey, entry, currentTimestamp, currentTimestamp, 0); err != nil {
+		return nil, false, err
+	}
+	return old, existed, nil
+}
+
 // del 根据哈希键删除缓存条目
 // 参数:
 //
@@ -402,9 +1089,9 @@ func (s *cacheShard) del(hashedKey uint64) error {
 			return err      // 返回错误
 		}
 
-		delete(s.hashmap, hashedKey)      // 从hashmap中删除条目索引
-		s.onRemove(wrappedEntry, Deleted) // 调用删除回调函数
-		if s.statsEnabled {               // 如果启用了统计
+		delete(s.hashmap, hashedKey)          // 从hashmap中删除条目索引
+		s.notifyRemove(wrappedEntry, Deleted) // 调用删除回调函数
+		if s.statsEnabled {                   // 如果启用了统计
 			delete(s.hashmapStats, hashedKey) // 删除统计信息
 		}
 		resetHashFromEntry(wrappedEntry) // 重置条目中的哈希值
@@ -415,6 +1102,36 @@ func (s *cacheShard) del(hashedKey uint64) error {
 	return nil // 返回成功
 }
 
+// deletePrefix 删除所有键以 prefix 开头的条目，对每个条目以 Deleted 原因调用删除回调
+// 参数:
+//
+//	prefix: 键的前缀
+//
+// 返回值:
+//
+//	int: 删除的条目数量
+func (s *cacheShard) deletePrefix(prefix string) int {
+	s.lock.Lock()         // 获取写锁，整个分片的遍历期间持有
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	removed := 0
+	for hashedKey, index := range s.hashmap { // 遍历过程中删除当前键是安全的
+		wrappedEntry, err := s.entries.Get(int(index))
+		if err != nil || !strings.HasPrefix(readKeyFromEntry(wrappedEntry), prefix) {
+			continue
+		}
+		delete(s.hashmap, hashedKey)          // 从hashmap中删除条目索引
+		s.notifyRemove(wrappedEntry, Deleted) // 调用删除回调函数
+		if s.statsEnabled {                   // 如果启用了统计
+			delete(s.hashmapStats, hashedKey) // 删除统计信息
+		}
+		resetHashFromEntry(wrappedEntry) // 重置条目中的哈希值
+		s.delhit()                       // 记录删除命中统计
+		removed++
+	}
+	return removed
+}
+
 // onEvict 检查条目是否过期并执行淘汰操作
 // 参数:
 //
@@ -436,7 +1153,7 @@ func (s *cacheShard) onEvict(oldestEntry []byte, currentTimestamp uint64, evict
 	return false // 返回false表示未淘汰
 }
 
-// isExpired 检查条目是否已过期
+// isExpired 检查条目是否已过期，条目自带生存时间时以其为准，否则使用 lifeWindow；写入后超过 maxAge 的条目同样视为过期
 // 参数:
 //
 //	oldestEntry: 要检查的条目
@@ -446,11 +1163,85 @@ func (s *cacheShard) onEvict(oldestEntry []byte, currentTimestamp uint64, evict
 //
 //	bool: 如果条目已过期则返回true，否则返回false
 func (s *cacheShard) isExpired(oldestEntry []byte, currentTimestamp uint64) bool {
+	if s.pastMaxAge(oldestEntry, currentTimestamp) {
+		return true
+	}
+	lifeWindow, expires := s.entryLifeWindow(oldestEntry) // 获取条目的生存时间窗口
+	if !expires {                                         // 如果条目永不过期
+		return false // 返回未过期
+	}
 	oldestTimestamp := readTimestampFromEntry(oldestEntry) // 从条目中读取时间戳
 	if currentTimestamp <= oldestTimestamp {               // 如果当前时间小于等于条目时间（防止溢出）
 		return false // 返回未过期
 	}
-	return currentTimestamp-oldestTimestamp > s.lifeWindow // 检查是否超过生存时间窗口
+	return currentTimestamp-oldestTimestamp > lifeWindow // 检查是否超过生存时间窗口
+}
+
+// pastMaxAge 检查条目的写入时间是否已超过 maxAge，未设置 maxAge 时总是返回false
+// 写入时间不会被 Touch 和 SlidingExpiration 刷新，因此读取不能延长条目的最长保留时间
+// 参数:
+//
+//	entry: 包装的条目
+//	currentTimestamp: 当前时间戳
+//
+// 返回值:
+//
+//	bool: 条目是否已超过最长保留时间
+func (s *cacheShard) pastMaxAge(entry []byte, currentTimestamp uint64) bool {
+	if s.maxAge == 0 {
+		return false
+	}
+	writtenAt := readWrittenAtFromEntry(entry)
+	return currentTimestamp > writtenAt && currentTimestamp-writtenAt > s.maxAge
+}
+
+// maxAgeExpiryOf 返回条目因 maxAge 被视为过期之前的最后一个时间戳，未设置 maxAge 时返回最大值
+// 参数:
+//
+//	entry: 包装的条目
+//
+// 返回值:
+//
+//	uint64: 时间戳（秒），当前时间超过它时条目过期
+func (s *cacheShard) maxAgeExpiryOf(entry []byte) uint64 {
+	writtenAt := readWrittenAtFromEntry(entry)
+	if s.maxAge == 0 || writtenAt > math.MaxUint64-s.maxAge { // 防止溢出
+		return math.MaxUint64
+	}
+	return writtenAt + s.maxAge
+}
+
+// entryLifeWindow 返回条目的生存时间窗口（秒），条目自带生存时间时以其为准，否则使用 lifeWindow
+// 参数:
+//
+//	entry: 包装的条目
+//
+// 返回值:
+//
+//	uint64: 生存时间窗口
+//	bool: 条目是否会过期
+func (s *cacheShard) entryLifeWindow(entry []byte) (uint64, bool) {
+	if ttl := readTTLFromEntry(entry); ttl != 0 { // 条目自带生存时间
+		return uint64(ttl), true
+	}
+	return s.lifeWindow, !s.neverExpire
+}
+
+// remainingLife 返回条目距离过期的剩余时间
+// 参数:
+//
+//	entry: 包装的条目
+//	currentTimestamp: 当前时间戳
+//
+// 返回值:
+//
+//	time.Duration: 剩余生存时间，为负数时条目已过期，条目永不过期时为 NoExpiration
+func (s *cacheShard) remainingLife(entry []byte, currentTimestamp uint64) time.Duration {
+	deadline := s.expiryOf(entry) // 超过该时间戳后条目过期
+	if deadline == math.MaxUint64 {
+		return NoExpiration
+	}
+	return time.Duration(int64(deadline)-int64(currentTimestamp)) * time.Second
 }
 
 // cleanUp 清理过期条目
@@ -458,6 +1249,10 @@ func (s *cacheShard) isExpired(oldestEntry []byte, currentTimestamp uint64) bool
 //
 //	currentTimestamp: 当前时间戳
 func (s *cacheShard) cleanUp(currentTimestamp uint64) {
+	scanMaxAge := currentTimestamp > s.nextMaxAgeExpiry.Load()
+	if currentTimestamp <= s.nextExpiry.Load() && !scanMaxAge { // 队列头部的条目还不会过期，无需加锁
+		return
+	}
 	s.lock.Lock() // 获取写锁
 	for {
 		if oldestEntry, err := s.entries.Peek(); err != nil { // 查看最旧条目
@@ -466,7 +1261,106 @@ func (s *cacheShard) cleanUp(currentTimestamp uint64) {
 			break // 如果未淘汰则退出循环
 		}
 	}
-	s.lock.Unlock() // 释放写锁
+	if scanMaxAge { // 清理在第一个未过期的条目处停止，被 Touch 等推迟的条目后面可能还有超过 maxAge 的条目
+		s.removePastMaxAgeWithoutLock(currentTimestamp)
+	}
+	s.refreshNextExpiryWithoutLock() // 头部条目的时间戳可能被刷新过，按实际值记录
+	s.lock.Unlock()                  // 释放写锁
+}
+
+// removePastMaxAgeWithoutLock 遍历分片删除所有超过 maxAge 的条目，并按剩余条目重新计算 nextMaxAgeExpiry
+// 条目以 Expired 原因触发删除回调，不经过 canEvict：最长保留时间不能被否决。
+// 被删除的条目占用的空间在其到达队列头部时回收
+// 参数:
+//
+//	currentTimestamp: 当前时间戳
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) removePastMaxAgeWithoutLock(currentTimestamp uint64) {
+	next := uint64(math.MaxUint64)
+	for hashedKey, index := range s.hashmap { // 遍历过程中删除当前键是安全的
+		wrappedEntry, err := s.entries.Get(int(index))
+		if err != nil {
+			continue
+		}
+		if !s.pastMaxAge(wrappedEntry, currentTimestamp) {
+			next = min(next, s.maxAgeExpiryOf(wrappedEntry))
+			continue
+		}
+		delete(s.hashmap, hashedKey)          // 从hashmap中删除条目索引
+		s.notifyRemove(wrappedEntry, Expired) // 调用删除回调函数
+		if s.statsEnabled {                   // 如果启用了统计
+			delete(s.hashmapStats, hashedKey) // 删除统计信息
+		}
+		resetHashFromEntry(wrappedEntry) // 重置条目中的哈希值，到达队列头部时被跳过
+	}
+	s.nextMaxAgeExpiry.Store(next)
+}
+
+// push 将包装条目追加到队列尾部，并在它可能成为队列头部时降低 nextExpiry
+// 参数:
+//
+//	wrappedEntry: 包装条目
+//
+// 返回值:
+//
+//	int: 条目在队列中的索引
+//	error: 队列空间不足时返回错误
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) push(wrappedEntry []byte) (int, error) {
+	index, err := s.entries.Push(wrappedEntry)
+	if err != nil {
+		return index, err
+	}
+	// 新条目只有在队列为空时才会成为头部，取较小值即可保证 nextExpiry 不大于头部条目的过期时间
+	lowerTo(&s.nextExpiry, s.expiryOf(wrappedEntry))
+	if s.maxAge > 0 {
+		lowerTo(&s.nextMaxAgeExpiry, s.maxAgeExpiryOf(wrappedEntry))
+	}
+	return index, nil
+}
+
+// lowerTo 在 value 小于 v 的当前值时将其原子地降低为 value
+func lowerTo(v *atomic.Uint64, value uint64) {
+	for {
+		current := v.Load()
+		if value >= current || v.CompareAndSwap(current, value) {
+			return
+		}
+	}
+}
+
+// refreshNextExpiryWithoutLock 按当前的队列头部条目重新计算 nextExpiry，队列为空时表示无需清理
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) refreshNextExpiryWithoutLock() {
+	oldest, err := s.entries.Peek()
+	if err != nil {
+		s.nextExpiry.Store(math.MaxUint64)
+		return
+	}
+	s.nextExpiry.Store(s.expiryOf(oldest))
+}
+
+// expiryOf 返回条目开始被视为过期之前的最后一个时间戳，永不过期的条目返回最大值
+// 参数:
+//
+//	wrappedEntry: 包装条目
+//
+// 返回值:
+//
+//	uint64: 时间戳（秒），当前时间超过它时条目过期
+func (s *cacheShard) expiryOf(wrappedEntry []byte) uint64 {
+	maxAgeExpiry := s.maxAgeExpiryOf(wrappedEntry)
+	lifeWindow, expires := s.entryLifeWindow(wrappedEntry)
+	if !expires {
+		return maxAgeExpiry
+	}
+	timestamp := readTimestampFromEntry(wrappedEntry)
+	if timestamp > math.MaxUint64-lifeWindow { // 防止溢出
+		return maxAgeExpiry
+	}
+	return min(timestamp+lifeWindow, maxAgeExpiry)
 }
 
 // getEntry 根据哈希键获取条目数据的副本
@@ -508,7 +1402,49 @@ func (s *cacheShard) copyHashKeys() (keys []uint64, next int) {
 	return keys, next // 返回键切片和数量
 }
 
+// snapshot 在读锁内复制分片中所有条目的键和值
+// 返回值:
+//
+//	[]snapshotEntry: 条目的副本，释放锁后仍可安全使用
+func (s *cacheShard) snapshot() []snapshotEntry {
+	s.lock.RLock()         // 获取读锁
+	defer s.lock.RUnlock() // 函数结束时释放读锁
+
+	entries := make([]snapshotEntry, 0, len(s.hashmap))
+	for _, index := range s.hashmap { // 遍历所有条目索引
+		wrappedEntry, err := s.entries.Get(int(index)) // 获取包装的条目数据
+		if err != nil {
+			continue
+		}
+		entries = append(entries, snapshotEntry{
+			key:   readKeyFromEntry(wrappedEntry), // 复制键
+			value: readEntry(wrappedEntry),        // 复制值
+		})
+	}
+	return entries
+}
+
+// wrappedEntries 在读锁内复制分片中所有包装条目，保留时间戳、生存时间和键
+// 返回值:
+//
+//	[][]byte: 包装条目的副本，释放锁后仍可安全使用
+func (s *cacheShard) wrappedEntries() [][]byte {
+	s.lock.RLock()         // 获取读锁
+	defer s.lock.RUnlock() // 函数结束时释放读锁
+
+	entries := make([][]byte, 0, len(s.hashmap))
+	for _, index := range s.hashmap { // 遍历所有条目索引
+		if wrappedEntry, err := s.entries.GetCopy(int(index)); err == nil {
+			entries = append(entries, wrappedEntry)
+		}
+	}
+	return entries
+}
+
 // removeOldestEntry 删除最旧的条目
+// 设置了 canEvict 时，被否决的条目会重新追加到队列尾部：
+// 因空间不足淘汰时继续尝试下一个最旧的条目，队列中的每个条目最多询问一次且最多跳过 maxEvictionVetoes 个，之后返回 errEvictionVetoed；
+// 因过期淘汰时刷新其时间戳后直接返回，使清理在该条目处停止
 // 参数:
 //
 //	reason: 删除原因
@@ -517,22 +1453,80 @@ func (s *cacheShard) copyHashKeys() (keys []uint64, next int) {
 //
 //	error: 错误信息
 func (s *cacheShard) removeOldestEntry(reason RemoveReason) error {
-	oldest, err := s.entries.Pop() // 弹出最旧的条目
-	if err == nil {                // 如果成功弹出
+	vetoes := min(s.entries.Len(), maxEvictionVetoes) // 最多跳过的条目数量
+	for {
+		oldest, err := s.entries.Pop() // 弹出最旧的条目
+		if err != nil {
+			return err // 返回错误
+		}
+		s.refreshNextExpiryWithoutLock()  // 队列头部已变为下一个条目
 		hash := readHashFromEntry(oldest) // 读取条目中的哈希值
 		if hash == 0 {                    // 如果哈希值为0（已被删除）
 			// entry has been explicitly deleted with resetHashFromEntry, ignore
 			// 条目已被显式删除，忽略
 			return nil // 返回成功
 		}
-		delete(s.hashmap, hash)    // 从hashmap中删除条目
-		s.onRemove(oldest, reason) // 调用删除回调函数
-		if s.statsEnabled {        // 如果启用了统计
+		if s.canEvict != nil && !s.pastMaxAge(oldest, uint64(s.clock.Epoch())) && !s.canEvict(readKeyFromEntry(oldest), reason) { // 淘汰被否决，超过最长保留时间的条目不能被否决
+			if reason != NoSpace { // 过期淘汰时刷新时间戳，避免清理反复遇到该条目
+				writeTimestampToEntry(oldest, uint64(s.clock.Epoch()))
+			}
+			if s.repushWithoutLock(hash, oldest) {
+				if reason != NoSpace {
+					return nil
+				}
+				if vetoes--; vetoes <= 0 { // 跳过的条目已达上限，放弃腾出空间
+					return errEvictionVetoed
+				}
+				continue // 尝试下一个最旧的条目
+			}
+			// 重新追加失败时只能淘汰该条目
+		}
+		delete(s.hashmap, hash)        // 从hashmap中删除条目
+		s.notifyRemove(oldest, reason) // 调用删除回调函数
+		if s.statsEnabled {            // 如果启用了统计
 			delete(s.hashmapStats, hash) // 删除统计信息
 		}
 		return nil // 返回成功
 	}
-	return err // 返回错误
+}
+
+// repushWithoutLock 将刚弹出的条目重新追加到队列尾部并更新其索引
+// 参数:
+//
+//	hash: 条目的哈希值
+//	entry: 刚弹出的条目，仍指向队列内存
+//
+// 返回值:
+//
+//	bool: 是否追加成功
+//
+// 注意: 调用此函数前必须已经持有写锁
+func (s *cacheShard) repushWithoutLock(hash uint64, entry []byte) bool {
+	// 弹出的条目所在的空间可能被本次追加覆盖，必须先复制
+	index, err := s.push(append([]byte(nil), entry...))
+	if err != nil {
+		return false
+	}
+	s.hashmap[hash] = uint64(index)
+	return true
+}
+
+// reserve 以更大的容量提示重建hashmap，避免批量写入时反复扩容
+// 参数:
+//
+//	entries: 分片预计容纳的条目数量
+func (s *cacheShard) reserve(entries int) {
+	s.lock.Lock()         // 获取写锁以保证并发安全
+	defer s.lock.Unlock() // 函数结束时释放写锁
+
+	if entries <= len(s.hashmap) { // 已有条目不少于预计数量时无需重建
+		return
+	}
+	hashmap := make(map[uint64]uint64, entries) // 以预计数量作为容量提示创建新的hashmap
+	for hash, index := range s.hashmap {        // 复制已有的索引
+		hashmap[hash] = index
+	}
+	s.hashmap = hashmap
 }
 
 // reset 重置缓存分片
@@ -544,9 +1538,26 @@ func (s *cacheShard) reset(config Config) {
 	s.hashmap = make(map[uint64]uint64, config.initialShardSize())       // 重新创建hashmap
 	s.entryBuffer = make([]byte, config.MaxEntrySize+headersSizeInBytes) // 重新创建条目缓冲区
 	s.entries.Reset()                                                    // 重置字节队列
+	s.nextExpiry.Store(math.MaxUint64)                                   // 队列为空，之后的写入会降低它
+	s.nextMaxAgeExpiry.Store(math.MaxUint64)                             // 分片为空，之后的写入会降低它
 	s.lock.Unlock()                                                      // 释放写锁
 }
 
+// close 释放缓存分片的字节队列
+// 返回值:
+//
+//	error: 释放失败时返回错误
+func (s *cacheShard) close() error {
+	s.lock.Lock()                       // 获取写锁
+	defer s.lock.Unlock()               // 函数结束时释放写锁
+	s.hashmap = make(map[uint64]uint64) // 清空hashmap，避免指向已释放的条目
+	s.closed = true                     // 之后取得锁的读写操作返回 ErrCacheClosed
+	if s.newEntries != nil {            // 字节队列尚未创建，无需释放
+		return nil
+	}
+	return s.entries.Close() // 释放字节队列
+}
+
 // resetStats 重置缓存分片的统计信息
 func (s *cacheShard) resetStats() {
 	s.lock.Lock()     // 获取写锁以保证并发安全
@@ -572,6 +1583,38 @@ func (s *cacheShard) capacity() int {
 	return res                  // 返回容量
 }
 
+// peakCapacity 返回缓存分片的历史最大容量
+// 返回值: 字节队列曾经分配过的最大字节数
+func (s *cacheShard) peakCapacity() int {
+	s.lock.RLock()                  // 获取读锁以保证并发安全
+	res := s.entries.PeakCapacity() // 获取字节队列的历史最大容量
+	s.lock.RUnlock()                // 释放读锁
+	return res                      // 返回历史最大容量
+}
+
+// shardSnapshot 是分片在同一时刻的各项计数
+type shardSnapshot struct {
+	len          int   // 条目数量
+	capacity     int   // 字节队列的容量
+	used         int   // 条目占用的字节数
+	peakCapacity int   // 字节队列的历史最大容量
+	stats        Stats // 统计信息
+}
+
+// stateSnapshot 在一次加锁内读取分片的各项计数
+// 返回值: 分片在同一时刻的各项计数
+func (s *cacheShard) stateSnapshot() shardSnapshot {
+	s.lock.RLock()         // 获取读锁，保证各项计数来自同一时刻
+	defer s.lock.RUnlock() // 函数返回时释放读锁
+	return shardSnapshot{
+		len:          len(s.hashmap),           // 条目数量
+		capacity:     s.entries.Capacity(),     // 字节队列的容量
+		used:         s.entries.Used(),         // 条目占用的字节数
+		peakCapacity: s.entries.PeakCapacity(), // 字节队列的历史最大容量
+		stats:        s.getStats(),             // 统计信息
+	}
+}
+
 // getStats 获取缓存分片的统计信息
 // 返回值: 包含各项统计信息的Stats结构体
 func (s *cacheShard) getStats() Stats {
@@ -612,6 +1655,15 @@ func (s *cacheShard) getKeyMetadata(key uint64) Metadata {
 	}
 }
 
+// notifyRemove 以条目在本分片中的元数据调用移除回调，调用前必须持有写锁，且条目的统计信息尚未删除
+// 参数:
+//
+//	wrappedEntry: 被移除的包装条目
+//	reason: 移除原因
+func (s *cacheShard) notifyRemove(wrappedEntry []byte, reason RemoveReason) {
+	s.onRemove(wrappedEntry, reason, s.getKeyMetadata(readHashFromEntry(wrappedEntry)))
+}
+
 // hit 记录缓存命中事件
 // 参数:
 //
@@ -652,8 +1704,16 @@ func (s *cacheShard) delmiss() {
 }
 
 // collision 记录哈希冲突事件
-func (s *cacheShard) collision() {
+// 返回值: 是否需要记录这次冲突的日志，启用详细日志时每 collisionLogSampleRate 次冲突记录一次
+func (s *cacheShard) collision() bool {
 	atomic.AddInt64(&s.stats.Collisions, 1) // 原子增加哈希冲突次数
+	if !s.isVerbose {
+		return false
+	}
+	if s.collisionLogSampleRate <= 1 { // 未设置采样时每次冲突都记录
+		return true
+	}
+	return s.collisionSamples.Add(1)%s.collisionLogSampleRate == 1 // 记录第1、N+1、2N+1...次冲突
 }
 
 // initNewShard 初始化并创建一个新的缓存分片
@@ -661,27 +1721,67 @@ func (s *cacheShard) collision() {
 //
 //	config: 缓存配置信息
 //	callback: 条目被移除时的回调函数
+//	cold: 磁盘冷层，未配置时为nil
 //	clock: 时钟接口，用于获取时间
 //
-// 返回值: 指向新创建的 cacheShard 结构体的指针
-func initNewShard(config Config, callback onRemoveCallback, clock clock.Clock) *cacheShard {
+// 返回值:
+//
+//	*cacheShard: 指向新创建的 cacheShard 结构体的指针
+//	error: 字节队列分配失败时返回错误
+func initNewShard(config Config, callback onRemoveCallback, cold *coldTier, clock clock.Clock) (*cacheShard, error) {
 	bytesQueueInitialCapacity := config.initialShardSize() * config.MaxEntrySize            // 计算字节队列的初始容量
 	maximumShardSizeInBytes := config.maximumShardSizeInBytes()                             // 获取分片的最大大小（字节）
 	if maximumShardSizeInBytes > 0 && bytesQueueInitialCapacity > maximumShardSizeInBytes { // 如果设置了最大分片大小且初始容量超过最大大小
 		bytesQueueInitialCapacity = maximumShardSizeInBytes // 将初始容量调整为最大分片大小
 	}
-	return &cacheShard{
-		hashmap:      make(map[uint64]uint64, config.initialShardSize()),                                            // 创建哈希映射，初始大小为配置的分片大小
-		hashmapStats: make(map[uint64]uint32, config.initialShardSize()),                                            // 创建哈希统计映射，初始大小为配置的分片大小
-		entries:      *bytesqyeye.NewBytesQueue(bytesQueueInitialCapacity, maximumShardSizeInBytes, config.Verbose), // 创建字节队列
-		entryBuffer:  make([]byte, config.MaxEntrySize+headersSizeInBytes),                                          // 创建条目缓冲区，大小为最大条目大小加上头部大小
-		onRemove:     callback,                                                                                      // 设置条目移除回调函数
+	var allocator bytesqyeye.Allocator = bytesqyeye.HeapAllocator{} // 默认在堆上分配字节队列
+	if config.OffHeap {                                             // 如果启用了堆外内存
+		allocator = bytesqyeye.MmapAllocator{} // 使用 mmap 在堆外分配字节队列
+	}
+	newEntries := func() (*bytesqyeye.BytesQueue, error) {
+		entries, err := bytesqyeye.NewBytesQueueWithAllocator(bytesQueueInitialCapacity, maximumShardSizeInBytes, config.Verbose, allocator) // 创建字节队列
+		if err != nil {
+			return nil, err
+		}
+		entries.SetOnAllocate(config.OnAllocate) // 设置扩容回调
+		return entries, nil
+	}
+
+	shard := &cacheShard{
+		hashmap:      make(map[uint64]uint64, config.initialShardSize()), // 创建哈希映射，初始大小为配置的分片大小
+		hashmapStats: make(map[uint64]uint32, config.initialShardSize()), // 创建哈希统计映射，初始大小为配置的分片大小
+		onRemove:     callback,                                           // 设置条目移除回调函数
+		cold:         cold,                                               // 设置磁盘冷层
+
+		isVerbose:              config.Verbose,                                    // 设置详细日志标志
+		collisionLogSampleRate: uint64(max(config.CollisionLogSampleRate, 1)),     // 设置哈希冲突日志的采样率
+		logger:                 config.Logger,                                     // 设置日志记录器
+		clock:                  clock,                                             // 设置时钟
+		lifeWindow:             uint64(config.LifeWindow.Seconds()),               // 设置条目生存时间窗口（转换为秒）
+		maxAge:                 uint64(config.MaxAge.Seconds()),                   // 设置条目的最长保留时间（转换为秒）
+		neverExpire:            config.AllowNeverExpire && config.LifeWindow == 0, // 设置条目永不过期标志
+		slidingExpiration:      config.SlidingExpiration,                          // 设置滑动过期标志
+		oversizePolicy:         config.OversizeEntryPolicy,                        // 设置超大条目的处理策略
+		maxValueSize:           config.shardMaxValueSize(),                        // 设置值的最大字节数
+		maxEntries:             config.MaxEntriesPerShard,                         // 设置分片的最大条目数
+		rejectOnFull:           config.RejectOnFull,                               // 设置达到最大条目数时是否拒绝写入
+		canEvict:               config.CanEvict,                                   // 设置淘汰否决回调
+		statsEnabled:           config.StatsEnabled,                               // 设置统计功能启用标志
+		cleanEnabled:           config.cleanupInterval() > 0,                      // 设置自动清理功能启用标志（设置了清理窗口或最长保留时间时启用）
+	}
+	shard.nextExpiry.Store(math.MaxUint64)       // 队列为空，第一次写入时降低
+	shard.nextMaxAgeExpiry.Store(math.MaxUint64) // 分片为空，第一次写入时降低
+
+	if config.LazyShards { // 延迟到第一次写入时再分配字节队列，条目缓冲区在包装条目时按需分配
+		shard.newEntries = newEntries
+		return shard, nil
+	}
 
-		isVerbose:    config.Verbose,                      // 设置详细日志标志
-		logger:       config.Logger,                       // 设置日志记录器
-		clock:        clock,                               // 设置时钟
-		lifeWindow:   uint64(config.LifeWindow.Seconds()), // 设置条目生存时间窗口（转换为秒）
-		statsEnabled: config.StatsEnabled,                 // 设置统计功能启用标志
-		cleanEnabled: config.CleanWindow > 0,              // 设置自动清理功能启用标志（如果清理窗口大于0则启用）
+	entries, err := newEntries()
+	if err != nil {
+		return nil, err
 	}
+	shard.entries = *entries                                                 // 设置字节队列
+	shard.entryBuffer = make([]byte, config.MaxEntrySize+headersSizeInBytes) // 创建条目缓冲区，大小为最大条目大小加上头部大小
+	return shard, nil
 }
@@ -0,0 +1,59 @@
+package bigcache
+
+import (
+	"errors"
+	"sync"
+)
+
+// errComputePanicked 是 compute 发生 panic 时等待同一次计算的调用方得到的错误
+var errComputePanicked = errors.New("bigcache: compute panicked")
+
+// flightCall 是一次正在进行或已完成的计算
+type flightCall struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+// flightGroup 合并同一个键上并发的计算，同一时刻每个键只有一次计算在进行
+// 零值可以直接使用
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do 执行 fn 并返回其结果，同一个键已有计算在进行时等待它结束并共享其结果
+// 返回的切片由所有调用方共享，不能修改
+// 参数:
+//
+//	key: 键
+//	fn: 计算函数
+//
+// 返回值:
+//
+//	[]byte: 计算结果
+//	error: 计算返回的错误
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.val, call.err
+	}
+	call := &flightCall{err: errComputePanicked} // fn 正常返回时被覆盖
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.val, call.err = fn()
+	return call.val, call.err
+}
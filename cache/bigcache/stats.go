@@ -13,3 +13,29 @@ type Stats struct {
 	// Collisions is a number of happened key-collisions
 	Collisions int64 `json:"collisions"`
 }
+
+// CollisionRate returns the fraction of lookups that ran into a hash collision, i.e. found an entry of another key.
+// Colliding lookups are counted neither as hits nor as misses. It returns 0 before the first lookup.
+func (s Stats) CollisionRate() float64 {
+	lookups := s.Hits + s.Misses + s.Collisions
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.Collisions) / float64(lookups)
+}
+
+// Snapshot bundles the statistics and sizes of the cache, see BigCache.Snapshot
+type Snapshot struct {
+	// Stats are the summed statistics of all shards
+	Stats Stats `json:"stats"`
+	// Len is the number of entries in the cache
+	Len int `json:"len"`
+	// Capacity is the number of bytes allocated for entries
+	Capacity int `json:"capacity"`
+	// Used is the number of allocated bytes occupied by entries, deleted entries count until they are evicted
+	Used int `json:"used"`
+	// PeakCapacity is the highest number of bytes ever allocated for entries
+	PeakCapacity int `json:"peak_capacity"`
+	// ShardLens is the number of entries in every shard, indexed like the shards
+	ShardLens []int `json:"shard_lens"`
+}
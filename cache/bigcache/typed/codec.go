@@ -0,0 +1,46 @@
+package typed
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec 负责值与字节切片之间的转换
+type Codec interface {
+	// Marshal 将 v 编码为字节切片
+	Marshal(v any) ([]byte, error)
+	// Unmarshal 将 data 解码到 v 指向的值中
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec 使用 encoding/json 编解码，输出可读且跨语言通用
+type JSONCodec struct{}
+
+// Marshal 将 v 编码为 JSON
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal 将 JSON 数据解码到 v 中
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec 使用 encoding/gob 编解码，适合只在 Go 程序之间共享的值
+// 每个值单独编码，都会带上类型描述，小值的编码结果比 JSON 大
+type GobCodec struct{}
+
+// Marshal 将 v 编码为 gob
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal 将 gob 数据解码到 v 中
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
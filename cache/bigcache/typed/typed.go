@@ -0,0 +1,70 @@
+// Package typed 在 BigCache 之上提供类型安全的泛型包装，由 Codec 负责值与字节切片之间的转换
+// 放在独立的子包中，bigcache 核心包的字节切片读写路径保持不变
+package typed
+
+import (
+	"fmt"
+
+	"github.com/andrewbytecoder/gokit/cache/bigcache"
+)
+
+// TypedCache 以 T 类型读写 BigCache 中的条目
+type TypedCache[T any] struct {
+	cache *bigcache.BigCache
+	codec Codec
+}
+
+// New 创建读写 c 的 TypedCache，codec 为 nil 时使用 JSONCodec
+// 参数:
+//
+//	c: 底层缓存
+//	codec: 值的编解码器
+//
+// 返回值:
+//
+//	*TypedCache[T]: 类型安全的缓存包装
+func New[T any](c *bigcache.BigCache, codec Codec) *TypedCache[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &TypedCache[T]{cache: c, codec: codec}
+}
+
+// Get 读取键对应的值并解码为 T
+// 参数:
+//
+//	key: 要查找的键
+//
+// 返回值:
+//
+//	T: 解码后的值，出错时为 T 的零值
+//	error: 条目不存在时返回 bigcache.ErrEntryNotFound，解码失败时返回包装后的 codec 错误
+func (c *TypedCache[T]) Get(key string) (T, error) {
+	var value T
+	data, err := c.cache.Get(key)
+	if err != nil {
+		return value, err
+	}
+	if err := c.codec.Unmarshal(data, &value); err != nil {
+		var zero T
+		return zero, fmt.Errorf("typed: decoding %q: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set 将 value 编码后保存在键下
+// 参数:
+//
+//	key: 键
+//	value: 要保存的值
+//
+// 返回值:
+//
+//	error: 编码失败时返回包装后的 codec 错误，其余为 BigCache.Set 的错误
+func (c *TypedCache[T]) Set(key string, value T) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("typed: encoding %q: %w", key, err)
+	}
+	return c.cache.Set(key, data)
+}
@@ -0,0 +1,72 @@
+package typed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewbytecoder/gokit/cache/bigcache"
+)
+
+type session struct {
+	User  string
+	Roles []string
+}
+
+func newCache(t *testing.T) *bigcache.BigCache {
+	cache, err := bigcache.New(context.Background(), bigcache.Config{
+		Shards:             2,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 16,
+		MaxEntrySize:       64,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestTypedCache(t *testing.T) {
+	for name, codec := range map[string]Codec{"json": JSONCodec{}, "gob": GobCodec{}, "default": nil} {
+		t.Run(name, func(t *testing.T) {
+			sessions := New[session](newCache(t), codec)
+			want := session{User: "alice", Roles: []string{"admin", "dev"}}
+
+			require.NoError(t, sessions.Set("token", want))
+			got, err := sessions.Get("token")
+
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestTypedCacheNotFound(t *testing.T) {
+	counts := New[int](newCache(t), GobCodec{})
+
+	got, err := counts.Get("missing")
+
+	assert.ErrorIs(t, err, bigcache.ErrEntryNotFound)
+	assert.Equal(t, 0, got)
+}
+
+func TestTypedCacheDecodeError(t *testing.T) {
+	cache := newCache(t)
+	require.NoError(t, cache.Set("key", []byte("not json")))
+	sessions := New[session](cache, JSONCodec{})
+
+	got, err := sessions.Get("key")
+
+	assert.ErrorContains(t, err, `typed: decoding "key"`)
+	assert.Equal(t, session{}, got)
+}
+
+func TestTypedCacheEncodeError(t *testing.T) {
+	channels := New[chan int](newCache(t), JSONCodec{})
+
+	err := channels.Set("key", make(chan int))
+
+	assert.ErrorContains(t, err, `typed: encoding "key"`)
+}
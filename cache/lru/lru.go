@@ -0,0 +1,312 @@
+package lru
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/andrewbytecoder/gokit/container/bytesqyeye"
+	hash2 "github.com/andrewbytecoder/gokit/encoding/hash"
+)
+
+const (
+	// 初始字节区大小，之后按需翻倍直到 MaxBytes
+	initialArenaSize = 1024
+	// 存活条目最多占用字节区的 3/4，剩余空间容纳垃圾，使压缩的开销被多次写入均摊
+	liveRatioNum, liveRatioDen = 3, 4
+)
+
+var (
+	// ErrEntryNotFound is returned when the key is not present in the cache
+	ErrEntryNotFound = errors.New("entry not found")
+	// ErrEntryTooLarge is returned when a single entry can not fit into MaxBytes
+	ErrEntryTooLarge = errors.New("entry is bigger than the cache size")
+)
+
+// Config LRU 缓存配置
+type Config struct {
+	// MaxEntries is the maximum number of entries kept in the cache, 0 means no limit.
+	MaxEntries int
+	// MaxBytes is the size of the byte arena holding keys and values. Required.
+	// Live entries use at most three quarters of it, the rest absorbs overwritten and
+	// deleted entries until the arena is compacted.
+	MaxBytes int
+	// Hasher used to calculate hash values for cache keys, defaults to FNV-1a 64.
+	Hasher hash2.Hasher
+}
+
+// element 最近使用链表中的节点，值本身存放在字节区中
+type element struct {
+	prev, next *element // 双向链表指针
+	hash       uint64   // 键的哈希值
+	index      int      // 条目在字节区中的索引
+	size       int      // 条目在字节区中占用的字节数
+}
+
+// LRU 基于 BytesQueue 字节区的真正 LRU 缓存
+//
+// 键值对被序列化存放在字节区中，map 和双向链表只保存哈希与索引，
+// 因此 GC 需要扫描的指针数量与条目数成正比，而与值的大小无关。
+// 字节区只追加不回收，空间不足时先把存活条目压缩到新的字节区，
+// 仍然放不下时才淘汰最久未使用的条目。
+type LRU struct {
+	mu         sync.Mutex
+	hash       hash2.Hasher
+	arena      *bytesqyeye.BytesQueue
+	items      map[uint64]*element
+	root       element // 哨兵节点，root.next 为最近使用，root.prev 为最久未使用
+	maxEntries int
+	maxBytes   int
+	maxLive    int    // 存活条目允许占用的字节数，超出时淘汰最久未使用的条目
+	live       int    // 存活条目占用的字节数
+	stale      int    // 上次压缩后被删除或覆盖的条目数，大于 0 时压缩才能腾出空间
+	buf        []byte // 序列化条目的复用缓冲区
+}
+
+// New 创建一个 LRU 缓存
+//
+// 参数:
+//
+//	config: 缓存配置，MaxBytes 必须大于 0
+//
+// 返回值:
+//
+//	*LRU: 缓存实例
+//	error: 配置无效时返回错误
+func New(config Config) (*LRU, error) {
+	if config.MaxBytes <= 0 {
+		return nil, fmt.Errorf("lru: MaxBytes must be positive, got %d", config.MaxBytes)
+	}
+	if config.MaxEntries < 0 {
+		return nil, fmt.Errorf("lru: MaxEntries must not be negative, got %d", config.MaxEntries)
+	}
+	if config.Hasher == nil {
+		config.Hasher = hash2.NewFnv64()
+	}
+
+	c := &LRU{
+		hash:       config.Hasher,
+		arena:      newArena(config.MaxBytes),
+		items:      make(map[uint64]*element),
+		maxEntries: config.MaxEntries,
+		maxBytes:   config.MaxBytes,
+		maxLive:    config.MaxBytes / liveRatioDen * liveRatioNum,
+	}
+	c.root.next = &c.root
+	c.root.prev = &c.root
+	return c, nil
+}
+
+func newArena(maxBytes int) *bytesqyeye.BytesQueue {
+	return bytesqyeye.NewBytesQueue(min(initialArenaSize, maxBytes), maxBytes, false)
+}
+
+// Get 读取 key 对应的值并将其标记为最近使用
+//
+// 参数:
+//
+//	key: 缓存键
+//
+// 返回值:
+//
+//	[]byte: 值的副本
+//	error: 不存在时返回 ErrEntryNotFound
+func (c *LRU) Get(key string) ([]byte, error) {
+	hashedKey := c.hash.Sum64(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[hashedKey]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	entry, err := c.arena.Get(e.index)
+	if err != nil {
+		return nil, err
+	}
+	storedKey, value := readEntry(entry)
+	if string(storedKey) != key { // 哈希冲突，视为未命中
+		return nil, ErrEntryNotFound
+	}
+	c.moveToFront(e)
+	return append([]byte(nil), value...), nil
+}
+
+// Set 写入 key 对应的值，空间不足时淘汰最久未使用的条目
+//
+// 参数:
+//
+//	key: 缓存键
+//	value: 缓存值
+//
+// 返回值:
+//
+//	error: 条目本身超过 MaxBytes 的 3/4 时返回 ErrEntryTooLarge
+func (c *LRU) Set(key string, value []byte) error {
+	hashedKey := c.hash.Sum64(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.buf = appendEntry(c.buf[:0], key, value)
+	size := entrySize(len(c.buf))
+	if size > c.maxLive {
+		return ErrEntryTooLarge
+	}
+
+	// 覆盖旧值：旧条目在字节区中变为垃圾，等待压缩回收
+	if e, ok := c.items[hashedKey]; ok {
+		c.removeElement(e)
+	}
+	for c.maxEntries > 0 && len(c.items) >= c.maxEntries || c.live+size > c.maxLive {
+		c.removeElement(c.root.prev)
+	}
+
+	index, err := c.push(c.buf)
+	if err != nil {
+		return err
+	}
+	e := &element{hash: hashedKey, index: index, size: size}
+	c.items[hashedKey] = e
+	c.insertFront(e)
+	c.live += size
+	return nil
+}
+
+// Delete 删除 key 对应的条目
+//
+// 参数:
+//
+//	key: 缓存键
+//
+// 返回值:
+//
+//	error: 不存在时返回 ErrEntryNotFound
+func (c *LRU) Delete(key string) error {
+	hashedKey := c.hash.Sum64(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[hashedKey]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	entry, err := c.arena.Get(e.index)
+	if err != nil {
+		return err
+	}
+	if storedKey, _ := readEntry(entry); string(storedKey) != key {
+		return ErrEntryNotFound
+	}
+	c.removeElement(e)
+	return nil
+}
+
+// Len 返回缓存中的条目数
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// push 将条目追加到字节区，空间不足时先压缩，再从最久未使用处淘汰
+func (c *LRU) push(entry []byte) (int, error) {
+	for {
+		index, err := c.arena.Push(entry)
+		if err == nil {
+			return index, nil
+		}
+		if !errors.Is(err, bytesqyeye.ErrFull) {
+			return -1, err
+		}
+		// 字节区中有垃圾时压缩即可腾出空间，否则淘汰最久未使用的条目
+		if c.stale > 0 {
+			c.compact()
+			continue
+		}
+		if len(c.items) == 0 {
+			return -1, ErrEntryTooLarge
+		}
+		c.removeElement(c.root.prev)
+	}
+}
+
+// compact 将存活条目按最近使用到最久未使用的顺序复制到新的字节区
+//
+// 扩容留下的填充可能让新字节区略小，一旦放不下，
+// 当前条目及其后所有更久未使用的条目都被丢弃，保证淘汰的总是链表尾部。
+func (c *LRU) compact() {
+	arena := newArena(c.maxBytes)
+	full := false
+	for e := c.root.next; e != &c.root; {
+		next := e.next
+		if !full {
+			entry, err := c.arena.Get(e.index)
+			if err == nil {
+				e.index, err = arena.Push(entry)
+			}
+			full = err != nil
+		}
+		if full {
+			c.unlink(e)
+		}
+		e = next
+	}
+	_ = c.arena.Close()
+	c.arena = arena
+	c.stale = 0
+}
+
+// removeElement 从链表和索引中移除节点，字节区中的数据留待压缩回收
+func (c *LRU) removeElement(e *element) {
+	c.unlink(e)
+	c.stale++
+}
+
+// unlink 将节点从链表和索引中摘除
+func (c *LRU) unlink(e *element) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+	delete(c.items, e.hash)
+	c.live -= e.size
+}
+
+func (c *LRU) insertFront(e *element) {
+	e.prev = &c.root
+	e.next = c.root.next
+	c.root.next.prev = e
+	c.root.next = e
+}
+
+func (c *LRU) moveToFront(e *element) {
+	if c.root.next == e {
+		return
+	}
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	c.insertFront(e)
+}
+
+// appendEntry 序列化条目：[uvarint 键长度][键][值]
+func appendEntry(dst []byte, key string, value []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(key)))
+	dst = append(dst, key...)
+	return append(dst, value...)
+}
+
+// entrySize 返回长度为 n 的条目连同 uvarint 长度头占用的字节数
+func entrySize(n int) int {
+	var header [binary.MaxVarintLen64]byte
+	return n + binary.PutUvarint(header[:], uint64(n))
+}
+
+// readEntry 解析 appendEntry 写入的条目，返回的值与字节区共享内存
+func readEntry(entry []byte) ([]byte, []byte) {
+	keyLen, n := binary.Uvarint(entry)
+	end := n + int(keyLen)
+	return entry[n:end], entry[end:]
+}
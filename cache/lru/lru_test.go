@@ -0,0 +1,169 @@
+package lru
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewbytecoder/gokit/cache/bigcache"
+)
+
+func TestLRUEvictionOrder(t *testing.T) {
+	// given
+	cache, err := New(Config{MaxEntries: 3, MaxBytes: 1024})
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Set(key, []byte(key)))
+	}
+
+	// when: 访问 a 使其成为最近使用，b 变为最久未使用
+	_, err = cache.Get("a")
+	require.NoError(t, err)
+	require.NoError(t, cache.Set("d", []byte("d")))
+
+	// then
+	_, err = cache.Get("b")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	for _, key := range []string{"a", "c", "d"} {
+		value, err := cache.Get(key)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(key), value)
+	}
+	assert.Equal(t, 3, cache.Len())
+
+	// when: 此时 a 最久未使用
+	require.NoError(t, cache.Set("e", []byte("e")))
+
+	// then
+	_, err = cache.Get("a")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestLRUOverwriteAndDelete(t *testing.T) {
+	cache, err := New(Config{MaxEntries: 2, MaxBytes: 1024})
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Set("a", []byte("1")))
+	require.NoError(t, cache.Set("b", []byte("2")))
+	// 覆盖会刷新 a 的使用时间，b 成为最久未使用
+	require.NoError(t, cache.Set("a", []byte("3")))
+	require.NoError(t, cache.Set("c", []byte("4")))
+
+	value, err := cache.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("3"), value)
+	_, err = cache.Get("b")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+
+	require.NoError(t, cache.Delete("a"))
+	assert.ErrorIs(t, cache.Delete("a"), ErrEntryNotFound)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestLRUByteLimit(t *testing.T) {
+	// given: 字节区只能容纳约 4 个 100 字节的条目
+	cache, err := New(Config{MaxBytes: 512})
+	require.NoError(t, err)
+	value := make([]byte, 100)
+
+	// when: 反复访问 key-0，使其一直是最近使用
+	for i := 0; i < 20; i++ {
+		require.NoError(t, cache.Set(strconv.Itoa(i), value))
+		_, err := cache.Get("0")
+		require.NoError(t, err, "key 0 must survive as the most recently used entry")
+	}
+
+	// then: 最近写入的条目仍在，早期条目已被淘汰
+	assert.LessOrEqual(t, cache.Len(), 4)
+	_, err = cache.Get("19")
+	assert.NoError(t, err)
+	_, err = cache.Get("1")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestLRUCompactReclaimsOverwrites(t *testing.T) {
+	// given
+	cache, err := New(Config{MaxBytes: 512})
+	require.NoError(t, err)
+	require.NoError(t, cache.Set("a", make([]byte, 100)))
+	require.NoError(t, cache.Set("b", make([]byte, 100)))
+
+	// when: 覆盖产生的垃圾只能靠压缩回收，不应淘汰存活条目
+	for i := 0; i < 50; i++ {
+		require.NoError(t, cache.Set("b", make([]byte, 100)))
+	}
+
+	// then
+	_, err = cache.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestLRUEntryTooLarge(t *testing.T) {
+	cache, err := New(Config{MaxBytes: 64})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, cache.Set("a", make([]byte, 64)), ErrEntryTooLarge)
+	assert.Equal(t, 0, cache.Len())
+
+	_, err = New(Config{})
+	assert.Error(t, err)
+}
+
+// skewedKeys 生成服从 Zipf 分布的访问序列，少量热点键占据大部分访问
+func skewedKeys(n int) []string {
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 100_000)
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", zipf.Uint64())
+	}
+	return keys
+}
+
+func BenchmarkSkewedLRU(b *testing.B) {
+	cache, err := New(Config{MaxBytes: 1 << 20})
+	require.NoError(b, err)
+	keys := skewedKeys(1 << 16)
+	value := make([]byte, 128)
+
+	var hits int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		if _, err := cache.Get(key); err == nil {
+			hits++
+			continue
+		}
+		_ = cache.Set(key, value)
+	}
+	b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+}
+
+func BenchmarkSkewedBigCacheFIFO(b *testing.B) {
+	config := bigcache.DefaultConfig(time.Hour)
+	config.Shards = 1
+	config.HardMaxCacheSize = 1
+	cache, err := bigcache.New(context.Background(), config)
+	require.NoError(b, err)
+	defer cache.Close()
+	keys := skewedKeys(1 << 16)
+	value := make([]byte, 128)
+
+	var hits int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		if _, err := cache.Get(key); err == nil {
+			hits++
+			continue
+		}
+		_ = cache.Set(key, value)
+	}
+	b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+}
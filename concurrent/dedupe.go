@@ -0,0 +1,82 @@
+package concurrent
+
+import "context"
+
+// Dedupe 丢弃与前一个值相等的值，只去除连续的重复
+// 输入通道关闭或上下文取消时关闭输出通道
+func Dedupe[T comparable](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var prev T
+		first := true
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !first && v == prev {
+					continue
+				}
+				first, prev = false, v
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// DedupeAll 丢弃所有之前出现过的值，实现全局去重
+// maxSeen 可选，限制记录的已出现值的数量，超出时按出现顺序淘汰最早的值，
+// 被淘汰的值再次出现时会被重新发送；不传或传入 <= 0 时不限制，内存随不同值的数量增长
+// 输入通道关闭或上下文取消时关闭输出通道
+func DedupeAll[T comparable](ctx context.Context, in <-chan T, maxSeen ...int) <-chan T {
+	limit := 0
+	if len(maxSeen) > 0 {
+		limit = maxSeen[0]
+	}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		seen := make(map[T]struct{})
+		var order []T // limit > 0 时按出现顺序记录的环形缓冲区
+		next := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if _, dup := seen[v]; dup {
+					continue
+				}
+				if limit > 0 {
+					if len(order) < limit {
+						order = append(order, v)
+					} else {
+						// 淘汰最早出现的值
+						delete(seen, order[next])
+						order[next] = v
+						next = (next + 1) % limit
+					}
+				}
+				seen[v] = struct{}{}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,63 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceStream 将切片按顺序发送到通道中，发送完毕后关闭通道
+func sliceStream[T any](values ...T) <-chan T {
+	c := make(chan T, len(values))
+	for _, v := range values {
+		c <- v
+	}
+	close(c)
+	return c
+}
+
+func drain[T any](c <-chan T) (ret []T) {
+	for v := range c {
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+func TestDedupe(t *testing.T) {
+	ctx := context.Background()
+	in := sliceStream(1, 1, 2, 2, 2, 1, 3, 3, 1)
+	// 只去除连续的重复值
+	assert.Equal(t, []int{1, 2, 1, 3, 1}, drain(Dedupe(ctx, in)))
+
+	// 零值作为第一个值时同样会发送
+	assert.Equal(t, []int{0, 1}, drain(Dedupe(ctx, sliceStream(0, 0, 1))))
+}
+
+func TestDedupeAll(t *testing.T) {
+	ctx := context.Background()
+	in := sliceStream("a", "b", "a", "c", "b", "d", "a")
+	// 去除所有出现过的值
+	assert.Equal(t, []string{"a", "b", "c", "d"}, drain(DedupeAll(ctx, in)))
+}
+
+func TestDedupeAllBounded(t *testing.T) {
+	ctx := context.Background()
+	// 只记录最近出现的 2 个值：3 出现后 1 被淘汰，因此 1 会再次发送，
+	// 随后 2 被淘汰，3 仍然被记录
+	in := sliceStream(1, 2, 1, 3, 1, 3, 2)
+	assert.Equal(t, []int{1, 2, 3, 1, 2}, drain(DedupeAll(ctx, in, 2)))
+}
+
+func TestDedupeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := Dedupe(ctx, in)
+	outAll := DedupeAll(ctx, in)
+	cancel()
+	// 取消后输出通道被关闭
+	for range out {
+	}
+	for range outAll {
+	}
+}
@@ -0,0 +1,103 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// Distribute 有序并行映射
+// 将输入流中的数据分发给 workers 个 goroutine 并发执行 fn，
+// 输出结果的顺序与输入顺序保持一致，先完成的乱序结果会被缓存直到轮到它输出
+// 同时处于处理中或等待输出的数据最多 2*workers 个，某个数据处理很慢时后续数据暂停分发，
+// 缓存的乱序结果不会无限增长
+// 当上下文被取消或输入流关闭且所有结果输出完毕时，输出通道会被关闭
+func Distribute[T, U any](ctx context.Context, in <-chan T, workers int, fn func(T) U) <-chan U {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type job struct {
+		index int
+		value T
+	}
+	type result struct {
+		index int
+		value U
+	}
+
+	jobs := make(chan job)
+	results := make(chan result)
+	out := make(chan U)
+	window := make(chan struct{}, 2*workers) // 每个已分发但尚未输出的数据占用一个位置
+
+	// 为输入数据编号并分发给 worker
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case <-ctx.Done():
+				return
+			case window <- struct{}{}:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case jobs <- job{index: index, value: v}:
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := result{index: j.index, value: fn(j.value)}
+				select {
+				case <-ctx.Done():
+					return
+				case results <- r:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// 按输入顺序输出结果
+	go func() {
+		defer close(out)
+		pending := make(map[int]U)
+		next := 0
+		for r := range results {
+			pending[r.index] = r.value
+			for {
+				v, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+				<-window
+				next++
+			}
+		}
+	}()
+
+	return out
+}
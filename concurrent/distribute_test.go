@@ -0,0 +1,103 @@
+package concurrent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistribute(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; i < 20; i++ {
+			in <- i
+		}
+	}()
+
+	// 越早的数据耗时越长，确保结果乱序完成
+	out := Distribute(context.Background(), in, 4, func(v int) int {
+		time.Sleep(time.Duration(20-v) * time.Millisecond)
+		return v * 2
+	})
+
+	var ret []int
+	for v := range out {
+		ret = append(ret, v)
+	}
+
+	expected := make([]int, 20)
+	for i := range expected {
+		expected[i] = i * 2
+	}
+	assert.Equal(t, expected, ret)
+}
+
+func TestDistributeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case in <- i:
+			}
+		}
+	}()
+
+	out := Distribute(ctx, in, 2, func(v int) int { return v })
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, i, <-out)
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("output channel was not closed after cancel")
+	}
+}
+
+func TestDistributeBoundsPending(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case in <- i:
+			}
+		}
+	}()
+
+	// 第一个数据一直阻塞，后续结果只能缓存等待
+	release := make(chan struct{})
+	var started atomic.Int32
+	out := Distribute(ctx, in, 2, func(v int) int {
+		started.Add(1)
+		if v == 0 {
+			<-release
+		}
+		return v
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(4), started.Load())
+
+	close(release)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, i, <-out)
+	}
+}
@@ -0,0 +1,49 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FirstN 并发执行所有任务，返回最先成功的 n 个结果，顺序与完成顺序一致
+// 收集到 n 个结果后立即取消派生的上下文并返回，不等待其余任务退出，其余任务的结果被丢弃
+// 成功的任务少于 n 个时返回所有任务错误的聚合错误
+func FirstN[T any](ctx context.Context, tasks []func(context.Context) (T, error), n int) ([]T, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	// 缓冲足够容纳所有结果，提前返回后其余任务也不会阻塞
+	results := make(chan result, len(tasks))
+	for _, task := range tasks {
+		go func(task func(context.Context) (T, error)) {
+			v, err := task(ctx)
+			results <- result{v: v, err: err}
+		}(task)
+	}
+
+	var (
+		values []T
+		errs   []error
+	)
+	for range tasks {
+		r := <-results
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		values = append(values, r.v)
+		if len(values) == n {
+			return values, nil
+		}
+	}
+	return nil, fmt.Errorf("concurrent: %d of %d required tasks succeeded: %w", len(values), n, errors.Join(errs...))
+}
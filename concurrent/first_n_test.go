@@ -0,0 +1,79 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// delayed 返回一个等待 d 后返回 v 的任务，上下文取消时提前返回并通过 cancelled 通知
+func delayed(v int, d time.Duration, err error, cancelled chan<- int) func(context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		select {
+		case <-time.After(d):
+			return v, err
+		case <-ctx.Done():
+			if cancelled != nil {
+				cancelled <- v
+			}
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func TestFirstN(t *testing.T) {
+	boom := errors.New("boom")
+	cancelled := make(chan int, 10)
+	tasks := []func(context.Context) (int, error){
+		delayed(1, time.Hour, nil, cancelled),
+		delayed(2, 30*time.Millisecond, nil, nil),
+		delayed(3, time.Millisecond, boom, nil),
+		delayed(4, 10*time.Millisecond, nil, nil),
+		delayed(5, time.Hour, nil, cancelled),
+	}
+
+	start := time.Now()
+	ret, err := FirstN(context.Background(), tasks, 2)
+	assert.NoError(t, err)
+	// 最快的两个成功结果，失败的任务不计入
+	assert.Equal(t, []int{4, 2}, ret)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// 其余任务的上下文被取消
+	got := map[int]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case v := <-cancelled:
+			got[v] = true
+		case <-time.After(time.Second):
+			t.Fatal("remaining tasks not cancelled")
+		}
+	}
+	assert.Equal(t, map[int]bool{1: true, 5: true}, got)
+}
+
+func TestFirstNNotEnough(t *testing.T) {
+	boom := errors.New("boom")
+	tasks := []func(context.Context) (int, error){
+		delayed(1, time.Millisecond, nil, nil),
+		delayed(2, time.Millisecond, boom, nil),
+		delayed(3, 2*time.Millisecond, boom, nil),
+	}
+
+	ret, err := FirstN(context.Background(), tasks, 2)
+	assert.Nil(t, ret)
+	assert.ErrorIs(t, err, boom)
+
+	// 父上下文取消时任务返回上下文错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FirstN(ctx, []func(context.Context) (int, error){delayed(1, time.Hour, nil, nil)}, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	ret, err = FirstN(context.Background(), tasks, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+}
@@ -0,0 +1,196 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// Flusher 批量刷新器
+// 缓存通过 Add 加入的数据，数据量达到 maxBatch 或者超过 maxIdle 没有新数据加入时，
+// 将缓存的一批数据交给 flush 处理。flush 在后台 goroutine 中按批次顺序依次调用，
+// 上一批尚未处理完时，交出下一批的 Add 会等待。flush 不能回调 Flusher 的方法，否则可能死锁
+type Flusher[T any] struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	maxBatch int
+	maxIdle  time.Duration
+	items    []T
+	timer    *clock.Timer
+	gen      uint64 // 空闲定时器的代数，用于忽略已被重置的定时器
+	closed   bool
+	pending  [][]T         // 已切分、等待交给后台 goroutine 的批次
+	send     chan struct{} // 交出批次的信号量，保证批次按切分的顺序交出
+	batches  chan []T
+	barriers chan chan struct{} // Flush 的屏障，处理到屏障时之前的批次都已刷新完毕
+	done     chan struct{}
+}
+
+// NewFlusher 创建批量刷新器
+// maxBatch 小于1时只按空闲时间刷新，maxIdle 小于等于0时只按数量刷新
+func NewFlusher[T any](clk clock.Clock, maxBatch int, maxIdle time.Duration, flush func([]T)) *Flusher[T] {
+	f := &Flusher[T]{
+		clock:    clk,
+		maxBatch: maxBatch,
+		maxIdle:  maxIdle,
+		send:     make(chan struct{}, 1),
+		batches:  make(chan []T),
+		barriers: make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(f.done)
+		for {
+			select {
+			case batch, ok := <-f.batches:
+				if !ok {
+					return
+				}
+				flush(batch)
+			case barrier := <-f.barriers:
+				close(barrier)
+			}
+		}
+	}()
+	return f
+}
+
+// Add 加入一条数据，Close 之后加入的数据会被丢弃
+func (f *Flusher[T]) Add(item T) {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+
+	f.items = append(f.items, item)
+	if f.maxBatch > 0 && len(f.items) >= f.maxBatch {
+		f.flushLocked()
+		f.mu.Unlock()
+		_ = f.handOff(context.Background())
+		return
+	}
+	f.resetTimerLocked()
+	f.mu.Unlock()
+}
+
+// Close 刷新剩余的数据，并等待所有 flush 调用结束
+func (f *Flusher[T]) Close() {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		<-f.done
+		return
+	}
+	f.closed = true
+	f.flushLocked()
+	f.mu.Unlock()
+
+	// 关闭后不会再有新的批次，交出剩余批次后持有信号量关闭通道，等待中的交出方随后会发现没有批次
+	_ = f.handOff(context.Background())
+	f.send <- struct{}{}
+	close(f.batches)
+	<-f.send
+
+	<-f.done
+}
+
+// Flush 立即刷新缓存的数据，并等待此前交给 flush 的所有批次处理完毕
+// ctx 先结束时返回 ctx.Err()，已经交出的批次仍会在后台继续处理，尚未交出的批次由之后的 Add、Flush 或 Close 交出
+func (f *Flusher[T]) Flush(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.closed {
+		f.flushLocked()
+	}
+	f.mu.Unlock()
+	if err := f.handOff(ctx); err != nil {
+		return err
+	}
+
+	// 刷新 goroutine 依次处理批次和屏障，屏障被处理时之前的批次都已刷新完毕
+	barrier := make(chan struct{})
+	select {
+	case f.barriers <- barrier:
+	case <-f.done: // 已经 Close，所有批次都已刷新
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-barrier:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resetTimerLocked 重新开始计算空闲时间，f.mu 必须已持有
+func (f *Flusher[T]) resetTimerLocked() {
+	if f.maxIdle <= 0 {
+		return
+	}
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	f.gen++
+	gen := f.gen
+	f.timer = f.clock.AfterFunc(f.maxIdle, func() {
+		f.mu.Lock()
+		if f.closed || gen != f.gen {
+			f.mu.Unlock()
+			return
+		}
+		f.flushLocked()
+		f.mu.Unlock()
+		_ = f.handOff(context.Background())
+	})
+}
+
+// flushLocked 将缓存的数据切分为一批，等待 handOff 交给后台 goroutine，f.mu 必须已持有
+func (f *Flusher[T]) flushLocked() {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	f.gen++
+	if len(f.items) == 0 {
+		return
+	}
+	f.pending = append(f.pending, f.items)
+	f.items = nil
+}
+
+// handOff 按顺序将等待中的批次交给后台 goroutine，直到没有等待的批次
+// 交出时不持有 f.mu，ctx 结束时未交出的批次放回队首并返回 ctx.Err()
+func (f *Flusher[T]) handOff(ctx context.Context) error {
+	for {
+		select {
+		case f.send <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		f.mu.Lock()
+		if len(f.pending) == 0 {
+			f.mu.Unlock()
+			<-f.send
+			return nil
+		}
+		batch := f.pending[0]
+		f.pending = f.pending[1:]
+		f.mu.Unlock()
+
+		select {
+		case f.batches <- batch:
+			<-f.send
+		case <-ctx.Done():
+			f.mu.Lock()
+			f.pending = append([][]T{batch}, f.pending...)
+			f.mu.Unlock()
+			<-f.send
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// collect 返回记录每次 flush 批次的回调及读取批次的通道
+func collect() (func([]int), <-chan []int) {
+	batches := make(chan []int, 16)
+	return func(batch []int) { batches <- batch }, batches
+}
+
+func receiveBatch(t *testing.T, batches <-chan []int) []int {
+	select {
+	case batch := <-batches:
+		return batch
+	case <-time.After(time.Second):
+		t.Fatal("expected flush")
+		return nil
+	}
+}
+
+func TestFlusherMaxBatch(t *testing.T) {
+	flush, batches := collect()
+	f := NewFlusher(clock.NewMock(), 3, time.Second, flush)
+	defer f.Close()
+
+	for i := 0; i < 7; i++ {
+		f.Add(i)
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, receiveBatch(t, batches))
+	assert.Equal(t, []int{3, 4, 5}, receiveBatch(t, batches))
+	assert.Empty(t, batches)
+}
+
+func TestFlusherMaxIdle(t *testing.T) {
+	mock := clock.NewMock()
+	flush, batches := collect()
+	f := NewFlusher(mock, 100, time.Second, flush)
+	defer f.Close()
+
+	f.Add(1)
+	mock.Add(900 * time.Millisecond)
+	// 新数据加入后重新计算空闲时间
+	f.Add(2)
+	mock.Add(900 * time.Millisecond)
+	assert.Empty(t, batches)
+
+	mock.Add(100 * time.Millisecond)
+	assert.Equal(t, []int{1, 2}, receiveBatch(t, batches))
+}
+
+func TestFlusherClose(t *testing.T) {
+	flush, batches := collect()
+	f := NewFlusher(clock.NewMock(), 100, time.Second, flush)
+
+	f.Add(1)
+	f.Add(2)
+	f.Close()
+
+	assert.Equal(t, []int{1, 2}, receiveBatch(t, batches))
+
+	f.Add(3)
+	f.Close()
+	assert.Empty(t, batches)
+}
+
+func TestFlusherFlush(t *testing.T) {
+	var flushed []int
+	f := NewFlusher(clock.NewMock(), 0, 0, func(batch []int) {
+		// 刷新较慢，Flush 必须等待其结束
+		time.Sleep(10 * time.Millisecond)
+		flushed = append(flushed, batch...)
+	})
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		f.Add(i)
+	}
+	assert.NoError(t, f.Flush(context.Background()))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, flushed)
+
+	// 没有缓存的数据时直接返回
+	assert.NoError(t, f.Flush(context.Background()))
+
+	f.Close()
+	assert.NoError(t, f.Flush(context.Background()))
+}
+
+func TestFlusherFlushContext(t *testing.T) {
+	release := make(chan struct{})
+	f := NewFlusher(clock.NewMock(), 1, 0, func([]int) { <-release })
+	defer f.Close()
+	defer close(release)
+
+	f.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, f.Flush(ctx), context.DeadlineExceeded)
+}
+
+func TestFlusherFlushContextWhileHandingOff(t *testing.T) {
+	release := make(chan struct{})
+	flushed := make(chan []int, 16)
+	f := NewFlusher(clock.NewMock(), 1, 0, func(batch []int) {
+		<-release
+		flushed <- batch
+	})
+
+	// 第一批正在刷新，第二批的 Add 等待交出
+	f.Add(1)
+	added := make(chan struct{})
+	go func() {
+		f.Add(2)
+		close(added)
+	}()
+
+	// 交出批次时不持有锁，Flush 不会被阻塞，并在 ctx 结束时返回
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, f.Flush(ctx), context.DeadlineExceeded)
+
+	close(release)
+	<-added
+	f.Close()
+	assert.Equal(t, []int{1}, receiveBatch(t, flushed))
+	assert.Equal(t, []int{2}, receiveBatch(t, flushed))
+}
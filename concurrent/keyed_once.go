@@ -0,0 +1,40 @@
+package concurrent
+
+import "sync"
+
+// KeyedOnce 按 key 区分的 sync.Once
+// 每个 key 对应的 fn 在所有 goroutine 中只会执行一次，并发调用者等待执行完成后得到同一个结果
+// 错误同样会被缓存：fn 返回错误后该 key 不会重试，之后的调用都返回这个错误
+// 零值可直接使用
+type KeyedOnce struct {
+	mu    sync.Mutex
+	calls map[string]*onceCall
+}
+
+// onceCall 单个 key 的执行状态
+type onceCall struct {
+	once sync.Once
+	err  error
+}
+
+// Do 对 key 执行一次 fn 并返回其结果
+// 同一 key 的首个调用者执行 fn，其余调用者阻塞直到 fn 返回；不同 key 之间互不阻塞
+// 与 sync.Once 一致，fn 发生 panic 时该 key 同样视为已执行，之后的调用返回 nil
+func (k *KeyedOnce) Do(key string, fn func() error) error {
+	k.mu.Lock()
+	if k.calls == nil {
+		k.calls = make(map[string]*onceCall)
+	}
+	c, ok := k.calls[key]
+	if !ok {
+		c = &onceCall{}
+		k.calls[key] = c
+	}
+	k.mu.Unlock()
+
+	// 在锁外执行 fn，避免慢的初始化阻塞其他 key
+	c.once.Do(func() {
+		c.err = fn()
+	})
+	return c.err
+}
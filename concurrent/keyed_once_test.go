@@ -0,0 +1,94 @@
+package concurrent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedOnceRunsOncePerKey(t *testing.T) {
+	var once KeyedOnce
+	const keys, callers = 8, 32
+
+	var counts [keys]atomic.Int32
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < keys*callers; i++ {
+		k := i % keys
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := once.Do(fmt.Sprintf("key-%d", k), func() error {
+				counts[k].Add(1)
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// 每个 key 只执行一次
+	for k := range counts {
+		assert.Equal(t, int32(1), counts[k].Load(), "key-%d", k)
+	}
+}
+
+func TestKeyedOnceCallersWaitForResult(t *testing.T) {
+	var once KeyedOnce
+	errBoom := errors.New("boom")
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	first := make(chan error, 1)
+	go func() {
+		first <- once.Do("key", func() error {
+			close(entered)
+			<-release
+			return errBoom
+		})
+	}()
+	<-entered
+
+	// fn 执行期间的其他调用者阻塞，并拿到同一个错误
+	second := make(chan error, 1)
+	go func() {
+		second <- once.Do("key", func() error {
+			t.Error("fn must not run twice")
+			return nil
+		})
+	}()
+	select {
+	case <-second:
+		t.Fatal("concurrent caller returned before fn finished")
+	default:
+	}
+	close(release)
+
+	assert.ErrorIs(t, <-first, errBoom)
+	assert.ErrorIs(t, <-second, errBoom)
+}
+
+func TestKeyedOnceCachesError(t *testing.T) {
+	var once KeyedOnce
+	errBoom := errors.New("boom")
+
+	calls := 0
+	fn := func() error {
+		calls++
+		return errBoom
+	}
+
+	// 错误被缓存，不会重试
+	assert.ErrorIs(t, once.Do("key", fn), errBoom)
+	assert.ErrorIs(t, once.Do("key", fn), errBoom)
+	assert.Equal(t, 1, calls)
+
+	// 其他 key 不受影响
+	assert.NoError(t, once.Do("other", func() error { return nil }))
+}
@@ -0,0 +1,63 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// Latch 一次性的倒计数门闩，计数从 NewLatch 指定的 n 开始，只能通过 Done 递减，不能增加
+// 计数归零时关闭内部的通道释放所有等待者，之后的 Wait 立即返回，门闩不能重置
+// 与 WaitGroupWithContext 的区别：参与者数量在创建时固定，计数归零后不会开始新的一轮
+type Latch struct {
+	mu    sync.Mutex
+	count int
+	done  chan struct{} // 计数归零时关闭
+}
+
+// NewLatch 创建计数为 n 的门闩，n 为0时门闩已经打开，n 小于0时 panic
+func NewLatch(n int) *Latch {
+	if n < 0 {
+		panic("concurrent: negative Latch count")
+	}
+	l := &Latch{count: n, done: make(chan struct{})}
+	if n == 0 {
+		close(l.done)
+	}
+	return l
+}
+
+// Count 返回当前的计数
+func (l *Latch) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+// Done 将计数减一，计数归零时释放所有等待者；计数已经为0时什么也不做
+func (l *Latch) Done() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		close(l.done)
+	}
+}
+
+// Wait 阻塞直到计数归零或 ctx 结束，ctx 先结束时返回 ctx.Err()
+func (l *Latch) Wait(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return nil
+	default:
+	}
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,71 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatch(t *testing.T) {
+	l := NewLatch(3)
+	assert.Equal(t, 3, l.Count())
+
+	// 多个等待者在计数归零时同时被释放
+	var released sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		released.Add(1)
+		go func() {
+			defer released.Done()
+			errs <- l.Wait(context.Background())
+		}()
+	}
+
+	l.Done()
+	l.Done()
+	assert.Equal(t, 1, l.Count())
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, errs, 0, "waiters released before the count reached zero")
+
+	l.Done()
+	released.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 0, l.Count())
+
+	// 归零后多余的 Done 被忽略，Wait 立即返回
+	l.Done()
+	assert.Equal(t, 0, l.Count())
+	assert.NoError(t, l.Wait(context.Background()))
+}
+
+func TestLatchZero(t *testing.T) {
+	l := NewLatch(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 已经打开的门闩即使 ctx 已结束也返回 nil
+	assert.NoError(t, l.Wait(ctx))
+	assert.Panics(t, func() { NewLatch(-1) })
+}
+
+func TestLatchCancel(t *testing.T) {
+	l := NewLatch(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+
+	// 取消的等待不影响计数
+	assert.Equal(t, 1, l.Count())
+	l.Done()
+	assert.NoError(t, l.Wait(context.Background()))
+}
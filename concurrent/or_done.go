@@ -60,3 +60,41 @@ func Or(channels ...<-chan interface{}) <-chan interface{} {
 	}()
 	return orDone
 }
+
+// OrWithDone 复合channel 当任意一个channel关闭或者done关闭时，返回
+// 与 Or 不同，关闭done会让递归创建的所有goroutine一并退出，不会因为输入channel永不关闭而泄漏
+func OrWithDone(done <-chan interface{}, channels ...<-chan interface{}) <-chan interface{} {
+	if len(channels) == 0 {
+		return done
+	}
+
+	orDone := make(chan interface{})
+	go func() {
+		defer close(orDone)
+		switch len(channels) {
+		case 1:
+			select {
+			case <-done:
+			case <-channels[0]:
+			}
+		case 2:
+			select {
+			case <-done:
+			case <-channels[0]:
+			case <-channels[1]:
+			}
+		default:
+			// 复制一份再追加orDone，避免改写调用方切片的底层数组
+			rest := make([]<-chan interface{}, 0, len(channels)-2)
+			rest = append(rest, channels[3:]...)
+			select {
+			case <-done:
+			case <-channels[0]:
+			case <-channels[1]:
+			case <-channels[2]:
+			case <-OrWithDone(done, append(rest, orDone)...):
+			}
+		}
+	}()
+	return orDone
+}
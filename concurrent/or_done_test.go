@@ -1,6 +1,7 @@
 package concurrent
 
 import (
+	"runtime"
 	"testing"
 	"time"
 )
@@ -39,3 +40,46 @@ func TestOr(t *testing.T) {
 	)
 	t.Log("done", time.Since(start))
 }
+
+func TestOrWithDone(t *testing.T) {
+	start := time.Now()
+	<-OrWithDone(
+		nil,
+		signal(10*time.Second),
+		signal(20*time.Second),
+		signal(30*time.Second),
+		signal(40*time.Second),
+		signal(3*time.Second),
+	)
+	t.Log("done", time.Since(start))
+}
+
+func TestOrWithDoneNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// 输入channel永不关闭，只能通过done退出
+	done := make(chan interface{})
+	channels := make([]<-chan interface{}, 10)
+	for i := range channels {
+		channels[i] = make(chan interface{})
+	}
+	result := OrWithDone(done, channels...)
+	if runtime.NumGoroutine() <= before {
+		t.Fatal("expected OrWithDone to start goroutines")
+	}
+
+	close(done)
+	select {
+	case <-result:
+	case <-time.After(time.Second):
+		t.Fatal("result not closed after done was closed")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("goroutines leaked: %d before, %d after", before, n)
+	}
+}
@@ -0,0 +1,64 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// ParallelMap 并行映射切片
+// 最多 workers 个 goroutine 并发对 items 执行 fn，结果顺序与 items 保持一致
+// 任一 fn 返回错误时取消共享的上下文，不再启动新的任务，并返回第一个错误
+func ParallelMap[T, U any](ctx context.Context, items []T, workers int, fn func(context.Context, T) (U, error)) ([]U, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]U, len(items))
+	sem := make(chan struct{}, workers)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i, item := range items {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if err := ctx.Err(); err != nil {
+			fail(err)
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			v, err := fn(ctx, item)
+			if err != nil {
+				fail(err)
+				return
+			}
+			results[i] = v
+		}(i, item)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
@@ -0,0 +1,74 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelMap(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	var running, peak int32
+	// 越早的数据耗时越长，确保结果乱序完成
+	ret, err := ParallelMap(context.Background(), items, 4, func(ctx context.Context, v int) (int, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(20-v) * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return v * 2, nil
+	})
+
+	assert.NoError(t, err)
+	expected := make([]int, 20)
+	for i := range expected {
+		expected[i] = i * 2
+	}
+	assert.Equal(t, expected, ret)
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(4))
+}
+
+func TestParallelMapError(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	boom := errors.New("boom")
+	var started int32
+	// 第一个任务出错，其余任务等待上下文取消
+	ret, err := ParallelMap(context.Background(), items, 2, func(ctx context.Context, v int) (int, error) {
+		atomic.AddInt32(&started, 1)
+		if v == 0 {
+			return 0, boom
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	assert.ErrorIs(t, err, boom)
+	assert.Nil(t, ret)
+	assert.Less(t, atomic.LoadInt32(&started), int32(len(items)))
+}
+
+func TestParallelMapCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ParallelMap(ctx, []int{1, 2, 3}, 2, func(ctx context.Context, v int) (int, error) {
+		return v, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,113 @@
+package concurrent
+
+import "context"
+
+// Pipeline 以链式调用的方式组合流水线的各个阶段
+// 每个阶段运行在自己的goroutine中，上下文取消或上游通道关闭时依次关闭各阶段的输出通道
+//
+//	out := NewPipeline(ctx, source).Map(double).Filter(even).Take(10).Out()
+type Pipeline[T any] struct {
+	ctx context.Context
+	out <-chan T
+}
+
+// NewPipeline 以 source 作为数据源创建流水线
+func NewPipeline[T any](ctx context.Context, source <-chan T) *Pipeline[T] {
+	return &Pipeline[T]{ctx: ctx, out: source}
+}
+
+// Map 追加一个阶段，将每个值替换为 fn 的返回值
+func (p *Pipeline[T]) Map(fn func(T) T) *Pipeline[T] {
+	return &Pipeline[T]{ctx: p.ctx, out: mapStream(p.ctx, p.out, fn)}
+}
+
+// Filter 追加一个阶段，只保留 fn 返回 true 的值
+func (p *Pipeline[T]) Filter(fn func(T) bool) *Pipeline[T] {
+	return &Pipeline[T]{ctx: p.ctx, out: filterStream(p.ctx, p.out, fn)}
+}
+
+// Take 追加一个阶段，只取前 n 个值
+// 取满后上游阶段会阻塞在发送上，直到上下文取消才退出，因此使用 Take 时应在读完后取消上下文
+func (p *Pipeline[T]) Take(n int) *Pipeline[T] {
+	return &Pipeline[T]{ctx: p.ctx, out: takeStream(p.ctx, p.out, n)}
+}
+
+// Out 返回流水线最后一个阶段的输出通道
+func (p *Pipeline[T]) Out() <-chan T {
+	return p.out
+}
+
+// mapStream 是 Multiply、Add 的泛型版本，对每个值执行 fn
+func mapStream[T any](ctx context.Context, in <-chan T, fn func(T) T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- fn(v):
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// filterStream 是 TaskFn 的泛型版本
+func filterStream[T any](ctx context.Context, in <-chan T, fn func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !fn(v) {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// takeStream 是 TaskN 的泛型版本
+func takeStream[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
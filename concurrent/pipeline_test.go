@@ -1,8 +1,11 @@
 package concurrent
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 //go:generate go test
@@ -29,3 +32,39 @@ func TestChannel(t *testing.T) {
 		t.Fatalf("should take about 1s, but actually %s", duration)
 	}
 }
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	// 手工组合的版本
+	expected := drain(Multiply(ctx, Add(ctx, Multiply(ctx, sliceStream(values...), 2), 1), 2))
+
+	out := NewPipeline(ctx, sliceStream(values...)).
+		Map(func(v int) int { return v * 2 }).
+		Map(func(v int) int { return v + 1 }).
+		Map(func(v int) int { return v * 2 }).
+		Out()
+	assert.Equal(t, expected, drain(out))
+
+	out = NewPipeline(ctx, sliceStream(values...)).
+		Filter(func(v int) bool { return v%2 == 0 }).
+		Map(func(v int) int { return v * 10 }).
+		Take(3).
+		Out()
+	assert.Equal(t, []int{20, 40, 60}, drain(out))
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := make(chan int)
+	out := NewPipeline(ctx, source).Map(func(v int) int { return v }).Filter(func(int) bool { return true }).Out()
+
+	cancel()
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("pipeline not closed after cancel")
+	}
+}
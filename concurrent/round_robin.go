@@ -0,0 +1,48 @@
+package concurrent
+
+import "context"
+
+// RoundRobin 轮询分发
+// 将输入流中的每个数据依次发送给 n 个输出通道中的下一个，每个数据只会被一个输出接收，
+// 与 FanOut 将每个数据广播给所有输出不同
+// 发送严格按轮询顺序进行：轮到的输出没有被读取时会一直等待，不会跳过它发送给其他输出，
+// 因此一个停滞的消费者会阻塞整个分发，但等待会在上下文取消时结束，不会永久阻塞
+// 当上下文被取消或输入流关闭时，所有输出通道会被关闭，取消时正在等待发送的数据会被丢弃
+func RoundRobin(ctx context.Context, in <-chan interface{}, n int) []<-chan interface{} {
+	if n < 1 {
+		n = 1
+	}
+
+	outs := make([]chan interface{}, n)
+	result := make([]<-chan interface{}, n)
+	for i := range outs {
+		outs[i] = make(chan interface{})
+		result[i] = outs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+
+		for next := 0; ; next = (next + 1) % n {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outs[next] <- v:
+				}
+			}
+		}
+	}()
+
+	return result
+}
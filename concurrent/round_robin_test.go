@@ -0,0 +1,87 @@
+package concurrent
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundRobin(t *testing.T) {
+	in := make(chan interface{})
+	go func() {
+		defer close(in)
+		for i := 0; i < 30; i++ {
+			in <- i
+		}
+	}()
+
+	outs := RoundRobin(context.Background(), in, 3)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	received := make([][]int, len(outs))
+	for i, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range out {
+				mu.Lock()
+				received[i] = append(received[i], v.(int))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 每个输出按轮询顺序收到相同数量的数据
+	var all []int
+	for i, values := range received {
+		assert.Len(t, values, 10)
+		for j, v := range values {
+			assert.Equal(t, j*3+i, v)
+		}
+		all = append(all, values...)
+	}
+
+	// 没有数据丢失或重复
+	sort.Ints(all)
+	expected := make([]int, 30)
+	for i := range expected {
+		expected[i] = i
+	}
+	assert.Equal(t, expected, all)
+}
+
+func TestRoundRobinCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan interface{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case in <- i:
+			}
+		}
+	}()
+
+	outs := RoundRobin(ctx, in, 2)
+	assert.Equal(t, 0, <-outs[0])
+
+	// 轮到的 outs[1] 没有消费者，取消后分发结束并关闭所有输出
+	cancel()
+	for _, out := range outs {
+		select {
+		case _, ok := <-out:
+			for ok {
+				_, ok = <-out
+			}
+		case <-time.After(time.Second):
+			t.Fatal("outputs were not closed after cancel")
+		}
+	}
+}
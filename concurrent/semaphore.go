@@ -0,0 +1,89 @@
+package concurrent
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Semaphore 带权重的计数信号量
+// 基于 mutex+cond 实现，支持一次获取多个单位，适用于资源池等场景
+// 公平性：等待者严格按照调用 Acquire 的先后顺序(FIFO)获取，队首的等待者资源不足时，
+// 即使后面的等待者所需更少也不会越过它，从而避免大请求被饿死
+type Semaphore struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	size    int64
+	cur     int64
+	waiters list.List // 按到达顺序排队的等待者
+}
+
+// NewSemaphore 创建总量为 size 的信号量
+func NewSemaphore(size int64) *Semaphore {
+	s := &Semaphore{size: size}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Acquire 获取 n 个单位，资源不足时阻塞直到获取成功或 ctx 结束
+// ctx 结束时返回 ctx.Err()，不会占用任何资源；n 大于总量时只能等待 ctx 结束
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiters.Len() == 0 && s.cur+n <= s.size {
+		s.cur += n
+		return nil
+	}
+
+	// ctx 结束时唤醒所有等待者，使其检查自己的 ctx
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer stop()
+
+	elem := s.waiters.PushBack(n)
+	for {
+		if s.waiters.Front() == elem && s.cur+n <= s.size {
+			s.cur += n
+			s.waiters.Remove(elem)
+			// 新的队首可能也能获取
+			s.cond.Broadcast()
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			s.waiters.Remove(elem)
+			// 离开队首后，后面的等待者可能可以获取
+			s.cond.Broadcast()
+			return err
+		}
+		s.cond.Wait()
+	}
+}
+
+// TryAcquire 尝试获取 n 个单位，不阻塞
+// 有其他等待者或资源不足时返回 false
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiters.Len() == 0 && s.cur+n <= s.size {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release 释放 n 个单位，释放多于已获取的数量时 panic
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("concurrent: released more than held")
+	}
+	s.cond.Broadcast()
+}
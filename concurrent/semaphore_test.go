@@ -0,0 +1,102 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForWaiters 等待信号量上排队的等待者达到 n 个
+func waitForWaiters(t *testing.T, s *Semaphore, n int) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		l := s.waiters.Len()
+		s.mu.Unlock()
+		if l == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d", n, l)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSemaphoreTryAcquire(t *testing.T) {
+	s := NewSemaphore(3)
+
+	assert.True(t, s.TryAcquire(2))
+	assert.False(t, s.TryAcquire(2))
+	assert.True(t, s.TryAcquire(1))
+
+	s.Release(3)
+	assert.True(t, s.TryAcquire(3))
+	assert.Panics(t, func() { s.Release(4) })
+}
+
+func TestSemaphoreWeightedOrder(t *testing.T) {
+	s := NewSemaphore(3)
+	assert.NoError(t, s.Acquire(context.Background(), 3))
+
+	var mu sync.Mutex
+	var order []int64
+	var wg sync.WaitGroup
+	acquire := func(n int64) {
+		defer wg.Done()
+		assert.NoError(t, s.Acquire(context.Background(), n))
+		mu.Lock()
+		order = append(order, n)
+		mu.Unlock()
+	}
+
+	// 先到的大请求排在队首
+	wg.Add(2)
+	go acquire(2)
+	waitForWaiters(t, s, 1)
+	go acquire(1)
+	waitForWaiters(t, s, 2)
+
+	// 释放1个单位不足以满足队首，后面的小请求也不能越过它
+	s.Release(1)
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, order)
+	mu.Unlock()
+	assert.False(t, s.TryAcquire(1))
+
+	s.Release(2)
+	wg.Wait()
+	assert.Equal(t, []int64{2, 1}, order)
+}
+
+func TestSemaphoreAcquireCancel(t *testing.T) {
+	s := NewSemaphore(2)
+	assert.NoError(t, s.Acquire(context.Background(), 2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() { errc <- s.Acquire(ctx, 2) }()
+	waitForWaiters(t, s, 1)
+
+	// 排在被取消者之后的等待者
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, s.Acquire(context.Background(), 1))
+		close(done)
+	}()
+	waitForWaiters(t, s, 2)
+
+	s.Release(1)
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waiter behind a cancelled waiter was not woken")
+	}
+}
@@ -0,0 +1,48 @@
+package concurrent
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/andrewbytecoder/gokit/math"
+)
+
+// stripe 计数器的一个分段，独占一个缓存行
+type stripe struct {
+	value   atomic.Int64
+	padding [56]byte // 缓存行填充，避免伪共享(cache line size 64 - int64大小 8 = 56)
+}
+
+// ShardedCounter 分段计数器
+// 将计数分散到多个独占缓存行的分段上，每次 Add 随机选择一个分段，
+// 高并发下比对单个变量执行 atomic.AddInt64 的缓存行争用更少
+type ShardedCounter struct {
+	stripes []stripe
+	mask    uint64
+}
+
+// NewShardedCounter 创建分段计数器
+// stripes 会向上取整为2的幂，小于1时使用 runtime.GOMAXPROCS(0)
+func NewShardedCounter(stripes int) *ShardedCounter {
+	if stripes < 1 {
+		stripes = runtime.GOMAXPROCS(0)
+	}
+	n := math.NextPowerOfTwo(stripes)
+	return &ShardedCounter{stripes: make([]stripe, n), mask: uint64(n - 1)}
+}
+
+// Add 将 delta 加到计数器上
+func (c *ShardedCounter) Add(delta int64) {
+	c.stripes[rand.Uint64()&c.mask].value.Add(delta)
+}
+
+// Value 返回所有分段之和
+// 与 Add 并发调用时返回值介于调用前后的计数之间，没有并发写入时即为精确值
+func (c *ShardedCounter) Value() int64 {
+	var sum int64
+	for i := range c.stripes {
+		sum += c.stripes[i].value.Load()
+	}
+	return sum
+}
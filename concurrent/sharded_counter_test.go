@@ -0,0 +1,51 @@
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedCounter(t *testing.T) {
+	c := NewShardedCounter(6)
+	assert.Len(t, c.stripes, 8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(64*1000), c.Value())
+
+	c.Add(-64 * 1000)
+	assert.Equal(t, int64(0), c.Value())
+}
+
+// 64个goroutine并发累加
+func BenchmarkShardedCounterAdd(b *testing.B) {
+	c := NewShardedCounter(0)
+	b.SetParallelism(64)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Add(1)
+		}
+	})
+}
+
+func BenchmarkAtomicAddInt64(b *testing.B) {
+	var c int64
+	b.SetParallelism(64)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			atomic.AddInt64(&c, 1)
+		}
+	})
+}
@@ -20,6 +20,32 @@ func Stream(ctx context.Context, values ...interface{}) <-chan interface{} {
 	return out
 }
 
+// StreamResult 与 Stream 相同，将离散数据转化为数据流
+// 额外返回一个函数，用于在数据流读取完毕后判断是否所有值都已发送，
+// 因上下文取消导致只发送了部分数据时返回false
+// 返回的函数会等待发送goroutine退出，因此必须在通道读取完毕或上下文取消后调用
+func StreamResult(ctx context.Context, values ...interface{}) (<-chan interface{}, func() bool) {
+	out := make(chan interface{})
+	done := make(chan struct{})
+	completed := false
+	go func() {
+		defer close(done)
+		defer close(out)
+		for _, value := range values {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- value:
+			}
+		}
+		completed = true
+	}()
+	return out, func() bool {
+		<-done
+		return completed
+	}
+}
+
 // TaskN 只取前n个数据
 // 从输入的数据流中获取前n个元素，发送到返回的通道中
 // 当获取到n个元素、上下文被取消或输入流关闭时，函数会停止并关闭输出通道
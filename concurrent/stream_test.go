@@ -40,6 +40,30 @@ func TestStream(t *testing.T) {
 	assert.Equal(t, []int{0, 1, 2}, ret)
 }
 
+func TestStreamResult(t *testing.T) {
+	c, completed := StreamResult(context.Background(), generateStreamNumber(10)...)
+	var ret []int
+	for v := range c {
+		ret = append(ret, v.(int))
+	}
+	assert.Equal(t, flagSlice, ret)
+	assert.True(t, completed())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, completed = StreamResult(ctx, generateStreamNumber(10)...)
+	ret = nil
+	for v := range c {
+		ret = append(ret, v.(int))
+		if len(ret) == 3 {
+			cancel()
+			break
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2}, ret)
+	assert.False(t, completed())
+}
+
 func TestTaskN(t *testing.T) {
 	c := Stream(context.Background(), generateStreamNumber(10)...)
 	taskN := TaskN(context.Background(), c, 3)
@@ -0,0 +1,56 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// WaitGroupWithContext 与 sync.WaitGroup 类似，但 Wait 可以通过上下文设置超时或取消
+// 计数归零时关闭内部的通道通知所有等待者，之后再次 Add 会开始新的一轮
+// 零值可以直接使用
+type WaitGroupWithContext struct {
+	mu   sync.Mutex
+	n    int
+	done chan struct{} // 计数大于0时有效，归零时关闭
+}
+
+// Add 将计数增加 delta，delta 可以为负数，计数小于0时 panic
+func (wg *WaitGroupWithContext) Add(delta int) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	wg.n += delta
+	switch {
+	case wg.n < 0:
+		panic("concurrent: negative WaitGroupWithContext counter")
+	case wg.n == 0:
+		if wg.done != nil {
+			close(wg.done)
+			wg.done = nil
+		}
+	case wg.done == nil:
+		wg.done = make(chan struct{})
+	}
+}
+
+// Done 将计数减一
+func (wg *WaitGroupWithContext) Done() {
+	wg.Add(-1)
+}
+
+// Wait 阻塞直到计数归零或 ctx 结束，ctx 先结束时返回 ctx.Err()
+func (wg *WaitGroupWithContext) Wait(ctx context.Context) error {
+	wg.mu.Lock()
+	done := wg.done
+	wg.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
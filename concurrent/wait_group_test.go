@@ -0,0 +1,55 @@
+package concurrent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitGroupWithContext(t *testing.T) {
+	var wg WaitGroupWithContext
+	// 计数为0时立即返回
+	assert.NoError(t, wg.Wait(context.Background()))
+
+	var finished atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(10 * time.Millisecond)
+			finished.Add(1)
+		}()
+	}
+	assert.NoError(t, wg.Wait(context.Background()))
+	assert.Equal(t, int32(10), finished.Load())
+
+	// 归零后可以开始新的一轮
+	wg.Add(1)
+	go wg.Done()
+	assert.NoError(t, wg.Wait(context.Background()))
+}
+
+func TestWaitGroupWithContextCancel(t *testing.T) {
+	var wg WaitGroupWithContext
+	wg.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, wg.Wait(ctx), context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, wg.Wait(ctx), context.Canceled)
+
+	// 取消的等待不影响计数
+	wg.Done()
+	assert.NoError(t, wg.Wait(context.Background()))
+}
+
+func TestWaitGroupWithContextNegative(t *testing.T) {
+	var wg WaitGroupWithContext
+	assert.Panics(t, wg.Done)
+}
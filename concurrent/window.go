@@ -0,0 +1,55 @@
+package concurrent
+
+import (
+	"context"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// TumblingWindow 按固定时长 d 将数据流切分为互不重叠的窗口，在每个窗口结束时
+// 将窗口内的数据交给 agg 聚合并发送结果，窗口边界由 clk 的 Ticker 决定
+// 没有数据的窗口不发送结果；恰好在边界到达的数据可能落入前后任意一个窗口
+// 输入通道关闭时发送最后一个不完整窗口的聚合结果后关闭输出通道，上下文取消时直接关闭输出通道
+func TumblingWindow[T any](ctx context.Context, in <-chan T, d time.Duration, clk clock.Clock, agg func([]T) T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		ticker := clk.Ticker(d)
+		defer ticker.Stop()
+
+		var items []T
+		emit := func() bool {
+			if len(items) == 0 {
+				return true
+			}
+			v := agg(items)
+			// agg 可能持有切片，因此每个窗口使用新的切片
+			items = nil
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- v:
+				return true
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !emit() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					emit()
+					return
+				}
+				items = append(items, v)
+			}
+		}
+	}()
+	return out
+}
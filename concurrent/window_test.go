@@ -0,0 +1,75 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func sum(items []int) int {
+	total := 0
+	for _, v := range items {
+		total += v
+	}
+	return total
+}
+
+func receiveWindow(t *testing.T, out <-chan int) int {
+	select {
+	case v := <-out:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("expected window")
+		return 0
+	}
+}
+
+func TestTumblingWindow(t *testing.T) {
+	mock := clock.NewMock()
+	in := make(chan int)
+	out := TumblingWindow(context.Background(), in, time.Second, mock, sum)
+
+	// 第一个窗口
+	in <- 1
+	in <- 2
+	in <- 3
+	mock.Add(time.Second)
+	assert.Equal(t, 6, receiveWindow(t, out))
+
+	// 空窗口不发送结果
+	mock.Add(time.Second)
+	select {
+	case v := <-out:
+		t.Fatalf("unexpected window %d", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// 第三个窗口
+	in <- 10
+	in <- 20
+	mock.Add(time.Second)
+	assert.Equal(t, 30, receiveWindow(t, out))
+
+	// 输入关闭时发送不完整的最后一个窗口
+	in <- 100
+	close(in)
+	assert.Equal(t, 100, receiveWindow(t, out))
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestTumblingWindowCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := TumblingWindow(ctx, in, time.Second, clock.NewMock(), sum)
+
+	in <- 1
+	cancel()
+	// 取消时不发送未完成的窗口
+	for v := range out {
+		t.Fatalf("unexpected window %d", v)
+	}
+}
@@ -0,0 +1,46 @@
+package bytesqyeye
+
+import (
+	"github.com/andrewbytecoder/gokit/fileutil/mmap"
+)
+
+// Allocator allocates and releases the backing array of BytesQueue
+type Allocator interface {
+	// Alloc returns a zeroed byte array of length size
+	Alloc(size int) ([]byte, error)
+	// Free releases an array returned by Alloc, the array must not be used afterwards
+	Free(array []byte) error
+}
+
+// HeapAllocator allocates the backing array on the Go heap, it is the default allocator
+type HeapAllocator struct{}
+
+// Alloc allocates size bytes with make
+func (HeapAllocator) Alloc(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+// Free does nothing, the array is reclaimed by the GC
+func (HeapAllocator) Free([]byte) error {
+	return nil
+}
+
+// MmapAllocator allocates the backing array off-heap with an anonymous mmap,
+// so that large queues add no work to the GC. Arrays must be released with Free.
+type MmapAllocator struct{}
+
+// Alloc maps size bytes of anonymous memory
+func (MmapAllocator) Alloc(size int) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	return mmap.MapAnonymous(size)
+}
+
+// Free unmaps an array returned by Alloc
+func (MmapAllocator) Free(array []byte) error {
+	if array == nil {
+		return nil
+	}
+	return mmap.Unmap(array)
+}
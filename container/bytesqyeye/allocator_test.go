@@ -0,0 +1,99 @@
+package bytesqyeye
+
+import (
+	"encoding/binary"
+	"runtime"
+	"testing"
+)
+
+func TestMmapAllocator(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue, err := NewBytesQueueWithAllocator(11, 0, false, MmapAllocator{})
+	noError(t, err)
+
+	// when
+	index, _ := queue.Push([]byte("hello1"))
+	queue.Push([]byte("hello2"))
+	queue.Push(blob('a', 100))
+
+	// then
+	assertEqual(t, []byte("hello1"), get(queue, index))
+	assertEqual(t, []byte("hello1"), pop(queue))
+	assertEqual(t, []byte("hello2"), pop(queue))
+	assertEqual(t, blob('a', 100), pop(queue))
+
+	// when
+	noError(t, queue.Close())
+	_, err = queue.Peek()
+
+	// then
+	assertEqual(t, "queue is empty", err.Error())
+	assertEqual(t, 0, queue.Capacity())
+}
+
+func TestNilAllocator(t *testing.T) {
+	t.Parallel()
+
+	// given a queue built without an allocator
+	queue := &BytesQueue{
+		array:        make([]byte, 11),
+		capacity:     11,
+		headerBuffer: make([]byte, binary.MaxVarintLen32),
+		head:         leftMarginIndex,
+		tail:         leftMarginIndex,
+		rightMargin:  leftMarginIndex,
+	}
+
+	// when
+	queue.Push([]byte("hello1"))
+	queue.Push(blob('a', 100))
+
+	// then the queue grows on the heap
+	assertEqual(t, []byte("hello1"), pop(queue))
+	assertEqual(t, blob('a', 100), pop(queue))
+	noError(t, queue.Close())
+
+	// when
+	queue, err := NewBytesQueueWithAllocator(11, 0, false, nil)
+
+	// then
+	noError(t, err)
+	queue.Push(blob('a', 100))
+	assertEqual(t, blob('a', 100), pop(queue))
+}
+
+func BenchmarkGCPauseHeap(b *testing.B) {
+	benchmarkGCPause(b, HeapAllocator{})
+}
+
+func BenchmarkGCPauseOffHeap(b *testing.B) {
+	benchmarkGCPause(b, MmapAllocator{})
+}
+
+// benchmarkGCPause 测量存在一个 256MB 队列时每次 GC 的平均停顿时间
+func benchmarkGCPause(b *testing.B, allocator Allocator) {
+	queue, err := NewBytesQueueWithAllocator(256*1024*1024, 0, false, allocator)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer queue.Close()
+	entry := blob('a', 1024)
+	for i := 0; i < 200*1024; i++ {
+		queue.Push(entry)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(after.NumGC-before.NumGC), "pause-ns/gc")
+	runtime.KeepAlive(queue)
+}
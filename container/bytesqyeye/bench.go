@@ -0,0 +1,13 @@
+package bytesqyeye
+
+// BenchFill pushes count entries of entrySize bytes into q, so that benchmarks and tests
+// can reproduce the same fill pattern. It stops at the first failed push and returns its error.
+func BenchFill(q *BytesQueue, entrySize, count int) error {
+	entry := make([]byte, entrySize)
+	for i := 0; i < count; i++ {
+		if _, err := q.Push(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
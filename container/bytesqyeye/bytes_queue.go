@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -17,25 +18,41 @@ const (
 )
 
 var (
-	errEmptyQueue       = errors.New("queue is empty")
-	errInvalidIndex     = errors.New("index must be greater than zero, Invalid index")
-	errIndexOutOfBounds = errors.New("index out of bounds")
-	errFullQueue        = errors.New("queue is full, Maximum size limit reached")
+	// ErrEmpty is returned when reading from a queue without entries
+	ErrEmpty = errors.New("queue is empty")
+	// ErrInvalidIndex is returned when reading from an index that is not greater than zero
+	ErrInvalidIndex = errors.New("index must be greater than zero, Invalid index")
+	// ErrIndexOutOfBounds is returned when reading from an index beyond the queue's byte array
+	ErrIndexOutOfBounds = errors.New("index out of bounds")
+	// ErrFull is returned by Push when the entry does not fit without exceeding the maximum capacity
+	ErrFull = errors.New("queue is full, Maximum size limit reached")
+)
+
+// unexported names used inside the package
+var (
+	errEmptyQueue       = ErrEmpty
+	errInvalidIndex     = ErrInvalidIndex
+	errIndexOutOfBounds = ErrIndexOutOfBounds
+	errFullQueue        = ErrFull
 )
 
 // BytesQueue is a non-thead safe queue type of fifo based on bytes array
 // for every  push operation index of entry is returned. It can be used to read the entry later
 type BytesQueue struct {
-	full         bool   // flag to indicate if queue is full
-	array        []byte // underlying byte array
-	capacity     int    // capacity of queue
-	maxCapacity  int    // maximum capacity of queue
-	head         int    // index of first element in queue
-	tail         int    // index of last element in queue
-	count        int    // number of elements in queue
-	rightMargin  int    // right margin index
-	headerBuffer []byte // header buffer
-	verbose      bool   // verbose mode
+	full         bool                                        // flag to indicate if queue is full
+	array        []byte                                      // underlying byte array
+	capacity     int                                         // capacity of queue
+	peakCapacity int                                         // highest capacity the queue ever had
+	maxCapacity  int                                         // maximum capacity of queue
+	head         int                                         // index of first element in queue
+	tail         int                                         // index of last element in queue
+	count        int                                         // number of elements in queue
+	rightMargin  int                                         // right margin index
+	headerBuffer []byte                                      // header buffer
+	verbose      bool                                        // verbose mode
+	allocator    Allocator                                   // allocator of the underlying byte array, nil means HeapAllocator
+	onAllocate   func(oldCap, newCap int, dur time.Duration) // reallocation hook, replaces verbose output when set
+	allocCount   int                                         // number of heap allocations triggered by pushes, read by tests
 }
 
 // getNeededSize returns the number of bytes an entry of length need in the queue
@@ -65,16 +82,51 @@ func getNeededSize(length int) int {
 // capacity is the used in bytes array allocated for queue.
 // When verbose flag is set then information about memory allocation are printed to console
 func NewBytesQueue(capacity int, maxCapacity int, verbose bool) *BytesQueue {
+	q, _ := NewBytesQueueWithAllocator(capacity, maxCapacity, verbose, HeapAllocator{})
+	return q
+}
+
+// NewBytesQueueWithAllocator initializes new bytes queue whose byte array is allocated by allocator.
+// Queue allocated with an allocator other than HeapAllocator must be released with Close.
+// A nil allocator is HeapAllocator.
+func NewBytesQueueWithAllocator(capacity int, maxCapacity int, verbose bool, allocator Allocator) (*BytesQueue, error) {
+	if allocator == nil {
+		allocator = HeapAllocator{}
+	}
+	array, err := allocator.Alloc(capacity) // 初始化一个字节数组
+	if err != nil {
+		return nil, err
+	}
 	return &BytesQueue{
-		array:        make([]byte, capacity),              // 初始化一个字节数组
+		array:        array,                               // 字节数组
 		capacity:     capacity,                            // 容量
+		peakCapacity: capacity,                            // 历史最大容量
 		maxCapacity:  maxCapacity,                         // 最大容量
 		headerBuffer: make([]byte, binary.MaxVarintLen32), // header buffer
 		tail:         leftMarginIndex,                     // tail index
 		head:         leftMarginIndex,                     // head index
 		rightMargin:  leftMarginIndex,                     // right margin index
 		verbose:      verbose,                             // verbose
+		allocator:    allocator,                           // 分配器
+	}, nil
+}
+
+// Close removes all entries from queue and releases the underlying byte array
+func (q *BytesQueue) Close() error {
+	array := q.array
+	q.Reset()
+	q.array = nil
+	q.capacity = 0
+	return q.arrayAllocator().Free(array)
+}
+
+// arrayAllocator returns the allocator of the underlying byte array,
+// queues built without NewBytesQueueWithAllocator use HeapAllocator
+func (q *BytesQueue) arrayAllocator() Allocator {
+	if q.allocator == nil {
+		return HeapAllocator{}
 	}
+	return q.allocator
 }
 
 // Reset removes all entries from queue
@@ -100,7 +152,9 @@ func (q *BytesQueue) Push(entry []byte) (int, error) {
 			return -1, errFullQueue
 		} else {
 			// 扩容
-			q.allocateAdditionalMemory(neededSize)
+			if err := q.allocateAdditionalMemory(neededSize); err != nil {
+				return -1, err
+			}
 		}
 	}
 
@@ -114,28 +168,31 @@ func (q *BytesQueue) Push(entry []byte) (int, error) {
 // allocateAdditionalMemory 为BytesQueue 分配额外的内存，使其容量至少能容纳minimum字节。
 // 该方法在当前容量不足时被调用 （例如Push 空间不够）
 // 扩容策略： 至少翻倍，但是不超过maxCapacity （如果设置）
-func (q *BytesQueue) allocateAdditionalMemory(minimum int) {
+func (q *BytesQueue) allocateAdditionalMemory(minimum int) error {
 	// 扩容开始时间
 	start := time.Now()
 
-	// 1. 确保新容量至少比 minimum 大
-	if q.capacity < minimum {
-		q.capacity += minimum
-	}
-
-	// 2. 将容量翻倍，避免频繁的扩容
-	q.capacity *= 2
-
-	// 3. 确保新容量不超过maxCapacity
-	if q.maxCapacity > 0 && q.capacity > q.maxCapacity {
-		q.capacity = q.maxCapacity
+	// 1~3. 计算新容量：至少比 minimum 大，翻倍，且不超过 maxCapacity
+	oldCapacity := q.capacity
+	capacity, err := q.grownCapacity(minimum)
+	if err != nil {
+		return err
 	}
 
 	// 4. 保存旧数组指针，用于后续数据迁移
 	oldArray := q.array
 
 	// 5. 创建新的数组，大小为旧数组的2倍
-	q.array = make([]byte, q.capacity)
+	array, err := q.arrayAllocator().Alloc(capacity)
+	if err != nil {
+		return err
+	}
+	q.allocCount++
+	q.array = array
+	q.capacity = capacity
+	if q.capacity > q.peakCapacity {
+		q.peakCapacity = q.capacity
+	}
 
 	// 6. 判断是否需要迁移旧数据
 	// leftMarginIndex 是一个常量（通常为 0, 这里为1），q.rightMargin 表示已使用数据的右边界
@@ -147,6 +204,7 @@ func (q *BytesQueue) allocateAdditionalMemory(minimum int) {
 		if q.tail <= q.head {
 			if q.tail != q.head {
 				// 创建空闲区，并使用空slice填充
+				q.allocCount++
 				q.push(make([]byte, q.head-q.tail), q.head-q.tail)
 			}
 			// 6.3 重置 head 和 tail 指针
@@ -159,10 +217,14 @@ func (q *BytesQueue) allocateAdditionalMemory(minimum int) {
 	}
 	// 7. 表级队列容量不满
 	q.full = false
-	// 8. 若使用verbose模式，打印扩容耗时和新容量信息
-	if q.verbose {
+	// 8. 若设置了回调则上报扩容信息，否则在verbose模式下打印扩容耗时和新容量信息
+	if q.onAllocate != nil {
+		q.onAllocate(oldCapacity, q.capacity, time.Since(start))
+	} else if q.verbose {
 		fmt.Printf("Expanding queue to %d bytes in %f\n", q.capacity, time.Since(start).Seconds())
 	}
+	// 9. 释放旧数组
+	return q.arrayAllocator().Free(oldArray)
 }
 
 func (q *BytesQueue) push(data []byte, len int) {
@@ -186,6 +248,33 @@ func (q *BytesQueue) copy(data []byte, len int) {
 	q.tail += copy(q.array[q.tail:], data[:len])
 }
 
+// grownCapacity 计算扩容后的容量，先按上限截断再翻倍，避免 int 溢出
+// 截断后的容量仍然容纳不下 minimum 时返回 errFullQueue
+func (q *BytesQueue) grownCapacity(minimum int) (int, error) {
+	limit := q.maxCapacity
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
+	if q.capacity > limit-minimum { // 当前容量加上 minimum 已经超过上限
+		return 0, errFullQueue
+	}
+
+	// 1. 确保新容量至少比 minimum 大
+	capacity := q.capacity
+	if capacity < minimum {
+		capacity += minimum
+	}
+
+	// 2. 将容量翻倍，避免频繁的扩容
+	// 3. 确保新容量不超过maxCapacity，翻倍前比较，翻倍本身不会溢出
+	if capacity > limit/2 {
+		capacity = limit
+	} else {
+		capacity *= 2
+	}
+	return capacity, nil
+}
+
 // canInsertAfterTail returns true if it's possible to insert an entry of size of need after the tail of the queue
 func (q *BytesQueue) canInsertAfterTail(need int) bool {
 	if q.full {
@@ -243,18 +332,30 @@ func (q *BytesQueue) Pop() ([]byte, error) {
 	return data, nil
 }
 
-// Peek reads the oldest entry from list  without moving head pointer
+// Peek reads the oldest entry from list  without moving head pointer.
+// The returned slice aliases the queue's memory and may be overwritten by a later Push, use PeekCopy to keep it.
 func (q *BytesQueue) Peek() ([]byte, error) {
 	data, _, err := q.peek(q.head)
 	return data, err
 }
 
-// Get reads entry from index
+// PeekCopy reads a copy of the oldest entry from list without moving head pointer
+func (q *BytesQueue) PeekCopy() ([]byte, error) {
+	return cloneEntry(q.Peek())
+}
+
+// Get reads entry from index.
+// The returned slice aliases the queue's memory and may be overwritten by a later Push, use GetCopy to keep it.
 func (q *BytesQueue) Get(index int) ([]byte, error) {
 	data, _, err := q.peek(index)
 	return data, err
 }
 
+// GetCopy reads a copy of the entry from index
+func (q *BytesQueue) GetCopy(index int) ([]byte, error) {
+	return cloneEntry(q.Get(index))
+}
+
 // CheckGet checks if an entry can be read from index
 func (q *BytesQueue) CheckGet(index int) error {
 	return q.peekCheckErr(index)
@@ -265,6 +366,100 @@ func (q *BytesQueue) Capacity() int {
 	return q.capacity
 }
 
+// MaxCapacity returns the maximum numbers of bytes the queue can allocate, 0 means unlimited
+func (q *BytesQueue) MaxCapacity() int {
+	return q.maxCapacity
+}
+
+// MaxPushSize returns the length of the largest entry Push currently accepts without returning ErrFull,
+// 0 when no entry fits and -1 when the queue has no maximum capacity
+func (q *BytesQueue) MaxPushSize() int {
+	if q.maxCapacity <= 0 {
+		return -1
+	}
+	// 扩容路径要求 capacity+need < maxCapacity
+	need := q.maxCapacity - q.capacity - 1
+	if !q.full {
+		if q.tail >= q.head {
+			need = max(need, q.capacity-q.tail, q.head-leftMarginIndex)
+		} else {
+			need = max(need, q.head-q.tail)
+		}
+	}
+	return max(maxEntryLength(need), 0)
+}
+
+// FreeContiguous returns the length of the largest entry Push currently accepts without allocating more memory,
+// taking into account the minimumHeaderSize bytes that must stay free between tail and head.
+// Unlike Capacity() - used space it does not add up free regions that a single entry cannot span.
+// Returns -1 when not even an empty entry fits
+func (q *BytesQueue) FreeContiguous() int {
+	if q.full {
+		return -1
+	}
+	if q.tail >= q.head {
+		// 空闲区域在 [tail, capacity) 和 [1, head)，对应 canInsertAfterTail 和 canInsertBeforeHead
+		return max(maxEntryLength(q.capacity-q.tail), maxEntryLengthBeforeHead(q.head-leftMarginIndex))
+	}
+	return maxEntryLengthBeforeHead(q.head - q.tail)
+}
+
+// maxEntryLengthBeforeHead returns the length of the largest entry that fits into the free bytes in front of head,
+// which must either be filled exactly or leave at least minimumHeaderSize bytes, -1 if no entry fits
+func maxEntryLengthBeforeHead(free int) int {
+	if length := maxEntryLength(free); length >= 0 && getNeededSize(length) == free {
+		return length
+	}
+	return maxEntryLength(free - minimumHeaderSize)
+}
+
+// maxEntryLength returns the length of the largest entry whose getNeededSize is at most size, -1 if no entry fits
+func maxEntryLength(size int) int {
+	// 反推 getNeededSize，去掉长度头部占用的字节
+	for header := 1; header <= binary.MaxVarintLen32; header++ {
+		if length := size - header; length >= 0 && getNeededSize(length) <= size {
+			return length
+		}
+	}
+	return -1
+}
+
+// SetMaxCapacity changes the maximum numbers of bytes the queue can allocate, 0 means unlimited.
+// Lowering it does not release memory that is already allocated.
+func (q *BytesQueue) SetMaxCapacity(maxCapacity int) {
+	q.maxCapacity = maxCapacity
+}
+
+// SetOnAllocate sets a callback invoked after every reallocation of the underlying byte array
+// with the old and new capacity and the time the reallocation took.
+// When set it replaces the output of verbose mode, nil restores it.
+func (q *BytesQueue) SetOnAllocate(onAllocate func(oldCap, newCap int, dur time.Duration)) {
+	q.onAllocate = onAllocate
+}
+
+// PeakCapacity returns the highest number of bytes ever allocated for queue
+func (q *BytesQueue) PeakCapacity() int {
+	return q.peakCapacity
+}
+
+// Used returns the number of bytes occupied by the entries in the queue, including their length headers
+func (q *BytesQueue) Used() int {
+	if q.count == 0 {
+		return 0
+	}
+	if q.tail > q.head {
+		return q.tail - q.head
+	}
+	// 队列已回绕，数据分布在 [head, rightMargin) 和 [1, tail)
+	return q.rightMargin - q.head + q.tail - leftMarginIndex
+}
+
+// AllocCount returns the number of heap allocations triggered by pushes since the queue was created,
+// i.e. reallocations of the underlying array and the padding entries they push
+func (q *BytesQueue) AllocCount() int {
+	return q.allocCount
+}
+
 // Len returns the number of elements in the queue
 func (q *BytesQueue) Len() int {
 	return q.count
@@ -296,3 +491,13 @@ func (q *BytesQueue) peek(index int) ([]byte, int, error) {
 	return q.array[index+n : index+int(blockSize)], int(blockSize), nil
 
 }
+
+// cloneEntry copies entry so that it stays valid after the queue memory is reused
+func cloneEntry(entry []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	dst := make([]byte, len(entry))
+	copy(dst, entry)
+	return dst, nil
+}
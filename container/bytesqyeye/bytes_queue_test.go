@@ -2,11 +2,15 @@ package bytesqyeye
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"path"
 	"reflect"
 	"runtime"
 	"testing"
+	"time"
 )
 
 func TestPushAndPop(t *testing.T) {
@@ -164,6 +168,94 @@ func TestAllocateAdditionalSpace(t *testing.T) {
 	assertEqual(t, 22, queue.Capacity())
 }
 
+func TestPeakCapacity(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(11, 0, false)
+	assertEqual(t, 11, queue.PeakCapacity())
+
+	// when
+	queue.Push([]byte("hello1"))
+	queue.Push([]byte("hello2"))
+	queue.Reset()
+
+	// then
+	assertEqual(t, 22, queue.PeakCapacity())
+
+	// when
+	queue.capacity = 11
+
+	// then
+	assertEqual(t, 22, queue.PeakCapacity())
+}
+
+func TestOnAllocate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var allocations [][2]int
+	queue := NewBytesQueue(11, 0, true)
+	queue.SetOnAllocate(func(oldCap, newCap int, dur time.Duration) {
+		allocations = append(allocations, [2]int{oldCap, newCap})
+	})
+
+	// when
+	queue.Push([]byte("hello1"))
+	queue.Push([]byte("hello2"))
+
+	// then
+	assertEqual(t, [][2]int{{11, 22}}, allocations)
+}
+
+func TestGetCopy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(20, 0, false)
+	index, _ := queue.Push(blob('a', 8))
+	queue.Push(blob('b', 8))
+	aliased, _ := queue.Get(index)
+	copied, _ := queue.GetCopy(index)
+	peeked, _ := queue.PeekCopy()
+
+	// when
+	queue.Pop()
+	newIndex, _ := queue.Push(blob('c', 8))
+
+	// then
+	assertEqual(t, index, newIndex)
+	assertEqual(t, blob('c', 8), aliased)
+	assertEqual(t, blob('a', 8), copied)
+	assertEqual(t, blob('a', 8), peeked)
+}
+
+func TestAllocCount(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(100, 0, false)
+
+	// when
+	for i := 0; i < 10; i++ {
+		queue.Push([]byte("hello"))
+	}
+
+	// then
+	assertEqual(t, 100, queue.Capacity())
+	assertEqual(t, 0, queue.AllocCount())
+
+	// when
+	for i := 0; i < 10; i++ {
+		queue.Push(blob('a', 50))
+	}
+
+	// then
+	if queue.AllocCount() == 0 {
+		t.Fatal("expected reallocations for entries that do not fit")
+	}
+}
+
 func TestAllocateAdditionalSpaceForInsufficientFreeFragmentedSpaceWhereHeadIsBeforeTail(t *testing.T) {
 	t.Parallel()
 
@@ -404,6 +496,237 @@ func TestMaxSizeLimit(t *testing.T) {
 	assertEqual(t, blob('b', 5), pop(queue))
 }
 
+func TestExportedErrors(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(10, 10, false)
+
+	// when
+	_, popErr := queue.Pop()
+	_, emptyErr := queue.Get(1)
+	queue.Push(blob('a', 4))
+	_, invalidErr := queue.Get(0)
+	_, outOfBoundsErr := queue.Get(42)
+	_, fullErr := queue.Push(blob('b', 10))
+
+	// then
+	assertEqual(t, true, errors.Is(popErr, ErrEmpty))
+	assertEqual(t, true, errors.Is(emptyErr, ErrEmpty))
+	assertEqual(t, true, errors.Is(invalidErr, ErrInvalidIndex))
+	assertEqual(t, true, errors.Is(outOfBoundsErr, ErrIndexOutOfBounds))
+	assertEqual(t, true, errors.Is(fullErr, ErrFull))
+	assertEqual(t, true, errors.Is(fmt.Errorf("wrapped: %w", fullErr), ErrFull))
+	assertEqual(t, errFullQueue, ErrFull)
+}
+
+func TestMaxPushSize(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(10, 50, false)
+	unlimited := NewBytesQueue(10, 0, false)
+
+	// when
+	size := queue.MaxPushSize()
+	_, tooBigErr := queue.Push(blob('a', size+1))
+	_, err := queue.Push(blob('a', size))
+
+	// then
+	assertEqual(t, -1, unlimited.MaxPushSize())
+	assertEqual(t, 38, size)
+	assertEqual(t, ErrFull, tooBigErr)
+	noError(t, err)
+	assertEqual(t, 9, queue.MaxPushSize())
+
+	// when
+	_, err = queue.Push(blob('b', queue.MaxPushSize()))
+
+	// then
+	noError(t, err)
+	assertEqual(t, 0, queue.MaxPushSize())
+}
+
+func TestFreeContiguous(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		setup func(q *BytesQueue)
+		// capacity and expected FreeContiguous
+		capacity, want int
+	}{
+		{
+			name:     "empty queue, after tail",
+			setup:    func(q *BytesQueue) {},
+			capacity: 100, want: 98,
+		},
+		{
+			name: "head is before tail, exact fit before head",
+			setup: func(q *BytesQueue) {
+				// [1, 91) used, then [1, 31) freed
+				q.Push(blob('a', 29))
+				q.Push(blob('b', 29))
+				q.Push(blob('c', 29))
+				q.Pop()
+			},
+			capacity: 100, want: 29,
+		},
+		{
+			name: "head is before tail, after tail is larger",
+			setup: func(q *BytesQueue) {
+				q.Push(blob('a', 9))
+				q.Push(blob('b', 9))
+				q.Pop()
+			},
+			capacity: 100, want: 78,
+		},
+		{
+			name: "tail is before head, exact fit",
+			setup: func(q *BytesQueue) {
+				q.Push(blob('a', 29))
+				q.Push(blob('b', 29))
+				q.Push(blob('c', 29))
+				q.Pop()
+				q.Pop()
+				// wraps around, leaving [21, 61) free
+				q.Push(blob('d', 19))
+			},
+			capacity: 100, want: 39,
+		},
+		{
+			name: "tail is before head, minimum header size reserved",
+			setup: func(q *BytesQueue) {
+				q.Push(blob('a', 176))
+				q.Push(blob('b', 99))
+				q.Pop()
+				// wraps around, leaving [51, 179) free, 128 bytes cannot be filled exactly
+				q.Push(blob('c', 49))
+			},
+			capacity: 300, want: 110,
+		},
+		{
+			name: "full queue",
+			setup: func(q *BytesQueue) {
+				q.Push(blob('a', 98))
+			},
+			capacity: 100, want: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newQueue := func() *BytesQueue {
+				q := NewBytesQueue(tt.capacity, 0, false)
+				tt.setup(q)
+				return q
+			}
+
+			// when
+			queue := newQueue()
+			free := queue.FreeContiguous()
+
+			// then
+			assertEqual(t, tt.want, free)
+
+			if free >= 0 {
+				// when
+				_, err := queue.Push(blob('x', free))
+
+				// then
+				noError(t, err)
+				assertEqual(t, tt.capacity, queue.Capacity())
+			}
+
+			// when
+			queue = newQueue()
+			_, err := queue.Push(blob('x', free+1))
+
+			// then
+			noError(t, err)
+			assertEqual(t, true, queue.Capacity() > tt.capacity)
+		})
+	}
+}
+
+func TestUsed(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(100, 0, false)
+
+	// then
+	assertEqual(t, 0, queue.Used())
+
+	// when
+	queue.Push(blob('a', 29))
+	queue.Push(blob('b', 29))
+	queue.Push(blob('c', 29))
+
+	// then
+	assertEqual(t, 90, queue.Used())
+
+	// when
+	queue.Pop()
+	queue.Pop()
+	// wraps around before head
+	queue.Push(blob('d', 19))
+
+	// then
+	assertEqual(t, 50, queue.Used())
+
+	// when
+	queue.Push(blob('e', 39))
+
+	// then queue is full
+	assertEqual(t, 90, queue.Used())
+	assertEqual(t, -1, queue.FreeContiguous())
+
+	// when
+	queue.Pop()
+	queue.Pop()
+	queue.Pop()
+
+	// then
+	assertEqual(t, 0, queue.Used())
+}
+
+func TestGrownCapacity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		capacity, maxCapacity, minimum int
+		want                           int
+		wantErr                        error
+	}{
+		{capacity: 10, minimum: 5, want: 20},
+		{capacity: 10, minimum: 20, want: 60},
+		{capacity: 600, maxCapacity: 1000, minimum: 100, want: 1000},
+		{capacity: 400, maxCapacity: 1000, minimum: 100, want: 800},
+		{capacity: 950, maxCapacity: 1000, minimum: 100, wantErr: ErrFull},
+		// doubling would overflow int without a maximum capacity
+		{capacity: math.MaxInt/2 + 10, minimum: 16, want: math.MaxInt},
+		{capacity: math.MaxInt / 4, minimum: math.MaxInt/2 + 1, want: math.MaxInt},
+		{capacity: math.MaxInt - 5, minimum: 16, wantErr: ErrFull},
+		{capacity: math.MaxInt/2 + 10, maxCapacity: math.MaxInt - 1, minimum: 16, want: math.MaxInt - 1},
+	}
+
+	for _, tt := range tests {
+		// given
+		queue := &BytesQueue{capacity: tt.capacity, maxCapacity: tt.maxCapacity}
+
+		// when
+		capacity, err := queue.grownCapacity(tt.minimum)
+
+		// then
+		assertEqual(t, tt.wantErr, err)
+		assertEqual(t, tt.want, capacity)
+		if err == nil && capacity < tt.capacity+tt.minimum {
+			t.Errorf("capacity %d cannot hold %d more bytes than %d", capacity, tt.minimum, tt.capacity)
+		}
+	}
+}
+
 func TestPushEntryAfterAllocateAdditionMemory(t *testing.T) {
 	t.Parallel()
 
@@ -508,3 +831,99 @@ func objectsAreEqual(expected, actual interface{}) bool {
 	}
 	return bytes.Equal(exp, act)
 }
+
+func TestBenchFillReallocations(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(64, 0, false)
+
+	// when
+	err := BenchFill(queue, 64, 1000)
+
+	// then the capacity at least doubles on every reallocation, so growing from 64 bytes
+	// to the 65000 bytes of 1000 entries with 1 byte headers takes at most 10 reallocations
+	noError(t, err)
+	assertEqual(t, 1000, queue.Len())
+	if queue.AllocCount() > 10 {
+		t.Fatalf("expected at most 10 reallocations, got %d", queue.AllocCount())
+	}
+	if queue.Capacity() >= 2*65000 {
+		t.Fatalf("expected capacity below %d, got %d", 2*65000, queue.Capacity())
+	}
+
+	// when
+	limited := NewBytesQueue(64, 1000, false)
+	err = BenchFill(limited, 64, 1000)
+
+	// then
+	assertEqual(t, ErrFull, err)
+}
+
+func TestPushPopAllocs(t *testing.T) {
+	// given
+	queue := NewBytesQueue(1024, 0, false)
+	entry := blob('a', 64)
+
+	// when
+	allocs := testing.AllocsPerRun(1000, func() {
+		queue.Push(entry)
+		queue.Pop()
+	})
+
+	// then
+	assertEqual(t, float64(0), allocs)
+	assertEqual(t, 0, queue.AllocCount())
+}
+
+func BenchmarkPush(b *testing.B) {
+	for _, size := range []int{16, 256, 4096} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			const entries = 1024
+			queue := NewBytesQueue(entries*(size+binary.MaxVarintLen32), 0, false)
+			entry := blob('a', size)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%entries == 0 {
+					queue.Reset()
+				}
+				queue.Push(entry)
+			}
+		})
+	}
+}
+
+func BenchmarkPop(b *testing.B) {
+	for _, size := range []int{16, 256, 4096} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			const entries = 1024
+			queue := NewBytesQueue(entries*(size+binary.MaxVarintLen32), 0, false)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if queue.Len() == 0 {
+					b.StopTimer()
+					BenchFill(queue, size, entries)
+					b.StartTimer()
+				}
+				queue.Pop()
+			}
+		})
+	}
+}
+
+func BenchmarkPushRealloc(b *testing.B) {
+	for _, count := range []int{100, 10000} {
+		b.Run(fmt.Sprintf("count=%d", count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				BenchFill(NewBytesQueue(64, 0, false), 64, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package bytesqyeye
+
+import (
+	"sync"
+)
+
+// SyncBytesQueue is a thread-safe BytesQueue guarded by a sync.RWMutex.
+// Reads take the read lock and mutations take the write lock.
+// Since entries may be overwritten as soon as the lock is released,
+// Pop, Peek and Get return copies of the entries instead of views into the underlying array.
+type SyncBytesQueue struct {
+	lock  sync.RWMutex
+	queue BytesQueue
+}
+
+// NewSyncBytesQueue initializes new thread-safe bytes queue, see NewBytesQueue
+func NewSyncBytesQueue(capacity int, maxCapacity int, verbose bool) *SyncBytesQueue {
+	return &SyncBytesQueue{queue: *NewBytesQueue(capacity, maxCapacity, verbose)}
+}
+
+// Push copies entry at the end of queue, see BytesQueue.Push
+func (q *SyncBytesQueue) Push(entry []byte) (int, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.queue.Push(entry)
+}
+
+// Pop removes the oldest entry from the queue and returns a copy of it
+func (q *SyncBytesQueue) Pop() ([]byte, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return cloneEntry(q.queue.Pop())
+}
+
+// Peek returns a copy of the oldest entry without removing it
+func (q *SyncBytesQueue) Peek() ([]byte, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return cloneEntry(q.queue.Peek())
+}
+
+// Get returns a copy of the entry at index
+func (q *SyncBytesQueue) Get(index int) ([]byte, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return cloneEntry(q.queue.Get(index))
+}
+
+// Reset removes all entries from queue
+func (q *SyncBytesQueue) Reset() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.queue.Reset()
+}
+
+// Len returns the number of elements in the queue
+func (q *SyncBytesQueue) Len() int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.queue.Len()
+}
+
+// Capacity returns the numbers of allocated bytes for queue
+func (q *SyncBytesQueue) Capacity() int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.queue.Capacity()
+}
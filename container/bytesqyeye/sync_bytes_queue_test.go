@@ -0,0 +1,51 @@
+package bytesqyeye
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestSyncBytesQueueConcurrent(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewSyncBytesQueue(64, 0, false)
+	workers := 8
+	pushes := 1000
+
+	// when
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(char byte) {
+			defer wg.Done()
+			for j := 0; j < pushes; j++ {
+				index, err := queue.Push(blob(char, 10))
+				noError(t, err)
+				if entry, err := queue.Get(index); err == nil && len(entry) != 10 {
+					t.Errorf("unexpected entry length %d", len(entry))
+				}
+			}
+		}(byte('a' + i))
+		go func() {
+			defer wg.Done()
+			for j := 0; j < pushes; j++ {
+				queue.Peek()
+				queue.Len()
+				queue.Capacity()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// then
+	assertEqual(t, workers*pushes, queue.Len())
+	for queue.Len() > 0 {
+		entry, err := queue.Pop()
+		noError(t, err)
+		if !bytes.Equal(entry, blob(entry[0], 10)) {
+			t.Fatalf("corrupted entry %q", entry)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package hash
+
+// ProbingHasher is a Hasher that can also generate a deterministic probe sequence for a key,
+// which is used to step through slots when resolving collisions in open addressing.
+type ProbingHasher interface {
+	Hasher
+	// Probe returns the i-th probe of key, Probe(key, 0) equals Sum64(key).
+	Probe(key string, i int) uint64
+}
+
+// Double combines two hashers using double hashing: probe i of a key is h1(key) + i*h2(key).
+type Double struct {
+	h1 Hasher
+	h2 Hasher
+}
+
+// NewDouble returns a ProbingHasher that uses h1 for the start position and h2 for the step.
+func NewDouble(h1, h2 Hasher) *Double {
+	return &Double{h1: h1, h2: h2}
+}
+
+// Sum64 returns the hash of key calculated by h1.
+func (d *Double) Sum64(key string) uint64 {
+	return d.h1.Sum64(key)
+}
+
+// Probe returns h1(key) + i*h2(key). The step is forced to be odd, so that for any
+// power of two table size the first size probes of a key visit every slot exactly once.
+func (d *Double) Probe(key string, i int) uint64 {
+	step := d.h2.Sum64(key) | 1
+	return d.h1.Sum64(key) + uint64(i)*step
+}
+
+var _ ProbingHasher = (*Double)(nil)
@@ -0,0 +1,60 @@
+package hash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// murmurHasher adapts Murmur to the Hasher interface
+type murmurHasher struct{}
+
+func (murmurHasher) Sum64(key string) uint64 {
+	h := NewMurmur(0x9747b28c)
+	h.Write([]byte(key))
+	sum := uint64(h.Sum32())
+	return sum<<32 | sum
+}
+
+func TestDoubleProbe(t *testing.T) {
+	d := NewDouble(NewFnv64(), murmurHasher{})
+
+	assert.Equal(t, NewFnv64().Sum64("key"), d.Sum64("key"))
+	assert.Equal(t, d.Sum64("key"), d.Probe("key", 0))
+
+	probes := make(map[uint64]struct{})
+	for i := 0; i < 1000; i++ {
+		probes[d.Probe("key", i)] = struct{}{}
+	}
+	assert.Len(t, probes, 1000)
+}
+
+func TestDoubleProbeVisitsEverySlot(t *testing.T) {
+	d := NewDouble(NewFnv64(), murmurHasher{})
+	const slots = 64
+
+	for k := 0; k < 100; k++ {
+		key := fmt.Sprintf("key%d", k)
+		visited := make(map[uint64]struct{})
+		for i := 0; i < slots; i++ {
+			visited[d.Probe(key, i)%slots] = struct{}{}
+		}
+		assert.Len(t, visited, slots, key)
+	}
+}
+
+func TestDoubleProbeDistribution(t *testing.T) {
+	d := NewDouble(NewFnv64(), murmurHasher{})
+	const slots = 16
+	const keys = 16000
+
+	// 第二次探测的位置应当均匀分布在所有槽位上
+	counts := make([]int, slots)
+	for k := 0; k < keys; k++ {
+		counts[d.Probe(fmt.Sprintf("key%d", k), 1)%slots]++
+	}
+	for slot, count := range counts {
+		assert.InDelta(t, keys/slots, count, keys/slots/4, "slot %d", slot)
+	}
+}
@@ -0,0 +1,25 @@
+package hash
+
+// Fnv64Mixed is FNV-1a followed by the fmix64 finalizer of MurmurHash3,
+// the extra avalanche step spreads short and adjacent keys over the low bits.
+type Fnv64Mixed struct {
+}
+
+func NewFnv64Mixed() *Fnv64Mixed {
+	return &Fnv64Mixed{}
+}
+
+// Sum64 gets the string and returns its mixed uint64 hash value.
+func (f Fnv64Mixed) Sum64(key string) uint64 {
+	return fmix64(Fnv64{}.Sum64(key))
+}
+
+// fmix64 is the 64-bit finalizer of MurmurHash3, every input bit affects every output bit.
+func fmix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
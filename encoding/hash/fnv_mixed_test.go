@@ -0,0 +1,40 @@
+package hash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// occupancyVariance 统计 key0..key{n-1} 按低位掩码分片后各分片占用数的方差
+func occupancyVariance(h Hasher, keys, shards int) float64 {
+	counts := make([]float64, shards)
+	for i := 0; i < keys; i++ {
+		counts[h.Sum64(fmt.Sprintf("key%d", i))&uint64(shards-1)]++
+	}
+	mean := float64(keys) / float64(shards)
+	var variance float64
+	for _, c := range counts {
+		variance += (c - mean) * (c - mean)
+	}
+	return variance / float64(shards)
+}
+
+func TestFnv64MixedFinalizes(t *testing.T) {
+	assert.Equal(t, fmix64(NewFnv64().Sum64("key")), NewFnv64Mixed().Sum64("key"))
+	assert.NotEqual(t, NewFnv64().Sum64("key"), NewFnv64Mixed().Sum64("key"))
+}
+
+func TestFnv64MixedShardDistribution(t *testing.T) {
+	const keys = 10000
+	for _, shards := range []int{8, 16, 64, 256, 1024} {
+		plain := occupancyVariance(NewFnv64(), keys, shards)
+		mixed := occupancyVariance(NewFnv64Mixed(), keys, shards)
+		t.Logf("shards=%d plain variance=%.2f mixed variance=%.2f", shards, plain, mixed)
+
+		// 均匀随机分布时方差期望为 keys/shards*(1-1/shards)
+		expected := float64(keys) / float64(shards) * (1 - 1/float64(shards))
+		assert.Less(t, mixed, 2*expected, "shards=%d", shards)
+	}
+}
@@ -0,0 +1,38 @@
+package hash
+
+import "fmt"
+
+// Static is a Hasher returning a fixed hash per key, which makes collisions
+// and shard placement fully deterministic in tests.
+type Static struct {
+	sums map[string]uint64
+}
+
+// NewStatic returns a Hasher that returns m[key] for every key,
+// Sum64 panics for keys that are not in m.
+func NewStatic(m map[string]uint64) Hasher {
+	return &Static{sums: m}
+}
+
+// Sum64 returns the fixed hash of key.
+func (s *Static) Sum64(key string) uint64 {
+	sum, ok := s.sums[key]
+	if !ok {
+		panic(fmt.Sprintf("hash: no static hash for key %q", key))
+	}
+	return sum
+}
+
+// Constant is a Hasher returning the same hash for every key,
+// e.g. to force all keys into a single shard.
+type Constant uint64
+
+// NewConstant returns a Hasher that always returns v.
+func NewConstant(v uint64) Hasher {
+	return Constant(v)
+}
+
+// Sum64 returns the constant hash regardless of key.
+func (c Constant) Sum64(string) uint64 {
+	return uint64(c)
+}
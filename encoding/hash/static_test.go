@@ -0,0 +1,23 @@
+package hash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatic(t *testing.T) {
+	h := NewStatic(map[string]uint64{"a": 1, "b": 1, "c": 42})
+
+	assert.Equal(t, uint64(1), h.Sum64("a"))
+	assert.Equal(t, uint64(1), h.Sum64("b"))
+	assert.Equal(t, uint64(42), h.Sum64("c"))
+	assert.Panics(t, func() { h.Sum64("unknown") })
+}
+
+func TestConstant(t *testing.T) {
+	h := NewConstant(7)
+
+	assert.Equal(t, uint64(7), h.Sum64("a"))
+	assert.Equal(t, uint64(7), h.Sum64("b"))
+}
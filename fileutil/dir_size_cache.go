@@ -0,0 +1,87 @@
+package fileutil
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// SizeCache caches DirSize results so that repeatedly polling a large
+// directory does not walk it every time.
+//
+// A cached size is reused while it is younger than the TTL and the
+// directory's own mtime is unchanged. The directory mtime only changes when
+// entries are added, removed or renamed directly inside it; growth of
+// existing files or changes in subdirectories are picked up once the TTL
+// expires.
+type SizeCache struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]sizeEntry
+}
+
+type sizeEntry struct {
+	size    int64
+	modTime time.Time
+	expires time.Time
+}
+
+// SizeCacheOption configures a SizeCache.
+type SizeCacheOption func(*SizeCache)
+
+// WithClock sets the clock used for TTL expiry, so tests can drive
+// invalidation with a mock clock instead of sleeping.
+func WithClock(c clock.Clock) SizeCacheOption {
+	return func(sc *SizeCache) { sc.clock = c }
+}
+
+// NewSizeCache returns a SizeCache whose results are reused for at most ttl.
+func NewSizeCache(ttl time.Duration, opts ...SizeCacheOption) *SizeCache {
+	sc := &SizeCache{
+		ttl:     ttl,
+		clock:   clock.New(),
+		entries: make(map[string]sizeEntry),
+	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	return sc
+}
+
+// DirSize returns the total size of the regular files under dir, walking the
+// directory only when the cached value is missing or stale.
+func (sc *SizeCache) DirSize(dir string) (int64, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, err
+	}
+	now := sc.clock.Now()
+
+	sc.mu.Lock()
+	e, ok := sc.entries[dir]
+	sc.mu.Unlock()
+	if ok && now.Before(e.expires) && info.ModTime().Equal(e.modTime) {
+		return e.size, nil
+	}
+
+	size, err := DirSize(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	sc.mu.Lock()
+	sc.entries[dir] = sizeEntry{size: size, modTime: info.ModTime(), expires: now.Add(sc.ttl)}
+	sc.mu.Unlock()
+	return size, nil
+}
+
+// Invalidate drops the cached size of dir.
+func (sc *SizeCache) Invalidate(dir string) {
+	sc.mu.Lock()
+	delete(sc.entries, dir)
+	sc.mu.Unlock()
+}
@@ -0,0 +1,72 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	require.NoError(t, os.WriteFile(name, make([]byte, 10), 0o644))
+
+	mock := clock.NewMock()
+	sc := NewSizeCache(time.Minute, WithClock(mock))
+
+	size, err := sc.DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	// growing an existing file does not change the directory mtime,
+	// so the cached size is served until the TTL expires
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, 5))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	mock.Add(59 * time.Second)
+	size, err = sc.DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	mock.Add(time.Second)
+	size, err = sc.DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), size)
+}
+
+func TestSizeCacheModTimeAndInvalidate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), make([]byte, 10), 0o644))
+
+	sc := NewSizeCache(time.Hour, WithClock(clock.NewMock()))
+	size, err := sc.DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	// a new entry changes the directory mtime and invalidates the cache
+	// (pin the mtime so the test does not depend on filesystem timestamp granularity)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b"), make([]byte, 20), 0o644))
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(dir, future, future))
+	size, err = sc.DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), size)
+
+	// Invalidate forces a walk even though mtime and TTL are unchanged
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), make([]byte, 1), 0o644))
+	sc.Invalidate(dir)
+	size, err = sc.DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(21), size)
+
+	_, err = sc.DirSize(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
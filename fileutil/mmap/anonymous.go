@@ -0,0 +1,27 @@
+package mmap
+
+import (
+	"fmt"
+
+	"github.com/edsrzf/mmap-go"
+)
+
+// MapAnonymous maps size bytes of zeroed memory that is not backed by a file.
+// The memory lives outside the Go heap, so it is neither tracked nor scanned by the GC,
+// and must be released with Unmap.
+func MapAnonymous(size int) ([]byte, error) {
+	m, err := mmap.MapRegion(nil, size, mmap.RDWR, mmap.ANON, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmap anonymous: %w", err)
+	}
+	return m, nil
+}
+
+// Unmap releases memory returned by MapAnonymous. b must be the exact slice returned.
+func Unmap(b []byte) error {
+	m := mmap.MMap(b)
+	if err := m.Unmap(); err != nil {
+		return fmt.Errorf("munmap anonymous: %w", err)
+	}
+	return nil
+}
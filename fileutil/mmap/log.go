@@ -0,0 +1,288 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/edsrzf/mmap-go"
+	"go.uber.org/zap"
+)
+
+// recordHeaderSize is the size of the length prefix written before every record.
+// The prefix holds the record length plus one, so that a zero prefix marks the end of the log.
+const recordHeaderSize = 4
+
+// checksumSize is the size of the CRC-32C written after the length prefix when checksums are enabled.
+const checksumSize = 4
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrLogFull is returned when a record does not fit into the remaining space of the log.
+	ErrLogFull = errors.New("mmap log: not enough space for record")
+	// ErrLogNotEmpty is returned when records are loaded into a log that already has records.
+	ErrLogNotEmpty = errors.New("mmap log: log is not empty")
+	// ErrInvalidOffset is returned when reading at an offset that does not point to a record.
+	ErrInvalidOffset = errors.New("mmap log: invalid offset")
+	// ErrChecksumMismatch is returned when a record does not match its checksum.
+	ErrChecksumMismatch = errors.New("mmap log: checksum mismatch")
+	// ErrLogClosed is returned when using a log after Close.
+	ErrLogClosed = errors.New("mmap log: log is closed")
+)
+
+// Log is an append-only log of length-prefixed records backed by a memory-mapped file.
+type Log struct {
+	mu       sync.RWMutex
+	data     []byte
+	closer   io.Closer
+	offset   int
+	checksum bool
+	closed   bool // the file is unmapped, data must not be accessed
+}
+
+// LogOption configures a Log.
+type LogOption func(*Log)
+
+// WithChecksum stores a CRC-32C after the length prefix of every record, so that
+// RecoverLog can tell a partially written record from a complete one.
+// A log must always be opened with the same setting it was written with.
+func WithChecksum() LogOption {
+	return func(l *Log) {
+		l.checksum = true
+	}
+}
+
+func newLog(data []byte, closer io.Closer, opts []LogOption) *Log {
+	l := &Log{data: data, closer: closer}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// OpenLog creates a new, empty log of the given size at filename.
+func OpenLog(filename string, size int, logger *zap.Logger, opts ...LogOption) (*Log, error) {
+	data, closer, err := GetMMappedFile(filename, size, logger)
+	if err != nil {
+		return nil, err
+	}
+	return newLog(data, closer, opts), nil
+}
+
+// RecoverLog opens the log of the given size at filename keeping the records already in it,
+// e.g. after a crash, and positions the write offset after the last complete record.
+// A final record that was only partially written, i.e. whose length points beyond the file
+// or, with WithChecksum, whose checksum does not match, is discarded together with
+// everything after it.
+func RecoverLog(filename string, size int, logger *zap.Logger, opts ...LogOption) (*Log, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	data, closer, err := GetMMappedFileWithOptions(filename, size, Options{Perm: 0o666, Keep: true, Logger: logger})
+	if err != nil {
+		return nil, err
+	}
+
+	l := newLog(data, closer, opts)
+	for {
+		_, next, err := l.recordAt(l.offset, len(data))
+		if err != nil {
+			break
+		}
+		l.offset = next
+	}
+
+	if tail := data[l.offset:]; slices.ContainsFunc(tail, func(b byte) bool { return b != 0 }) {
+		// clear the torn record so that it cannot be mistaken for a complete one
+		// once shorter records are appended over it
+		logger.Warn("mmap log: discarding partially written record", zap.String("file", filename), zap.Int("offset", l.offset))
+		clear(tail)
+	}
+	return l, nil
+}
+
+// headerSize returns the number of bytes written before the payload of every record.
+func (l *Log) headerSize() int {
+	if l.checksum {
+		return recordHeaderSize + checksumSize
+	}
+	return recordHeaderSize
+}
+
+// recordAt returns the payload of the record at offset without copying it and the offset of
+// the next record, considering only the first limit bytes of the log.
+func (l *Log) recordAt(offset, limit int) ([]byte, int, error) {
+	headerSize := l.headerSize()
+	if offset < 0 || offset+headerSize > limit {
+		return nil, 0, ErrInvalidOffset
+	}
+	length := int(binary.LittleEndian.Uint32(l.data[offset:])) - 1
+	next := offset + headerSize + length
+	if length < 0 || next > limit {
+		return nil, 0, ErrInvalidOffset
+	}
+	record := l.data[offset+headerSize : next]
+	if l.checksum && binary.LittleEndian.Uint32(l.data[offset+recordHeaderSize:]) != crc32.Checksum(record, crcTable) {
+		return nil, 0, ErrChecksumMismatch
+	}
+	return record, next, nil
+}
+
+// Append writes record at the end of the log and returns the offset it was written at.
+func (l *Log) Append(record []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return 0, ErrLogClosed
+	}
+	return l.appendLocked(record)
+}
+
+func (l *Log) appendLocked(record []byte) (int, error) {
+	headerSize := l.headerSize()
+	if len(l.data)-l.offset < headerSize+len(record) {
+		return 0, ErrLogFull
+	}
+	offset := l.offset
+	copy(l.data[offset+headerSize:], record)
+	if l.checksum {
+		binary.LittleEndian.PutUint32(l.data[offset+recordHeaderSize:], crc32.Checksum(record, crcTable))
+	}
+	binary.LittleEndian.PutUint32(l.data[offset:], uint32(len(record)+1))
+	l.offset += headerSize + len(record)
+	return offset, nil
+}
+
+// ReadAt returns a copy of the record at offset and the offset of the next record.
+func (l *Log) ReadAt(offset int) ([]byte, int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return nil, 0, ErrLogClosed
+	}
+
+	data, next, err := l.recordAt(offset, l.offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	record := make([]byte, len(data))
+	copy(record, data)
+	return record, next, nil
+}
+
+// Size returns the number of bytes written to the log, which is also the offset of the next record.
+func (l *Log) Size() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(l.offset)
+}
+
+// Capacity returns the size of the underlying file, i.e. the maximum number of bytes the log can hold.
+// It returns 0 after Close.
+func (l *Log) Capacity() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(len(l.data))
+}
+
+// Sync flushes the records written so far to the underlying file.
+func (l *Log) Sync() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return ErrLogClosed
+	}
+	return l.syncLocked()
+}
+
+func (l *Log) syncLocked() error {
+	if err := mmap.MMap(l.data).Flush(); err != nil {
+		return fmt.Errorf("mmap log: sync: %w", err)
+	}
+	return nil
+}
+
+// WriteTo writes all records of the log to w in the same length-prefixed format,
+// so that they can be replayed with LoadFrom. It implements io.WriterTo.
+func (l *Log) WriteTo(w io.Writer) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return 0, ErrLogClosed
+	}
+
+	n, err := w.Write(l.data[:l.offset])
+	return int64(n), err
+}
+
+// LoadFrom replays the records read from r into the log, which must be empty.
+// On success the write offset points right after the last replayed record.
+// On error the records replayed so far are discarded and the log is left empty.
+func (l *Log) LoadFrom(r io.Reader) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrLogClosed
+	}
+	if l.offset != 0 {
+		return ErrLogNotEmpty
+	}
+	defer func() {
+		if err != nil {
+			clear(l.data[:l.offset])
+			l.offset = 0
+		}
+	}()
+
+	headerSize := l.headerSize()
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("mmap log: read header: %w", err)
+		}
+		length := binary.LittleEndian.Uint32(header)
+		if length == 0 {
+			return errors.New("mmap log: invalid record header")
+		}
+		// the length comes from the input, check it before allocating the record
+		if room := len(l.data) - l.offset - headerSize; room < 0 || uint64(length-1) > uint64(room) {
+			return ErrLogFull
+		}
+		record := make([]byte, length-1)
+		if _, err := io.ReadFull(r, record); err != nil {
+			return fmt.Errorf("mmap log: read record: %w", err)
+		}
+		if l.checksum && binary.LittleEndian.Uint32(header[recordHeaderSize:]) != crc32.Checksum(record, crcTable) {
+			return ErrChecksumMismatch
+		}
+		if _, err := l.appendLocked(record); err != nil {
+			return err
+		}
+	}
+}
+
+// Close syncs the log, then unmaps and closes the underlying file.
+// The file is closed even if the sync fails, both errors are returned joined.
+// Closing a closed log does nothing, the other methods return ErrLogClosed after Close.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	err := errors.Join(l.syncLocked(), l.closer.Close())
+	l.closed = true
+	l.data = nil
+	return err
+}
+
+var _ io.WriterTo = (*Log)(nil)
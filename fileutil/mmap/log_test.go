@@ -0,0 +1,218 @@
+package mmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLogWriteToLoadFrom(t *testing.T) {
+	dir := t.TempDir()
+	logger := zaptest.NewLogger(t)
+
+	src, err := OpenLog(filepath.Join(dir, "src.log"), 1024, logger)
+	require.NoError(t, err)
+	defer src.Close()
+
+	records := [][]byte{[]byte("first"), {}, []byte("third record")}
+	for _, record := range records {
+		_, err := src.Append(record)
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	n, err := src.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, src.Size(), n)
+
+	dst, err := OpenLog(filepath.Join(dir, "dst.log"), 1024, logger)
+	require.NoError(t, err)
+	defer dst.Close()
+
+	require.NoError(t, dst.LoadFrom(&buf))
+	assert.Equal(t, src.Size(), dst.Size())
+
+	offset := 0
+	for _, expected := range records {
+		record, next, err := dst.ReadAt(offset)
+		require.NoError(t, err)
+		assert.Equal(t, expected, record)
+		offset = next
+	}
+	_, _, err = dst.ReadAt(offset)
+	assert.ErrorIs(t, err, ErrInvalidOffset)
+
+	// new records are appended after the replayed ones
+	offset, err = dst.Append([]byte("fourth"))
+	require.NoError(t, err)
+	assert.Equal(t, src.Size(), int64(offset))
+
+	assert.ErrorIs(t, dst.LoadFrom(bytes.NewReader(nil)), ErrLogNotEmpty)
+}
+
+func TestLogLoadFromInvalid(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenLog(filepath.Join(dir, "dst.log"), 64, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer log.Close()
+
+	record := func(payload string) []byte {
+		return append(binary.LittleEndian.AppendUint32(nil, uint32(len(payload)+1)), payload...)
+	}
+	huge := binary.LittleEndian.AppendUint32(nil, 1<<32-1)
+
+	for name, input := range map[string][]byte{
+		"huge length":      append(record("first"), huge...),
+		"truncated record": append(record("first"), record("second")[:6]...),
+		"too large":        append(record("first"), record(string(make([]byte, 60)))...),
+	} {
+		// the length is checked before allocating, and the records loaded before the failure are rolled back
+		assert.Error(t, log.LoadFrom(bytes.NewReader(input)), name)
+		assert.Equal(t, int64(0), log.Size(), name)
+		_, _, err := log.ReadAt(0)
+		assert.ErrorIs(t, err, ErrInvalidOffset, name)
+	}
+
+	require.NoError(t, log.LoadFrom(bytes.NewReader(record("first"))))
+	assert.Equal(t, int64(recordHeaderSize+len("first")), log.Size())
+}
+
+func TestLogClose(t *testing.T) {
+	log, err := OpenLog(filepath.Join(t.TempDir(), "closed.log"), 64, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	_, err = log.Append([]byte("record"))
+	require.NoError(t, err)
+
+	require.NoError(t, log.Close())
+	require.NoError(t, log.Close())
+
+	_, err = log.Append([]byte("record"))
+	assert.ErrorIs(t, err, ErrLogClosed)
+	_, _, err = log.ReadAt(0)
+	assert.ErrorIs(t, err, ErrLogClosed)
+	_, err = log.WriteTo(&bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrLogClosed)
+	assert.ErrorIs(t, log.Sync(), ErrLogClosed)
+	assert.ErrorIs(t, log.LoadFrom(bytes.NewReader(nil)), ErrLogClosed)
+	assert.Equal(t, int64(0), log.Capacity())
+}
+
+func TestLogFull(t *testing.T) {
+	log, err := OpenLog(filepath.Join(t.TempDir(), "full.log"), 8, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer log.Close()
+
+	_, err = log.Append([]byte("1234"))
+	require.NoError(t, err)
+	_, err = log.Append([]byte("5"))
+	assert.ErrorIs(t, err, ErrLogFull)
+}
+
+func TestLogRecover(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "wal.log")
+	logger := zaptest.NewLogger(t)
+
+	log, err := OpenLog(filename, 1024, logger)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), log.Capacity())
+
+	records := [][]byte{[]byte("first"), {}, []byte("third record")}
+	for _, record := range records {
+		_, err := log.Append(record)
+		require.NoError(t, err)
+	}
+	require.NoError(t, log.Sync())
+
+	// reopen without closing, as after a crash
+	recovered, err := RecoverLog(filename, 1024, logger)
+	require.NoError(t, err)
+	assert.Equal(t, log.Size(), recovered.Size())
+
+	offset := 0
+	for _, expected := range records {
+		record, next, err := recovered.ReadAt(offset)
+		require.NoError(t, err)
+		assert.Equal(t, expected, record)
+		offset = next
+	}
+
+	require.NoError(t, log.Close())
+	require.NoError(t, recovered.Close())
+}
+
+func TestLogRecoverTruncatedRecord(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "wal.log")
+	logger := zaptest.NewLogger(t)
+
+	log, err := OpenLog(filename, 64, logger)
+	require.NoError(t, err)
+	_, err = log.Append([]byte("complete"))
+	require.NoError(t, err)
+	valid := log.Size()
+
+	// a length prefix pointing beyond the end of the file, as left by a torn append
+	binary.LittleEndian.PutUint32(log.data[valid:], 1024)
+	copy(log.data[valid+recordHeaderSize:], "partial")
+	require.NoError(t, log.Close())
+
+	recovered, err := RecoverLog(filename, 64, logger)
+	require.NoError(t, err)
+	defer recovered.Close()
+	assert.Equal(t, valid, recovered.Size())
+
+	// a shorter record written over the torn one is followed by the end of the log
+	_, err = recovered.Append([]byte("x"))
+	require.NoError(t, err)
+	size := recovered.Size()
+	require.NoError(t, recovered.Sync())
+
+	again, err := RecoverLog(filename, 64, logger)
+	require.NoError(t, err)
+	defer again.Close()
+	assert.Equal(t, size, again.Size())
+}
+
+func TestLogRecoverChecksum(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "wal.log")
+	logger := zaptest.NewLogger(t)
+
+	log, err := OpenLog(filename, 1024, logger, WithChecksum())
+	require.NoError(t, err)
+	_, err = log.Append([]byte("first"))
+	require.NoError(t, err)
+	valid := log.Size()
+	offset, err := log.Append([]byte("second"))
+	require.NoError(t, err)
+
+	// the payload of the last record did not fully reach the file
+	log.data[offset+recordHeaderSize+checksumSize+3] = 0
+	_, _, err = log.ReadAt(offset)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	require.NoError(t, log.Close())
+
+	recovered, err := RecoverLog(filename, 1024, logger, WithChecksum())
+	require.NoError(t, err)
+	defer recovered.Close()
+	assert.Equal(t, valid, recovered.Size())
+
+	record, next, err := recovered.ReadAt(0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), record)
+	_, _, err = recovered.ReadAt(next)
+	assert.ErrorIs(t, err, ErrInvalidOffset)
+
+	// records are replayed with their checksums
+	var buf bytes.Buffer
+	_, err = recovered.WriteTo(&buf)
+	require.NoError(t, err)
+	dst, err := OpenLog(filepath.Join(t.TempDir(), "dst.log"), 1024, logger, WithChecksum())
+	require.NoError(t, err)
+	defer dst.Close()
+	require.NoError(t, dst.LoadFrom(&buf))
+	assert.Equal(t, valid, dst.Size())
+}
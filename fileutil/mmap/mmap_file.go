@@ -29,8 +29,52 @@ func (m *MMappedFile) Close() error {
 	return err
 }
 
+// defaultPerm is the permission of files created by GetMMappedFile, before the umask.
+const defaultPerm os.FileMode = 0o666
+
+// Options controls how GetMMappedFileWithOptions creates the underlying file.
+// The zero value creates the file like GetMMappedFile without a logger.
+type Options struct {
+	// Perm is the permission used when the file is created, before the umask.
+	// It defaults to 0o666 when zero and is ignored for an existing file.
+	Perm os.FileMode
+	// Exclusive makes the call fail if the file already exists, so that
+	// only one caller can ever be the creator of the file.
+	Exclusive bool
+	// Keep preserves the contents of an existing file instead of truncating it,
+	// the file is still resized to filesize.
+	Keep bool
+	// Logger is used to report failures, defaults to a no-op logger.
+	Logger *zap.Logger
+}
+
 func GetMMappedFile(filename string, filesize int, logger *zap.Logger) ([]byte, io.Closer, error) {
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o666)
+	return GetMMappedFileWithOptions(filename, filesize, Options{Logger: logger})
+}
+
+// GetMMappedFileWithOptions opens filename for reading and writing, creating
+// it if needed, resizes it to filesize and maps it into memory. By default an
+// existing file is truncated first, see Options for how the file is created.
+// It returns the mapped bytes and a closer that unmaps and closes the file.
+func GetMMappedFileWithOptions(filename string, filesize int, opts Options) ([]byte, io.Closer, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	perm := opts.Perm
+	if perm == 0 {
+		perm = defaultPerm
+	}
+
+	flag := os.O_CREATE | os.O_RDWR
+	if !opts.Keep {
+		flag |= os.O_TRUNC
+	}
+	if opts.Exclusive {
+		flag |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(filename, flag, perm)
 	if err != nil {
 		absPath, pathErr := filepath.Abs(filename)
 		if pathErr != nil {
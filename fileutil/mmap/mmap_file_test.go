@@ -0,0 +1,51 @@
+package mmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMMappedFileWithOptionsExclusive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "exclusive")
+
+	data, closer, err := GetMMappedFileWithOptions(filename, 16, Options{Perm: 0o600, Exclusive: true})
+	require.NoError(t, err)
+	defer closer.Close()
+	assert.Len(t, data, 16)
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, _, err = GetMMappedFileWithOptions(filename, 16, Options{Perm: 0o600, Exclusive: true})
+	assert.ErrorIs(t, err, os.ErrExist)
+
+	// non-exclusive creation still succeeds on an existing file
+	_, closer2, err := GetMMappedFileWithOptions(filename, 16, Options{Perm: 0o600})
+	require.NoError(t, err)
+	require.NoError(t, closer2.Close())
+}
+
+func TestGetMMappedFileWithOptionsDefaultPerm(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "default")
+
+	_, closer, err := GetMMappedFileWithOptions(filename, 16, Options{})
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+
+	// os.Create also uses 0o666 before the umask
+	want, err := os.Create(filepath.Join(dir, "reference"))
+	require.NoError(t, err)
+	require.NoError(t, want.Close())
+	wantInfo, err := os.Stat(want.Name())
+	require.NoError(t, err)
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, wantInfo.Mode().Perm(), info.Mode().Perm())
+}
@@ -1,6 +1,15 @@
 package gate
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/limit/ratelimit"
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+// maxBackoffShift caps the exponential growth of the StartWithBackoff delay at base << maxBackoffShift.
+const maxBackoffShift = 5
 
 // A Gate controls the maximum number of concurrent running and waiting queries.
 type Gate struct {
@@ -24,6 +33,38 @@ func (g *Gate) Start(ctx context.Context) error {
 	}
 }
 
+// StartWithBackoff is like Start, but when the gate is full it does not wait for the next free spot.
+// Instead it retries after a jittered, exponentially growing delay starting at base, measured with clk,
+// so that a burst of callers hitting a full gate does not retry all at once.
+// A base <= 0 behaves like Start.
+func (g *Gate) StartWithBackoff(ctx context.Context, base time.Duration, clk clock.Clock) error {
+	if base <= 0 {
+		return g.Start(ctx)
+	}
+	delays := newBackoff(base)
+	for {
+		select {
+		case g.sem <- struct{}{}:
+			return nil
+		default:
+		}
+
+		timer := clk.Timer(delays.Next())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// newBackoff returns the delays of StartWithBackoff: a random delay in [d/2, d]
+// with d = base << attempt, capped at maxBackoffShift.
+func newBackoff(base time.Duration) *ratelimit.BackoffIterator {
+	return ratelimit.NewBackoffIterator(base, base<<maxBackoffShift, 2, true)
+}
+
 // Done releases a single spot int the gate.
 func (g *Gate) Done() {
 	<-g.sem
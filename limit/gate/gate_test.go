@@ -0,0 +1,102 @@
+package gate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/timer/clock"
+)
+
+func TestBackoff(t *testing.T) {
+	base := 10 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		delays := newBackoff(base)
+		for attempt := 0; attempt < 10; attempt++ {
+			d := base << min(attempt, maxBackoffShift)
+			if got := delays.Next(); got < d/2 || got > d {
+				t.Fatalf("attempt %d: backoff %v, want in [%v, %v]", attempt, got, d/2, d)
+			}
+		}
+	}
+}
+
+// timerClock reports the duration of every timer created through it
+type timerClock struct {
+	*clock.Mock
+	timers chan time.Duration
+}
+
+func (c timerClock) Timer(d time.Duration) *clock.Timer {
+	timer := c.Mock.Timer(d)
+	c.timers <- d
+	return timer
+}
+
+func TestStartWithBackoff(t *testing.T) {
+	g := New(1)
+	clk := timerClock{Mock: clock.NewMock(), timers: make(chan time.Duration, 1)}
+	if err := g.StartWithBackoff(context.Background(), time.Second, clk); err != nil {
+		t.Fatalf("StartWithBackoff on free gate: %v", err)
+	}
+
+	started := make(chan error, 1)
+	go func() {
+		started <- g.StartWithBackoff(context.Background(), time.Second, clk)
+	}()
+
+	// the delay grows with every attempt
+	first := <-clk.timers
+	if first < 500*time.Millisecond || first > time.Second {
+		t.Fatalf("first backoff %v, want in [500ms, 1s]", first)
+	}
+	clk.Add(first)
+	second := <-clk.timers
+	if second < time.Second || second > 2*time.Second {
+		t.Fatalf("second backoff %v, want in [1s, 2s]", second)
+	}
+
+	// the spot released during the backoff is only taken on the next attempt
+	g.Done()
+	select {
+	case err := <-started:
+		t.Fatalf("StartWithBackoff returned %v before the backoff elapsed", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clk.Add(second)
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("StartWithBackoff: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartWithBackoff did not retry after the backoff")
+	}
+	if len(g.sem) != 1 {
+		t.Fatalf("expected the spot to be taken, got %d", len(g.sem))
+	}
+}
+
+func TestStartWithBackoffCancel(t *testing.T) {
+	g := New(1)
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() {
+		started <- g.StartWithBackoff(ctx, time.Hour, clock.NewMock())
+	}()
+	cancel()
+
+	select {
+	case err := <-started:
+		if err != context.Canceled {
+			t.Fatalf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartWithBackoff did not return after cancel")
+	}
+}
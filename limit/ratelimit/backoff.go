@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffIterator produces the delays of successive retries: base, base*factor,
+// base*factor^2, ... capped at max. With jitter enabled every delay d is
+// replaced by a random delay in [d/2, d], so that callers failing at the same
+// time do not retry at the same time.
+//
+// A BackoffIterator is not safe for concurrent use, every retry loop should
+// own its iterator.
+type BackoffIterator struct {
+	base    time.Duration
+	max     time.Duration
+	factor  float64
+	jitter  bool
+	rand    *rand.Rand
+	current time.Duration
+}
+
+// BackoffOption configures a BackoffIterator.
+type BackoffOption func(*BackoffIterator)
+
+// WithRandSource returns an option for NewBackoffIterator that draws the
+// jitter from src instead of the global random source, typically a seeded
+// source for reproducible delays in tests.
+func WithRandSource(src rand.Source) BackoffOption {
+	return func(b *BackoffIterator) {
+		b.rand = rand.New(src)
+	}
+}
+
+// NewBackoffIterator returns an iterator whose delays start at base and grow
+// by factor up to max. A factor below 1 is treated as 1 and a max below base
+// as base.
+func NewBackoffIterator(base, max time.Duration, factor float64, jitter bool, opts ...BackoffOption) *BackoffIterator {
+	if base < 0 {
+		base = 0
+	}
+	if max < base {
+		max = base
+	}
+	if factor < 1 {
+		factor = 1
+	}
+	b := &BackoffIterator{
+		base:    base,
+		max:     max,
+		factor:  factor,
+		jitter:  jitter,
+		current: base,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Next returns the delay before the next retry and advances the iterator.
+func (b *BackoffIterator) Next() time.Duration {
+	d := b.current
+	// grow in float64 and compare before converting back, so that a large
+	// factor saturates at max instead of overflowing time.Duration
+	if next := float64(b.current) * b.factor; next < float64(b.max) {
+		b.current = time.Duration(next)
+	} else {
+		b.current = b.max
+	}
+	if !b.jitter || d <= 0 {
+		return d
+	}
+	return d/2 + b.randN(d/2+1)
+}
+
+// Reset restarts the iterator at base, typically after a successful attempt.
+func (b *BackoffIterator) Reset() {
+	b.current = b.base
+}
+
+// randN returns a random duration in [0, n).
+func (b *BackoffIterator) randN(n time.Duration) time.Duration {
+	if b.rand == nil {
+		return rand.N(n)
+	}
+	return time.Duration(b.rand.Int64N(int64(n)))
+}
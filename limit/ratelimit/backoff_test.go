@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffIteratorCapsAtMax(t *testing.T) {
+	t.Parallel()
+
+	b := NewBackoffIterator(10*time.Millisecond, time.Second, 3, false)
+
+	want := []time.Duration{
+		10 * time.Millisecond,
+		30 * time.Millisecond,
+		90 * time.Millisecond,
+		270 * time.Millisecond,
+		810 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		assert.Equal(t, w, b.Next(), "attempt %d", i)
+	}
+
+	b.Reset()
+	assert.Equal(t, 10*time.Millisecond, b.Next(), "after reset")
+}
+
+func TestBackoffIteratorDoesNotOverflow(t *testing.T) {
+	t.Parallel()
+
+	b := NewBackoffIterator(time.Hour, 24*time.Hour, 1e12, false)
+
+	assert.Equal(t, time.Hour, b.Next())
+	assert.Equal(t, 24*time.Hour, b.Next())
+	assert.Equal(t, 24*time.Hour, b.Next())
+}
+
+func TestBackoffIteratorJitter(t *testing.T) {
+	t.Parallel()
+
+	newIterator := func() *BackoffIterator {
+		return NewBackoffIterator(10*time.Millisecond, time.Second, 2, true,
+			WithRandSource(rand.NewPCG(1, 2)))
+	}
+	a, b := newIterator(), newIterator()
+	plain := NewBackoffIterator(10*time.Millisecond, time.Second, 2, false)
+
+	for i := 0; i < 20; i++ {
+		d := plain.Next()
+		got := a.Next()
+		assert.Equal(t, got, b.Next(), "attempt %d: same seed must give the same delay", i)
+		assert.GreaterOrEqual(t, got, d/2, "attempt %d", i)
+		assert.LessOrEqual(t, got, d, "attempt %d", i)
+	}
+}
@@ -76,16 +76,8 @@ func (t *atomicLimiter) Take() time.Time {
 
 		// 计算需要睡眠的时间：基于每个请求的预算时间和上次请求到现在的时间差
 		// 因为请求可能比预算时间长，这个数值可能是负数，并且会在请求间累加
-		newState.sleepFor += t.perRequest - now.Sub(oldState.last)
-
-		// 不应让 sleepFor 变得太消极，因为这意味着服务在短时间内大幅减速后会获得更高的 RPS
-		// 有一次请求间隔超长之后会补偿slack个个数
-		// 1. 上次请求间隔时间超过 perRequest 会导致sleepFor < 0，但是如果不是一次超过 slack次就不会触发最大补偿个数修正
-		// 比如超时时间间隔是两次，那么后面会每次都修正sleepFor 直到sleepFor > 0为止
-		//2. 如果sleepFor < t.maxSlack 会触发最大补偿个数修正，最多允许补偿slack个数
-		if newState.sleepFor < t.maxSlack {
-			newState.sleepFor = t.maxSlack
-		}
+		// 有一次请求间隔超长之后最多补偿 slack 个请求，见 accumulateSleep
+		newState.sleepFor = accumulateSleep(newState.sleepFor, t.perRequest, now.Sub(oldState.last), t.maxSlack)
 
 		// 如果需要睡眠，则调整最后时间和间隔
 		if newState.sleepFor > 0 {
@@ -45,14 +45,9 @@ func (t *mutexLimiter) Take() time.Time {
 	}
 
 	// 计算需要睡眠的时间：基于每个请求的预算时间和上次请求到现在的时间差
-	// 因为请求可能比预算时间长，这个数值可能是负数，并且会在请求间累加
-	t.sleepFor += t.perRequest - now.Sub(t.last)
-
-	// 不应让 sleepFor 变得太负，因为这意味着服务在短时间内大幅减速后会获得更高的 RPS
-	// maxSlack 是负数，用于限制 sleepFor 的最小值
-	if t.sleepFor < t.maxSlack {
-		t.sleepFor = t.maxSlack
-	}
+	// 因为请求可能比预算时间长，这个数值可能是负数，并且会在请求间累加，
+	// 但不会低于 maxSlack，否则服务在短时间内大幅减速后会获得更高的 RPS
+	t.sleepFor = accumulateSleep(t.sleepFor, t.perRequest, now.Sub(t.last), t.maxSlack)
 
 	// 如果需要睡眠，则执行睡眠
 	if t.sleepFor > 0 {
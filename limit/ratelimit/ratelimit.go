@@ -37,6 +37,18 @@ func New(rate int, opts ...Option) Limiter {
 	return newAtomicInt64Based(rate, opts...)
 }
 
+// accumulateSleep is the leaky-bucket step shared by the mutex and atomic
+// limiters: it adds the budget of one request minus the time elapsed since the
+// previous one to sleepFor, and clamps the result at maxSlack (which is
+// negative) so that a long pause earns at most slack requests of burst.
+func accumulateSleep(sleepFor, perRequest, elapsed, maxSlack time.Duration) time.Duration {
+	sleepFor += perRequest - elapsed
+	if sleepFor < maxSlack {
+		sleepFor = maxSlack
+	}
+	return sleepFor
+}
+
 // buildConfig combines defaults with options
 func buildConfig(opts []Option) config {
 	c := config{
@@ -412,3 +412,42 @@ func TestSlack(t *testing.T) {
 		})
 	}
 }
+
+// sleepClock is a Clock whose Sleep advances Now instantly, so a single
+// goroutine can Take many times and observe the total throttling delay.
+type sleepClock struct {
+	now time.Time
+}
+
+func (c *sleepClock) Now() time.Time        { return c.now }
+func (c *sleepClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestTakeEnforcesRate(t *testing.T) {
+	constructors := map[string]func(int, ...Option) Limiter{
+		"mutex":        func(rate int, opts ...Option) Limiter { return newMutexBased(rate, opts...) },
+		"atomic":       func(rate int, opts ...Option) Limiter { return newAtomicBased(rate, opts...) },
+		"atomic_int64": func(rate int, opts ...Option) Limiter { return newAtomicInt64Based(rate, opts...) },
+	}
+	const (
+		rate = 100
+		n    = 50
+	)
+	perRequest := time.Second / rate
+
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			clk := &sleepClock{now: time.Now()}
+			rl := constructor(rate, WithClock(clk))
+
+			start := clk.Now()
+			var last time.Time
+			for i := 0; i < n; i++ {
+				last = rl.Take()
+			}
+
+			elapsed := clk.Now().Sub(start)
+			assert.GreaterOrEqual(t, elapsed, (n-1)*perRequest, "limiter did not sleep between requests")
+			assert.True(t, clk.Now().Equal(last), "Take must return the time the request was let through")
+		})
+	}
+}
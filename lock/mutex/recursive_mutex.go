@@ -2,22 +2,47 @@ package mutex
 
 import (
 	"fmt"
+	"log/slog"
+	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/andrewbytecoder/gokit/sys/goid"
 )
 
 // RecursiveMutex 包装一个Mutex，实现可重入
+//
+// 拥有者以goid解析出的goroutine id标识，正确性依赖该id在goroutine之间唯一。
+// goid解析失败返回0时 Lock 会panic；如果goid对不同goroutine返回相同的非0 id，
+// 另一个goroutine会被当作拥有者重入，这种情况无法检测
 type RecursiveMutex struct {
 	sync.Mutex       // 嵌入Mutex以提供基础互斥功能
 	owner      int64 // 当前持有锁的goroutine的id
 	recursion  int32 // 当前goroutine的重入次数
+
+	// DeadlockTimeout 大于0时开启调试模式：Lock 等待超过该时长仍未获取到锁时，
+	// 输出当前持有锁的goroutine id及其获取锁时的调用栈，为0时不改变加锁行为
+	DeadlockTimeout time.Duration
+	// Logger 调试模式下用于输出诊断信息，为nil时使用 slog.Default()
+	Logger *slog.Logger
+
+	ownerStack atomic.Value // 调试模式下持有者获取锁时的调用栈
 }
 
+// goroutineId 获取当前goroutine ID，测试中可替换以模拟goid解析错误
+var goroutineId = goid.GoroutineId
+
+// invalidGoroutineId goid解析失败时的panic信息
+const invalidGoroutineId = "recursive mutex: goroutine id could not be determined"
+
 // Lock 获取锁，支持同goroutine多次获取（重入）
 func (m *RecursiveMutex) Lock() {
-	g := goid.GoroutineId() // 获取当前goroutine ID
+	g := goroutineId() // 获取当前goroutine ID
+	if g == 0 {
+		// goroutine id 从1开始，为0说明解析失败，继续执行会把所有解析失败的goroutine当作同一个拥有者
+		panic(invalidGoroutineId)
+	}
 
 	// 如果当前锁的拥有者是当前goroutine，说明是重入
 	if atomic.LoadInt64(&m.owner) == int64(g) {
@@ -26,16 +51,44 @@ func (m *RecursiveMutex) Lock() {
 	}
 
 	// 首次获取锁，调用底层Mutex.Lock()
-	m.Mutex.Lock()
+	if m.DeadlockTimeout > 0 {
+		// 调试模式下启动看门狗，等待超时后输出持有者信息
+		watchdog := time.AfterFunc(m.DeadlockTimeout, func() { m.reportContention(g) })
+		m.Mutex.Lock()
+		watchdog.Stop()
+		m.ownerStack.Store(stack())
+	} else {
+		m.Mutex.Lock()
+	}
 
 	// 记录锁的拥有者为当前goroutine，并初始化重入计数为1
 	atomic.StoreInt64(&m.owner, int64(g))
 	m.recursion = 1
 }
 
+// reportContention 输出等待超时的goroutine以及当前持有者的信息
+func (m *RecursiveMutex) reportContention(waiter uint64) {
+	logger := m.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ownerStack, _ := m.ownerStack.Load().(string)
+	logger.Warn("recursive mutex lock wait exceeded timeout",
+		"waiter", waiter,
+		"timeout", m.DeadlockTimeout,
+		"owner", atomic.LoadInt64(&m.owner),
+		"ownerStack", ownerStack)
+}
+
+// stack 返回当前goroutine的调用栈
+func stack() string {
+	buf := make([]byte, 4096)
+	return string(buf[:runtime.Stack(buf, false)])
+}
+
 // Unlock 释放锁，只有当递归计数归零时才真正释放
 func (m *RecursiveMutex) Unlock() {
-	g := goid.GoroutineId() // 获取当前goroutine ID
+	g := goroutineId() // 获取当前goroutine ID
 
 	// 检查解锁操作是否由锁的拥有者执行
 	if atomic.LoadInt64(&m.owner) != int64(g) {
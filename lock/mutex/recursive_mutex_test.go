@@ -0,0 +1,104 @@
+package mutex
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrewbytecoder/gokit/sys/goid"
+)
+
+// syncBuffer 并发安全的 bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRecursiveMutexReentrant(t *testing.T) {
+	var m RecursiveMutex
+	m.Lock()
+	m.Lock()
+	m.Unlock()
+	m.Unlock()
+
+	locked := make(chan struct{})
+	go func() {
+		m.Lock()
+		m.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("lock was not released")
+	}
+}
+
+func TestRecursiveMutexDeadlockWatchdog(t *testing.T) {
+	var out syncBuffer
+	m := &RecursiveMutex{
+		DeadlockTimeout: 10 * time.Millisecond,
+		Logger:          slog.New(slog.NewTextHandler(&out, nil)),
+	}
+
+	m.Lock()
+	owner := goid.GoroutineId()
+
+	locked := make(chan struct{})
+	go func() {
+		m.Lock()
+		m.Unlock()
+		close(locked)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	m.Unlock()
+	<-locked
+
+	log := out.String()
+	if !strings.Contains(log, "owner="+strconv.FormatUint(owner, 10)) {
+		t.Fatalf("expected owner goroutine id in log, got: %s", log)
+	}
+	if !strings.Contains(log, "TestRecursiveMutexDeadlockWatchdog") {
+		t.Fatalf("expected owner acquisition stack in log, got: %s", log)
+	}
+}
+
+// setGoroutineId 在测试期间替换goid函数
+func setGoroutineId(t *testing.T, fn func() uint64) {
+	prev := goroutineId
+	goroutineId = fn
+	t.Cleanup(func() { goroutineId = prev })
+}
+
+// recoverPanic 执行fn并返回其panic信息
+func recoverPanic(fn func()) (r interface{}) {
+	defer func() { r = recover() }()
+	fn()
+	return nil
+}
+
+func TestRecursiveMutexZeroGoroutineId(t *testing.T) {
+	setGoroutineId(t, func() uint64 { return 0 })
+
+	var m RecursiveMutex
+	if r := recoverPanic(m.Lock); r != invalidGoroutineId {
+		t.Fatalf("expected invalid goroutine id panic, got: %v", r)
+	}
+}
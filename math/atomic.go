@@ -0,0 +1,50 @@
+package math
+
+import "sync/atomic"
+
+// signBit 翻转符号位后，int64 的大小顺序与 uint64 的大小顺序一致
+const signBit = 1 << 63
+
+// AtomicMaxInt64 无锁记录运行过程中的最大值，零值可直接使用
+// 内部存储 uint64(v) ^ signBit，使零值对应 math.MinInt64，第一次 Update 一定生效
+type AtomicMaxInt64 struct {
+	v atomic.Uint64
+}
+
+// Update 若 v 大于当前最大值则将其记录为最大值
+func (m *AtomicMaxInt64) Update(v int64) {
+	n := uint64(v) ^ signBit
+	for {
+		old := m.v.Load()
+		if n <= old || m.v.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+// Value 返回当前最大值，没有调用过 Update 时返回 math.MinInt64
+func (m *AtomicMaxInt64) Value() int64 {
+	return int64(m.v.Load() ^ signBit)
+}
+
+// AtomicMinInt64 无锁记录运行过程中的最小值，零值可直接使用
+// 内部存储 ^(uint64(v) ^ signBit)，使零值对应 math.MaxInt64，第一次 Update 一定生效
+type AtomicMinInt64 struct {
+	v atomic.Uint64
+}
+
+// Update 若 v 小于当前最小值则将其记录为最小值
+func (m *AtomicMinInt64) Update(v int64) {
+	n := ^(uint64(v) ^ signBit)
+	for {
+		old := m.v.Load()
+		if n <= old || m.v.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+// Value 返回当前最小值，没有调用过 Update 时返回 math.MaxInt64
+func (m *AtomicMinInt64) Value() int64 {
+	return int64(^m.v.Load() ^ signBit)
+}
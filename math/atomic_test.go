@@ -0,0 +1,79 @@
+package math
+
+import (
+	stdmath "math"
+	"sync"
+	"testing"
+)
+
+func TestAtomicMinMaxZeroValue(t *testing.T) {
+	var maxValue AtomicMaxInt64
+	var minValue AtomicMinInt64
+	if got := maxValue.Value(); got != stdmath.MinInt64 {
+		t.Fatalf("zero AtomicMaxInt64.Value() = %d, want %d", got, int64(stdmath.MinInt64))
+	}
+	if got := minValue.Value(); got != stdmath.MaxInt64 {
+		t.Fatalf("zero AtomicMinInt64.Value() = %d, want %d", got, int64(stdmath.MaxInt64))
+	}
+
+	// 只有负数时最大值也应为负数，只有正数时最小值也应为正数
+	maxValue.Update(-5)
+	maxValue.Update(-7)
+	minValue.Update(5)
+	minValue.Update(7)
+	if got := maxValue.Value(); got != -5 {
+		t.Fatalf("AtomicMaxInt64.Value() = %d, want -5", got)
+	}
+	if got := minValue.Value(); got != 5 {
+		t.Fatalf("AtomicMinInt64.Value() = %d, want 5", got)
+	}
+
+	maxValue.Update(stdmath.MaxInt64)
+	minValue.Update(stdmath.MinInt64)
+	if got := maxValue.Value(); got != stdmath.MaxInt64 {
+		t.Fatalf("AtomicMaxInt64.Value() = %d, want %d", got, int64(stdmath.MaxInt64))
+	}
+	if got := minValue.Value(); got != stdmath.MinInt64 {
+		t.Fatalf("AtomicMinInt64.Value() = %d, want %d", got, int64(stdmath.MinInt64))
+	}
+}
+
+func TestAtomicMinMaxConcurrent(t *testing.T) {
+	var maxValue AtomicMaxInt64
+	var minValue AtomicMinInt64
+
+	const goroutines, perGoroutine = 16, 1000
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				// 每个 goroutine 更新不同的值区间，最值分别由不同的 goroutine 产生
+				v := int64(g*perGoroutine+i) - goroutines*perGoroutine/2
+				maxValue.Update(v)
+				minValue.Update(v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := maxValue.Value(), int64(goroutines*perGoroutine/2-1); got != want {
+		t.Fatalf("AtomicMaxInt64.Value() = %d, want %d", got, want)
+	}
+	if got, want := minValue.Value(), int64(-goroutines*perGoroutine/2); got != want {
+		t.Fatalf("AtomicMinInt64.Value() = %d, want %d", got, want)
+	}
+}
+
+func BenchmarkAtomicMaxInt64(b *testing.B) {
+	var maxValue AtomicMaxInt64
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var v int64
+		for pb.Next() {
+			maxValue.Update(v)
+			v++
+		}
+	})
+}
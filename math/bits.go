@@ -25,6 +25,25 @@ func IsPowerOfTwo[T Integer](n T) bool {
 	return n > 0 && (n&(n-1)) == 0
 }
 
+// NextPowerOfTwo 返回大于等于n的最小的2的幂次方
+// 参数:
+//
+//	n: 整数，小于等于1时返回1
+//
+// 返回值:
+//
+//	T: 大于等于n的最小的2的幂次方，T 无法表示该值（n 大于 T 能表示的最大的2的幂次方）时返回0
+func NextPowerOfTwo[T Integer](n T) T {
+	p := T(1)
+	for p < n {
+		p <<= 1
+		if p <= 0 { // 无符号类型溢出为0，有符号类型溢出为负数
+			return 0
+		}
+	}
+	return p
+}
+
 //func IsPowerOfTwo32(n int) bool {
 //	return n > 0 && (n&(n-1)) == 0
 //}
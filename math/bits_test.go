@@ -0,0 +1,33 @@
+package math
+
+import (
+	stdmath "math"
+	"testing"
+)
+
+func TestNextPowerOfTwo(t *testing.T) {
+	for _, tc := range []struct{ n, want int }{
+		{n: -1, want: 1}, {n: 0, want: 1}, {n: 1, want: 1}, {n: 3, want: 4}, {n: 64, want: 64}, {n: 100, want: 128},
+	} {
+		if got := NextPowerOfTwo(tc.n); got != tc.want {
+			t.Fatalf("NextPowerOfTwo(%d) = %d, want %d", tc.n, got, tc.want)
+		}
+	}
+
+	// 结果超出类型范围时返回0，而不是无限循环
+	if got := NextPowerOfTwo(int8(64)); got != 64 {
+		t.Fatalf("NextPowerOfTwo(int8(64)) = %d, want 64", got)
+	}
+	if got := NextPowerOfTwo(int8(65)); got != 0 {
+		t.Fatalf("NextPowerOfTwo(int8(65)) = %d, want 0", got)
+	}
+	if got := NextPowerOfTwo(uint8(200)); got != 0 {
+		t.Fatalf("NextPowerOfTwo(uint8(200)) = %d, want 0", got)
+	}
+	if got := NextPowerOfTwo(stdmath.MaxInt); got != 0 {
+		t.Fatalf("NextPowerOfTwo(MaxInt) = %d, want 0", got)
+	}
+	if got := NextPowerOfTwo(uint64(1<<63 + 1)); got != 0 {
+		t.Fatalf("NextPowerOfTwo(1<<63+1) = %d, want 0", got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net"
 	"net/http"
 	"strings"
+
+	"github.com/andrewbytecoder/gokit/network/ip"
 )
 
 // xForwardedFor 定义了 HTTP 请求头 X-Forwarded-For 的名称，用于获取客户端真实 IP 地址
@@ -94,14 +96,9 @@ func filterFlags(content string) string {
 	return content
 }
 
-// StringToLong 将 IPv4 地址字符串转换为无符号整数表示形式
-func StringToLong(ip string) (uint, error) {
-	b := net.ParseIP(ip).To4()
-	if b == nil {
-		return 0, errors.New("invalid ipv4 format")
-	}
-
-	return uint(b[3]) | uint(b[2])<<8 | uint(b[1])<<16 | uint(b[0])<<24, nil
+// StringToLong 将 IPv4 地址字符串转换为无符号整数表示形式，支持 IPv4 映射的 IPv6 地址（如 ::ffff:192.0.2.1）
+func StringToLong(s string) (uint, error) {
+	return ToLong(net.ParseIP(s))
 }
 
 // LongToIPString 将无符号整数表示的 IPv4 地址转换为标准的点分十进制字符串格式
@@ -118,10 +115,10 @@ func LongToIPString(i uint) (string, error) {
 	return ip.String(), nil
 }
 
-// ToLong 将 net.IP 类型的 IPv4 地址转换为无符号整数表示形式
-func ToLong(ip net.IP) (uint, error) {
-	b := ip.To4()
-	if b == nil {
+// ToLong 将 net.IP 类型的 IPv4 地址转换为无符号整数表示形式，支持 IPv4 映射的 IPv6 地址
+func ToLong(addr net.IP) (uint, error) {
+	b := ip.Normalize(addr)
+	if len(b) != net.IPv4len {
 		return 0, errors.New("invalid ipv4 format")
 	}
 	return uint(b[3]) | uint(b[2])<<8 | uint(b[1])<<16 | uint(b[0])<<24, nil
@@ -286,3 +286,29 @@ func TestToLongAndLongToIP(t *testing.T) {
 		t.Errorf("LongToIP() = %v, want %v", ipBack.String(), ipStr)
 	}
 }
+
+func TestToLongIPv4Mapped(t *testing.T) {
+	long, err := StringToLong("::ffff:192.0.2.1")
+	if err != nil {
+		t.Fatalf("StringToLong() error = %v", err)
+	}
+	if long != 3221225985 {
+		t.Errorf("StringToLong() = %v, want %v", long, 3221225985)
+	}
+
+	long, err = ToLong(net.ParseIP("::ffff:192.0.2.1"))
+	if err != nil {
+		t.Fatalf("ToLong() error = %v", err)
+	}
+	if long != 3221225985 {
+		t.Errorf("ToLong() = %v, want %v", long, 3221225985)
+	}
+
+	ipBack, err := LongToIP(long)
+	if err != nil {
+		t.Fatalf("LongToIP() error = %v", err)
+	}
+	if ipBack.String() != "192.0.2.1" {
+		t.Errorf("LongToIP() = %v, want %v", ipBack.String(), "192.0.2.1")
+	}
+}
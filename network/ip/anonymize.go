@@ -0,0 +1,35 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+)
+
+var (
+	// anonymizeMaskV4 保留 IPv4 地址的前 24 位
+	anonymizeMaskV4 = net.CIDRMask(24, 8*net.IPv4len)
+	// anonymizeMaskV6 保留 IPv6 地址的前 48 位
+	anonymizeMaskV6 = net.CIDRMask(48, 8*net.IPv6len)
+)
+
+// Anonymize 按常见的统计分析约定抹去地址的主机部分，用于满足隐私合规的日志记录：
+// IPv4（包括 IPv4 映射地址）保留 /24，即最后一个字节置零；IPv6 保留 /48，即最后 80 位置零
+// 返回新的地址，不修改 ip；ip 无效时返回 nil
+func Anonymize(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(anonymizeMaskV4)
+	}
+	if len(ip) == net.IPv6len {
+		return ip.Mask(anonymizeMaskV6)
+	}
+	return nil
+}
+
+// AnonymizeString 解析字符串形式的地址并返回 Anonymize 之后的字符串形式
+func AnonymizeString(s string) (string, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return "", fmt.Errorf("invalid ip address %q", s)
+	}
+	return Anonymize(ip).String(), nil
+}
@@ -0,0 +1,66 @@
+package ip
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   net.IP
+		want string
+	}{
+		{"IPv4", net.ParseIP("192.0.2.123"), "192.0.2.0"},
+		{"4 字节 IPv4", net.IPv4(198, 51, 100, 7).To4(), "198.51.100.0"},
+		{"IPv4 映射地址", net.ParseIP("::ffff:203.0.113.99"), "203.0.113.0"},
+		{"IPv6", net.ParseIP("2001:db8:abcd:1234:5678:9abc:def0:1234"), "2001:db8:abcd::"},
+		{"回环 IPv6", net.ParseIP("::1"), "::"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.ip.String()
+			if got := Anonymize(tt.ip); got.String() != tt.want {
+				t.Errorf("Anonymize() = %v, want %v", got, tt.want)
+			}
+			if tt.ip.String() != original {
+				t.Errorf("Anonymize() modified its argument to %v", tt.ip)
+			}
+		})
+	}
+
+	if got := Anonymize(nil); got != nil {
+		t.Errorf("Anonymize(nil) = %v, want nil", got)
+	}
+	if got := Anonymize(net.IP{1, 2, 3}); got != nil {
+		t.Errorf("Anonymize(invalid) = %v, want nil", got)
+	}
+}
+
+func TestAnonymizeString(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"IPv4", "192.0.2.123", "192.0.2.0", false},
+		{"IPv6", "2001:db8:abcd:1234::1", "2001:db8:abcd::", false},
+		{"无效地址", "not an ip", "", true},
+		{"带端口", "192.0.2.1:80", "", true},
+		{"空字符串", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AnonymizeString(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AnonymizeString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AnonymizeString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package ip
+
+import "net"
+
+// Normalize 将 IPv4 映射的 IPv6 地址（如 ::ffff:192.0.2.1）转换为 4 字节的 IPv4 地址，
+// 其他地址原样返回；双栈 socket 上的 RemoteAddr 常常是这种形式
+func Normalize(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// IsIPv4Mapped 判断 ip 是否为 16 字节的 IPv4 映射 IPv6 地址（::ffff:a.b.c.d）
+// 注意：net.ParseIP 解析 IPv4 字符串时同样返回 16 字节的映射形式，因此对其结果也会返回 true
+func IsIPv4Mapped(ip net.IP) bool {
+	return len(ip) == net.IPv6len && ip.To4() != nil
+}
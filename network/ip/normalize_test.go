@@ -0,0 +1,40 @@
+package ip
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		ip         net.IP
+		want       string
+		wantLen    int
+		wantMapped bool
+	}{
+		{"IPv4 映射地址", net.ParseIP("::ffff:192.0.2.1"), "192.0.2.1", net.IPv4len, true},
+		{"4 字节 IPv4", net.IPv4(192, 0, 2, 1).To4(), "192.0.2.1", net.IPv4len, false},
+		{"IPv6 地址", net.ParseIP("2001:db8::1"), "2001:db8::1", net.IPv6len, false},
+		{"回环 IPv6", net.ParseIP("::1"), "::1", net.IPv6len, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsIPv4Mapped(tt.ip); got != tt.wantMapped {
+				t.Errorf("IsIPv4Mapped() = %v, want %v", got, tt.wantMapped)
+			}
+			got := Normalize(tt.ip)
+			if got.String() != tt.want || len(got) != tt.wantLen {
+				t.Errorf("Normalize() = %v (len %d), want %v (len %d)", got, len(got), tt.want, tt.wantLen)
+			}
+			if IsIPv4Mapped(got) {
+				t.Errorf("IsIPv4Mapped(Normalize()) = true, want false")
+			}
+		})
+	}
+
+	if got := Normalize(nil); got != nil {
+		t.Errorf("Normalize(nil) = %v, want nil", got)
+	}
+}
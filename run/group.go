@@ -1,5 +1,10 @@
 package run
 
+import (
+	"sync"
+	"time"
+)
+
 type actor struct {
 	execute   func() error
 	interrupt func(error)
@@ -10,6 +15,10 @@ type actor struct {
 // The zero value of a Group is useful.
 type Group struct {
 	actors []actor
+
+	mu           sync.Mutex
+	interrupted  bool
+	interruptErr error
 }
 
 // Add adds an actor to the group. Each actor must be pre-emptable by an
@@ -22,6 +31,95 @@ func (g *Group) Add(execute func() error, interrupt func(error)) {
 	g.actors = append(g.actors, actor{execute, interrupt})
 }
 
+// Once wraps interrupt so that it runs at most once, no matter how often the
+// returned function is called. Only the error of the first call is passed on.
+// It is meant for interrupt functions that are not idempotent on their own,
+// such as ones closing a channel, since an interrupt may be called both by
+// the group and by the actor itself.
+func Once(interrupt func(error)) func(error) {
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { interrupt(err) })
+	}
+}
+
+// Interrupt invokes the interrupt function of every actor with err. It is
+// meant for cleanup when Run will not be called, e.g. on an error path after
+// some actors were already constructed. The interrupt functions are invoked
+// at most once per Group, whether by Interrupt or by Run, so further calls
+// are no-ops.
+//
+// Calling Run after Interrupt does not start the actors: Run returns the
+// error passed to Interrupt immediately. Calling Interrupt while Run is
+// running interrupts the actors, and Run returns once they have exited.
+func (g *Group) Interrupt(err error) {
+	g.mu.Lock()
+	if g.interrupted {
+		g.mu.Unlock()
+		return
+	}
+	g.interrupted = true
+	g.interruptErr = err
+	g.mu.Unlock()
+
+	for _, a := range g.actors {
+		a.interrupt(err)
+	}
+}
+
+// AddRestartable adds an actor that is restarted when execute returns an error.
+// Execute is restarted up to maxRestarts times, waiting backoff(attempt) before
+// each restart, where attempt starts at 1. The actor only returns, and thereby
+// interrupts the group, when execute returns nil, when the restarts are
+// exhausted, or when the error is not retryable. If retryable is provided, an
+// error is only retried when retryable reports true for it.
+//
+// Once interrupt has been invoked, execute is no longer restarted.
+func (g *Group) AddRestartable(execute func() error, interrupt func(error), maxRestarts int, backoff func(int) time.Duration, retryable ...func(error) bool) {
+	stop := make(chan struct{})
+	var once sync.Once
+
+	g.Add(func() error {
+		for attempt := 1; ; attempt++ {
+			err := execute()
+			if err == nil || attempt > maxRestarts || !isRetryable(err, retryable) {
+				return err
+			}
+
+			var wait time.Duration
+			if backoff != nil {
+				wait = backoff(attempt)
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-stop:
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+			// interrupt may have raced with an expired backoff
+			select {
+			case <-stop:
+				return err
+			default:
+			}
+		}
+	}, func(err error) {
+		once.Do(func() { close(stop) })
+		interrupt(err)
+	})
+}
+
+// isRetryable reports whether err passes all retryable predicates.
+func isRetryable(err error, retryable []func(error) bool) bool {
+	for _, fn := range retryable {
+		if fn != nil && !fn(err) {
+			return false
+		}
+	}
+	return true
+}
+
 // Run runs all actors concurrently.
 // When the first actor returns, all actors are interrupted.
 // Run only returns when all actors have exited.
@@ -31,6 +129,14 @@ func (g *Group) Run() error {
 		return nil
 	}
 
+	// the actors were already interrupted, don't start them
+	g.mu.Lock()
+	interrupted, interruptErr := g.interrupted, g.interruptErr
+	g.mu.Unlock()
+	if interrupted {
+		return interruptErr
+	}
+
 	// Run each actor
 	errors := make(chan error, len(g.actors))
 	for _, a := range g.actors {
@@ -43,9 +149,7 @@ func (g *Group) Run() error {
 	err := <-errors
 
 	// Signal all actors to stop
-	for _, a := range g.actors {
-		a.interrupt(err)
-	}
+	g.Interrupt(err)
 
 	// wait for all actors to stop
 	// 这里使用cap, 避免在启动协程过程中出现错误导致这里len != cap
@@ -55,3 +55,150 @@ func TestMany(t *testing.T) {
 		t.Errorf("timeout")
 	}
 }
+
+func TestRestartable(t *testing.T) {
+	transient := errors.New("transient")
+	shutdown := errors.New("shutdown")
+	var g run.Group
+
+	attempts := 0
+	cancel := make(chan struct{})
+	g.AddRestartable(func() error {
+		attempts++
+		if attempts <= 2 {
+			return transient
+		}
+		<-cancel
+		return nil
+	}, func(error) { close(cancel) }, 3, func(int) time.Duration { return time.Millisecond })
+	g.Add(func() error { time.Sleep(50 * time.Millisecond); return shutdown }, func(error) {})
+
+	res := make(chan error)
+	go func() { res <- g.Run() }()
+	select {
+	case err := <-res:
+		if want, have := shutdown, err; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+		if want, have := 3, attempts; want != have {
+			t.Errorf("want %d attempts, have %d", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Error("timeout")
+	}
+}
+
+func TestRestartableExhausted(t *testing.T) {
+	transient := errors.New("transient")
+	var g run.Group
+
+	attempts := 0
+	g.AddRestartable(func() error {
+		attempts++
+		return transient
+	}, func(error) {}, 2, nil)
+
+	if want, have := transient, g.Run(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 3, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
+
+func TestRestartableNotRetryable(t *testing.T) {
+	fatal := errors.New("fatal")
+	var g run.Group
+
+	attempts := 0
+	g.AddRestartable(func() error {
+		attempts++
+		return fatal
+	}, func(error) {}, 5, nil, func(err error) bool { return err != fatal })
+
+	if want, have := fatal, g.Run(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 1, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
+
+func TestInterruptWithoutRun(t *testing.T) {
+	myError := errors.New("setup failed")
+	var g run.Group
+	calls := make([]int, 3)
+	for i := range calls {
+		g.Add(func() error { return nil }, func(err error) {
+			if err != myError {
+				t.Errorf("want %v, have %v", myError, err)
+			}
+			calls[i]++
+		})
+	}
+
+	g.Interrupt(myError)
+	g.Interrupt(errors.New("second"))
+	for i, n := range calls {
+		if n != 1 {
+			t.Errorf("interrupt %d: want 1 call, have %d", i, n)
+		}
+	}
+
+	// Run after Interrupt does not start the actors
+	if want, have := myError, g.Run(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	for i, n := range calls {
+		if n != 1 {
+			t.Errorf("interrupt %d: want 1 call after Run, have %d", i, n)
+		}
+	}
+}
+
+func TestInterruptDuringRun(t *testing.T) {
+	myError := errors.New("shutdown")
+	var g run.Group
+	started := make(chan struct{})
+	cancel := make(chan struct{})
+	calls := 0
+	g.Add(func() error { close(started); <-cancel; return nil }, func(error) {
+		calls++
+		close(cancel)
+	})
+	res := make(chan error)
+	go func() { res <- g.Run() }()
+	<-started
+	g.Interrupt(myError)
+	select {
+	case err := <-res:
+		if err != nil {
+			t.Errorf("want nil, have %v", err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout")
+	}
+	if calls != 1 {
+		t.Errorf("want 1 interrupt call, have %d", calls)
+	}
+}
+
+func TestOnce(t *testing.T) {
+	var calls int
+	var got error
+	first, second := errors.New("first"), errors.New("second")
+	interrupt := run.Once(func(err error) {
+		calls++
+		got = err
+	})
+
+	interrupt(first)
+	interrupt(second)
+
+	if want, have := 1, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+	if want, have := first, got; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -11,10 +11,10 @@ import (
 type Duration = time.Duration
 
 // Clock represents an interface to the functions in the standard library time
-// package. Two implementations are available in the clock package. The first
+// package. Three implementations are available in the clock package. The first
 // is a real-time clock which simply wraps the time package's functions. The
 // second is a mock clock which will only change when
-// programmatically adjusted.
+// programmatically adjusted. The third is a fixed clock which never changes.
 type Clock interface {
 	After(d time.Duration) <-chan time.Time
 	AfterFunc(d time.Duration, f func()) *Timer
@@ -279,6 +279,11 @@ func (t *Timer) Stop() bool {
 	if t.timer != nil {
 		return t.timer.Stop()
 	}
+	if t.mock == nil { // fixed clock, only a pending timer can be stopped
+		pending := !t.stopped
+		t.stopped = true
+		return pending
+	}
 
 	t.mock.mu.Lock()
 	registered := !t.stopped
@@ -293,6 +298,9 @@ func (t *Timer) Reset(d time.Duration) bool {
 	if t.timer != nil {
 		return t.timer.Reset(d)
 	}
+	if t.mock == nil { // fixed clock
+		return t.resetFixed(d)
+	}
 
 	t.mock.mu.Lock()
 	t.next = t.mock.now.Add(d)
@@ -341,7 +349,7 @@ type Ticker struct {
 func (t *Ticker) Stop() {
 	if t.ticker != nil {
 		t.ticker.Stop()
-	} else {
+	} else if t.mock != nil {
 		t.mock.mu.Lock()
 		t.mock.removeClockTimer((*internalTicker)(t))
 		t.mock.mu.Unlock()
@@ -354,6 +362,9 @@ func (t *Ticker) Reset(dur time.Duration) {
 		t.ticker.Reset(dur)
 		return
 	}
+	if t.mock == nil { // fixed clock, the ticker never ticks
+		return
+	}
 
 	t.mock.mu.Lock()
 	defer t.mock.mu.Unlock()
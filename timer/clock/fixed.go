@@ -0,0 +1,114 @@
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Fixed is a clock whose time never changes. Unlike Mock it cannot be
+// adjusted and takes no locks, so benchmarks can call Now and Epoch without
+// measuring the cost of reading the system time.
+//
+// Because time does not move, After, Timer and AfterFunc fire at once when
+// the duration is not positive and otherwise never fire, and Sleep returns at
+// once. Repeating events never happen: Tick returns a nil channel and Ticker
+// never ticks. Contexts from
+// WithTimeout and WithDeadline are already expired when the timeout is not
+// positive or the deadline is not after Now, and otherwise only end with
+// their parent.
+type Fixed struct {
+	now time.Time
+}
+
+// NewFixed returns a clock that always reports t.
+func NewFixed(t time.Time) *Fixed {
+	return &Fixed{now: t}
+}
+
+// After returns a channel that already holds the fixed time when d is not
+// positive, and otherwise never delivers.
+func (f *Fixed) After(d time.Duration) <-chan time.Time {
+	return f.Timer(d).C
+}
+
+// AfterFunc calls fn immediately in its own goroutine when d is not positive,
+// and otherwise returns a pending timer that never fires.
+func (f *Fixed) AfterFunc(d time.Duration, fn func()) *Timer {
+	t := &Timer{next: f.now, fn: fn, stopped: true}
+	t.resetFixed(d)
+	return t
+}
+
+// Now returns the fixed time.
+func (f *Fixed) Now() time.Time { return f.now }
+
+// Epoch returns the fixed time in seconds since the Unix epoch.
+func (f *Fixed) Epoch() int64 { return f.now.Unix() }
+
+// Since returns the time elapsed between t and the fixed time.
+func (f *Fixed) Since(t time.Time) time.Duration { return f.now.Sub(t) }
+
+// Until returns the duration from the fixed time until t.
+func (f *Fixed) Until(t time.Time) time.Duration { return t.Sub(f.now) }
+
+// Sleep returns immediately.
+func (f *Fixed) Sleep(d time.Duration) {}
+
+// Tick returns a nil channel, which never delivers a tick.
+func (f *Fixed) Tick(d time.Duration) <-chan time.Time { return nil }
+
+// Ticker returns a ticker that never ticks.
+func (f *Fixed) Ticker(d time.Duration) *Ticker {
+	c := make(chan time.Time)
+	return &Ticker{C: c, c: c, d: d}
+}
+
+// Timer returns a timer that has already fired when d is not positive, and
+// otherwise a pending timer that never fires.
+func (f *Fixed) Timer(d time.Duration) *Timer {
+	c := make(chan time.Time, 1)
+	t := &Timer{C: c, c: c, next: f.now, stopped: true}
+	t.resetFixed(d)
+	return t
+}
+
+// WithDeadline returns a context that is already expired when d is not after
+// the fixed time, and otherwise is only cancelled through its parent or the
+// returned cancel function.
+func (f *Fixed) WithDeadline(parent context.Context, d time.Time) (context.Context, context.CancelFunc) {
+	if !d.After(f.now) {
+		// a deadline in the past of the real clock expires at once
+		return context.WithDeadline(parent, time.Time{})
+	}
+	return context.WithCancel(parent)
+}
+
+// WithTimeout is WithDeadline(parent, Now().Add(t)).
+func (f *Fixed) WithTimeout(parent context.Context, t time.Duration) (context.Context, context.CancelFunc) {
+	return f.WithDeadline(parent, f.now.Add(t))
+}
+
+// resetFixed restarts a timer of a fixed clock: it fires at once when d is not
+// positive and otherwise stays pending until stopped or reset. It reports
+// whether the timer was pending.
+func (t *Timer) resetFixed(d time.Duration) bool {
+	pending := !t.stopped
+	t.stopped = d <= 0
+	if d <= 0 {
+		t.fire()
+	}
+	return pending
+}
+
+// fire delivers a timer of a fixed clock: AfterFunc timers run their function
+// in a new goroutine, the others send the fixed time unless C still holds it.
+func (t *Timer) fire() {
+	if t.fn != nil {
+		go t.fn()
+		return
+	}
+	select {
+	case t.c <- t.next:
+	default:
+	}
+}
@@ -0,0 +1,134 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ensure that the fixed clock always reports the same time.
+func TestFixed_Now(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var c Clock = NewFixed(now)
+
+	for i := 0; i < 3; i++ {
+		c.Sleep(time.Hour)
+		if got := c.Now(); !got.Equal(now) {
+			t.Fatalf("Now() = %s, want %s", got, now)
+		}
+		if got := c.Epoch(); got != now.Unix() {
+			t.Fatalf("Epoch() = %d, want %d", got, now.Unix())
+		}
+	}
+	if got := c.Since(now.Add(-time.Minute)); got != time.Minute {
+		t.Fatalf("Since() = %s, want 1m", got)
+	}
+	if got := c.Until(now.Add(time.Minute)); got != time.Minute {
+		t.Fatalf("Until() = %s, want 1m", got)
+	}
+}
+
+// Ensure that the fixed clock's timers fire immediately for non-positive durations,
+// never fire otherwise, and that its tickers never tick.
+func TestFixed_Timers(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewFixed(now)
+
+	if got := <-c.After(0); !got.Equal(now) {
+		t.Fatalf("After() sent %s, want %s", got, now)
+	}
+	select {
+	case <-c.After(time.Hour):
+		t.Fatal("After() fired for a positive duration")
+	default:
+	}
+
+	timer := c.Timer(-time.Second)
+	<-timer.C
+	if timer.Stop() {
+		t.Fatal("Stop() = true for a fired timer")
+	}
+	if timer.Reset(time.Hour) {
+		t.Fatal("Reset() = true for a fired timer")
+	}
+	select {
+	case <-timer.C:
+		t.Fatal("timer fired for a positive duration")
+	default:
+	}
+	if !timer.Reset(0) {
+		t.Fatal("Reset() = false for a pending timer")
+	}
+	<-timer.C
+	timer = c.Timer(time.Hour)
+	if !timer.Stop() {
+		t.Fatal("Stop() = false for a pending timer")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	ft := c.AfterFunc(0, wg.Done)
+	ft.Reset(0)
+	wg.Wait()
+
+	fired := make(chan struct{})
+	ft = c.AfterFunc(time.Hour, func() { close(fired) })
+	select {
+	case <-fired:
+		t.Fatal("AfterFunc() fired for a positive duration")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if !ft.Stop() {
+		t.Fatal("Stop() = false for a pending AfterFunc timer")
+	}
+
+	ticker := c.Ticker(time.Millisecond)
+	ticker.Reset(time.Millisecond)
+	select {
+	case <-ticker.C:
+		t.Fatal("fixed ticker ticked")
+	case <-time.After(10 * time.Millisecond):
+	}
+	ticker.Stop()
+}
+
+// Ensure that a scheduler driven by the fixed clock never fires its jobs.
+func TestFixed_Scheduler(t *testing.T) {
+	s := NewScheduler(NewFixed(time.Now()))
+	defer s.Stop()
+
+	var c counter
+	s.Every(time.Millisecond, c.incr)
+	time.Sleep(10 * time.Millisecond)
+	if n := c.get(); n != 0 {
+		t.Fatalf("scheduler fired on a fixed clock: %d", n)
+	}
+}
+
+// Ensure that the fixed clock's contexts only expire when the deadline is not in the future.
+func TestFixed_Context(t *testing.T) {
+	c := NewFixed(time.Now())
+
+	ctx, cancel := c.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Fatalf("expired context err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = c.WithTimeout(context.Background(), time.Nanosecond)
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("unexpired context err = %v", err)
+	}
+	cancel()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Fatalf("cancelled context err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func BenchmarkFixed_Now(b *testing.B) {
+	c := NewFixed(time.Now())
+	for i := 0; i < b.N; i++ {
+		c.Now()
+	}
+}
@@ -0,0 +1,105 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler runs periodic jobs driven by a single timer of a Clock.
+// Using a Mock clock, all due jobs fire deterministically when the mock is advanced.
+type Scheduler struct {
+	mu      sync.Mutex
+	clock   Clock
+	jobs    []*scheduledJob
+	timer   *Timer
+	stopped bool
+}
+
+// scheduledJob is a job registered with Scheduler.Every.
+type scheduledJob struct {
+	every time.Duration
+	next  time.Time
+	fn    func()
+}
+
+// NewScheduler returns a Scheduler driven by the given clock.
+func NewScheduler(clock Clock) *Scheduler {
+	return &Scheduler{clock: clock}
+}
+
+// Every registers fn to be called every d, starting d from now.
+// Like a Ticker, ticks that were missed because fn or the clock fell behind are coalesced.
+// It panics if d is not positive.
+func (s *Scheduler) Every(d time.Duration, fn func()) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Scheduler.Every")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.jobs = append(s.jobs, &scheduledJob{every: d, next: s.clock.Now().Add(d), fn: fn})
+	s.resetLocked()
+}
+
+// Stop stops the scheduler, no job is started after Stop returns.
+// Stop does not wait for jobs that are already running, so a job may call Stop itself.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// run fires all due jobs and re-arms the timer for the next one.
+func (s *Scheduler) run() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	now := s.clock.Now()
+	var due []func()
+	for _, job := range s.jobs {
+		if job.next.After(now) {
+			continue
+		}
+		due = append(due, job.fn)
+		job.next = job.next.Add(job.every)
+		if !job.next.After(now) {
+			job.next = now.Add(job.every)
+		}
+	}
+	s.resetLocked()
+	s.mu.Unlock()
+
+	for _, fn := range due {
+		// a job run before this one may have stopped the scheduler, or Stop was called concurrently
+		s.mu.Lock()
+		stopped := s.stopped
+		s.mu.Unlock()
+		if stopped {
+			return
+		}
+		fn()
+	}
+}
+
+// resetLocked arms the timer for the earliest job, s.mu must be held.
+func (s *Scheduler) resetLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	next := s.jobs[0].next
+	for _, job := range s.jobs[1:] {
+		if job.next.Before(next) {
+			next = job.next
+		}
+	}
+	s.timer = s.clock.AfterFunc(s.clock.Until(next), s.run)
+}
@@ -0,0 +1,68 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure that the scheduler fires every job at its own interval.
+func TestScheduler_Every(t *testing.T) {
+	clock := NewMock()
+	s := NewScheduler(clock)
+
+	var fast, slow counter
+	s.Every(1*time.Second, fast.incr)
+	s.Every(3*time.Second, slow.incr)
+
+	clock.Add(10 * time.Second)
+	if n := fast.get(); n != 10 {
+		t.Fatalf("unexpected fast job count: %d", n)
+	}
+	if n := slow.get(); n != 3 {
+		t.Fatalf("unexpected slow job count: %d", n)
+	}
+
+	s.Stop()
+	clock.Add(10 * time.Second)
+	if n := fast.get(); n != 10 {
+		t.Fatalf("fast job fired after stop: %d", n)
+	}
+	if n := slow.get(); n != 3 {
+		t.Fatalf("slow job fired after stop: %d", n)
+	}
+}
+
+// Ensure that a job stopping the scheduler prevents the other due jobs from starting.
+func TestScheduler_StopFromJob(t *testing.T) {
+	clock := NewMock()
+	s := NewScheduler(clock)
+
+	var c counter
+	s.Every(1*time.Second, s.Stop)
+	s.Every(1*time.Second, c.incr)
+
+	clock.Add(10 * time.Second)
+	if n := c.get(); n != 0 {
+		t.Fatalf("job started after stop: %d", n)
+	}
+}
+
+// Ensure that the scheduler works with a real-time clock.
+func TestScheduler_Every_Clock(t *testing.T) {
+	s := NewScheduler(New())
+	defer s.Stop()
+
+	done := make(chan struct{})
+	var c counter
+	s.Every(10*time.Millisecond, func() {
+		if c.incr(); c.get() == 2 {
+			close(done)
+		}
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("too late")
+	}
+}
@@ -0,0 +1,73 @@
+package clock
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// AlignedTicker returns a channel that delivers ticks at every multiple of period,
+// the first tick lands on the next period boundary (e.g. the top of the next minute).
+// Ticks are dropped when the receiver falls behind. Calling stop releases the underlying timer.
+func AlignedTicker(clk Clock, period time.Duration) (<-chan time.Time, func()) {
+	if period <= 0 {
+		panic("clock: non-positive period for AlignedTicker")
+	}
+	return startFuncTicker(clk, func(now time.Time) time.Duration {
+		return now.Truncate(period).Add(period).Sub(now)
+	})
+}
+
+// JitteredTicker returns a channel that delivers ticks every period plus a random
+// duration in [0, jitter), which staggers periodic work across instances.
+// Ticks are dropped when the receiver falls behind. Calling stop releases the underlying timer.
+func JitteredTicker(clk Clock, period, jitter time.Duration) (<-chan time.Time, func()) {
+	if period <= 0 {
+		panic("clock: non-positive period for JitteredTicker")
+	}
+	return startFuncTicker(clk, func(time.Time) time.Duration {
+		if jitter <= 0 {
+			return period
+		}
+		return period + time.Duration(rand.Int63n(int64(jitter)))
+	})
+}
+
+// funcTicker is a ticker whose interval is computed by next before every tick.
+type funcTicker struct {
+	mu      sync.Mutex
+	clock   Clock
+	c       chan time.Time
+	next    func(now time.Time) time.Duration
+	timer   *Timer
+	stopped bool
+}
+
+func startFuncTicker(clk Clock, next func(now time.Time) time.Duration) (<-chan time.Time, func()) {
+	t := &funcTicker{clock: clk, c: make(chan time.Time, 1), next: next}
+	t.mu.Lock()
+	t.timer = clk.AfterFunc(next(clk.Now()), t.tick)
+	t.mu.Unlock()
+	return t.c, t.stop
+}
+
+func (t *funcTicker) tick() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	now := t.clock.Now()
+	select {
+	case t.c <- now:
+	default:
+	}
+	t.timer = t.clock.AfterFunc(t.next(now), t.tick)
+}
+
+func (t *funcTicker) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	t.timer.Stop()
+}
@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure that the first aligned tick lands on a period boundary.
+func TestAlignedTicker(t *testing.T) {
+	clock := NewMock()
+	clock.Add(1500 * time.Millisecond)
+
+	ticks, stop := AlignedTicker(clock, time.Second)
+	defer stop()
+
+	clock.Add(500 * time.Millisecond)
+	select {
+	case tick := <-ticks:
+		if !tick.Equal(time.Unix(2, 0)) {
+			t.Fatalf("unexpected first tick: %s", tick)
+		}
+	default:
+		t.Fatal("expected tick")
+	}
+
+	clock.Add(time.Second)
+	select {
+	case tick := <-ticks:
+		if !tick.Equal(time.Unix(3, 0)) {
+			t.Fatalf("unexpected second tick: %s", tick)
+		}
+	default:
+		t.Fatal("expected tick")
+	}
+}
+
+// Ensure that jittered ticks fire within period and period+jitter and stop firing after stop.
+func TestJitteredTicker(t *testing.T) {
+	clock := NewMock()
+
+	ticks, stop := JitteredTicker(clock, time.Second, 500*time.Millisecond)
+
+	clock.Add(999 * time.Millisecond)
+	select {
+	case <-ticks:
+		t.Fatal("too early")
+	default:
+	}
+
+	clock.Add(501 * time.Millisecond)
+	select {
+	case <-ticks:
+	default:
+		t.Fatal("expected tick")
+	}
+
+	stop()
+	clock.Add(10 * time.Second)
+	select {
+	case <-ticks:
+		t.Fatal("unexpected tick after stop")
+	default:
+	}
+}